@@ -0,0 +1,94 @@
+package topgg
+
+import (
+	"context"
+	"strings"
+	"sync"
+)
+
+// Group of API endpoints sharing a bulkhead, see ClientOptions.Bulkheads.
+type EndpointGroup string
+
+const (
+	EndpointGroupVotes   EndpointGroup = "votes"   // GetVote, GetVotes and StreamVotes.
+	EndpointGroupMetrics EndpointGroup = "metrics" // PostMetrics and PostMetricsInBatch.
+	EndpointGroupProject EndpointGroup = "project" // Every other endpoint (project, commands, announcements).
+)
+
+type BulkheadOptions struct {
+	MaxConcurrent int // Calls of the group in flight at once. 0 disables the bulkhead.
+	MaxQueue      int // Calls waiting for a free slot, further calls fail with ErrBulkheadFull. 0 means no limit.
+}
+
+// Caps concurrent calls of an endpoint group, so e.g. a flood of vote checks can't starve metrics posting.
+type bulkhead struct {
+	slots    chan struct{}
+	queued   int
+	maxQueue int
+	mu       sync.Mutex
+}
+
+func newBulkheads(opts map[EndpointGroup]BulkheadOptions) map[EndpointGroup]*bulkhead {
+	if len(opts) == 0 {
+		return nil
+	}
+
+	bulkheads := make(map[EndpointGroup]*bulkhead, len(opts))
+	for group, opt := range opts {
+		if opt.MaxConcurrent <= 0 {
+			continue
+		}
+
+		bulkheads[group] = &bulkhead{
+			slots:    make(chan struct{}, opt.MaxConcurrent),
+			maxQueue: opt.MaxQueue,
+		}
+	}
+
+	return bulkheads
+}
+
+// Waits for a free slot. Returned func releases it.
+func (b *bulkhead) acquire(ctx context.Context) (func(), error) {
+	release := func() { <-b.slots }
+
+	select {
+	case b.slots <- struct{}{}:
+		return release, nil
+	default:
+	}
+
+	b.mu.Lock()
+	if b.maxQueue > 0 && b.queued >= b.maxQueue {
+		b.mu.Unlock()
+		return nil, ErrBulkheadFull
+	}
+	b.queued++
+	b.mu.Unlock()
+
+	defer func() {
+		b.mu.Lock()
+		b.queued--
+		b.mu.Unlock()
+	}()
+
+	select {
+	case b.slots <- struct{}{}:
+		return release, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// Returns group of versioned route, e.g. "/v1/projects/@me/votes/1" belongs to EndpointGroupVotes.
+func endpointGroupOf(route string) EndpointGroup {
+	path, _, _ := strings.Cut(route, "?")
+	switch {
+	case strings.Contains(path, "/projects/@me/votes"):
+		return EndpointGroupVotes
+	case strings.Contains(path, "/projects/@me/metrics"):
+		return EndpointGroupMetrics
+	default:
+		return EndpointGroupProject
+	}
+}
@@ -0,0 +1,172 @@
+package topgg
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Waits until given number of calls queue up in bulkhead.
+func waitForQueued(t *testing.T, b *bulkhead, n int) {
+	t.Helper()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		b.mu.Lock()
+		queued := b.queued
+		b.mu.Unlock()
+
+		if queued == n {
+			return
+		}
+
+		if time.Now().After(deadline) {
+			t.Fatalf("%d calls queued, want %d", queued, n)
+		}
+
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestBulkheadQueueLimit(t *testing.T) {
+	b := newBulkheads(map[EndpointGroup]BulkheadOptions{EndpointGroupVotes: {MaxConcurrent: 1, MaxQueue: 1}})[EndpointGroupVotes]
+
+	release, err := b.acquire(context.Background())
+	if err != nil {
+		t.Fatalf("first call: %v", err)
+	}
+
+	queued := make(chan func(), 1)
+	go func() {
+		release, err := b.acquire(context.Background())
+		if err != nil {
+			t.Errorf("queued call: %v", err)
+		}
+		queued <- release
+	}()
+	waitForQueued(t, b, 1)
+
+	if _, err := b.acquire(context.Background()); !errors.Is(err, ErrBulkheadFull) {
+		t.Fatalf("call past queue limit got %v, want ErrBulkheadFull", err)
+	}
+
+	// Freed slot goes to the queued call.
+	release()
+	select {
+	case release := <-queued:
+		release()
+	case <-time.After(5 * time.Second):
+		t.Fatal("queued call didn't get released slot")
+	}
+
+	waitForQueued(t, b, 0)
+	if release, err := b.acquire(context.Background()); err != nil {
+		t.Fatalf("call after queue drained: %v", err)
+	} else {
+		release()
+	}
+}
+
+func TestBulkheadCancelWhileQueued(t *testing.T) {
+	b := newBulkheads(map[EndpointGroup]BulkheadOptions{EndpointGroupVotes: {MaxConcurrent: 1}})[EndpointGroupVotes]
+
+	release, err := b.acquire(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer release()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	result := make(chan error, 1)
+	go func() {
+		_, err := b.acquire(ctx)
+		result <- err
+	}()
+	waitForQueued(t, b, 1)
+
+	cancel()
+	if err := <-result; !errors.Is(err, context.Canceled) {
+		t.Fatalf("got %v, want context.Canceled", err)
+	}
+
+	// Cancelled call leaves the queue and never takes a slot.
+	waitForQueued(t, b, 0)
+	if len(b.slots) != 1 {
+		t.Fatalf("%d slots taken, want 1", len(b.slots))
+	}
+}
+
+func TestNewBulkheads(t *testing.T) {
+	if newBulkheads(nil) != nil {
+		t.Fatal("bulkheads were created without options")
+	}
+
+	bulkheads := newBulkheads(map[EndpointGroup]BulkheadOptions{
+		EndpointGroupVotes:   {MaxConcurrent: 2},
+		EndpointGroupMetrics: {MaxConcurrent: 0, MaxQueue: 5},
+	})
+
+	if b := bulkheads[EndpointGroupVotes]; b == nil || cap(b.slots) != 2 {
+		t.Fatalf("votes bulkhead is %+v, want 2 slots", b)
+	}
+
+	if b := bulkheads[EndpointGroupMetrics]; b != nil {
+		t.Fatal("bulkhead without MaxConcurrent was created")
+	}
+}
+
+func TestEndpointGroupOf(t *testing.T) {
+	tests := []struct {
+		route string
+		want  EndpointGroup
+	}{
+		{"/v1/projects/@me/votes/1?source=discord", EndpointGroupVotes},
+		{"/v1/projects/@me/votes?startDate=2026-01-01", EndpointGroupVotes},
+		{"/v1/projects/@me/metrics", EndpointGroupMetrics},
+		{"/v1/projects/@me/metrics/batch", EndpointGroupMetrics},
+		{"/v1/projects/@me", EndpointGroupProject},
+		{"/v1/projects/@me/commands", EndpointGroupProject},
+	}
+
+	for _, tt := range tests {
+		if got := endpointGroupOf(tt.route); got != tt.want {
+			t.Errorf("route %s belongs to %s, want %s", tt.route, got, tt.want)
+		}
+	}
+}
+
+func TestClientBulkheadIsolatesGroups(t *testing.T) {
+	release := make(chan struct{})
+	defer close(release)
+
+	started := make(chan struct{}, 1)
+	client := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.Contains(r.URL.Path, "/votes") {
+			started <- struct{}{}
+			<-release
+		}
+		_, _ = w.Write([]byte(`{}`))
+	}), ClientOptions{Bulkheads: map[EndpointGroup]BulkheadOptions{
+		EndpointGroupVotes:   {MaxConcurrent: 1, MaxQueue: 1},
+		EndpointGroupMetrics: {MaxConcurrent: 1},
+	}})
+
+	for i := 0; i < 2; i++ {
+		go func() {
+			_, _ = client.GetVote(context.Background(), 1, PlatformDiscord, NoCoalesce())
+		}()
+	}
+	<-started
+	waitForQueued(t, client.bulkheads[EndpointGroupVotes], 1)
+
+	if _, err := client.GetVote(context.Background(), 1, PlatformDiscord, NoCoalesce()); !errors.Is(err, ErrBulkheadFull) {
+		t.Fatalf("vote check past queue got %v, want ErrBulkheadFull", err)
+	}
+
+	if err := client.PostMetrics(context.Background(), MetricsPayload{ServerCount: 1}); err != nil {
+		t.Fatalf("metrics were held up by vote checks: %v", err)
+	}
+}
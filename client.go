@@ -2,32 +2,110 @@ package topgg
 
 import (
 	"bytes"
+	"compress/gzip"
 	"context"
-	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"log"
 	"net"
 	"net/http"
+	"net/url"
 	"os"
+	"sync"
 	"time"
 )
 
 type Client struct {
-	limiter     *RateLimiter
-	traceLogger *log.Logger
-	HTTPClient  http.Client
-	token       string
+	limiter              Limiter
+	codec                Codec
+	scheduler            Scheduler
+	traceLogger          *log.Logger
+	metricsGuard         func(previous, next MetricsPayload) error
+	onUnavailable        func(err error)
+	lastMetrics          *MetricsPayload
+	counters             *expvarCounters
+	latencies            *endpointLatencies
+	sink                 MetricsSink
+	transcripts          *transcriptRing
+	bulkheads            map[EndpointGroup]*bulkhead
+	HTTPClient           http.Client
+	token                string
+	apiVersion           string
+	inflight             flightGroup
+	compressionThreshold int
+	voteValidity         time.Duration
+	metricsMu            sync.Mutex
+	strictDecoding       bool
 }
 
 type ClientOptions struct {
-	RateLimiterOptions RateLimiterOptions
-	HTTPClient         *http.Client
-	Token              string
-	MaxWaitTime        time.Duration
-	RetryThreshold     uint32
-	MaxRetries         uint8
-	Trace              bool
+	Hooks                ClientHooks
+	Bulkheads            map[EndpointGroup]BulkheadOptions // Isolates concurrency of endpoint groups, so e.g. a flood of vote checks can't hold up PostMetrics. Groups without entry aren't limited.
+	Codec                Codec                             // JSON implementation used for requests, responses and webhooks, defaults to encoding/json. Strict decoding and StreamVotes always use encoding/json.
+	Limiter              Limiter                           // Replaces default RateLimiter, RateLimiterOptions are ignored when set.
+	Scheduler            Scheduler                         // Runs periodic work of components created by the client (reconcilers, MetricsPoster, ProjectWatcher, VoteRateMonitor), defaults to TickerScheduler.
+	Transport            http.RoundTripper                 // Replaces default transport, rate limiting & retries still apply. Proxy and DialContext are ignored when set.
+	HTTPClient           *http.Client
+	Proxy                func(*http.Request) (*url.URL, error)                             // Proxy of default transport, defaults to http.ProxyFromEnvironment.
+	DialContext          func(ctx context.Context, network, addr string) (net.Conn, error) // Dialer of default transport.
+	OnUnavailable        func(err error)                                                   // Called when a call fails because Top.gg is unavailable (see IsUnavailable), e.g. to show degraded status.
+	ShouldRetry          func(resp *http.Response, err error, attempt int) bool            // Overrides which failed attempts are retried. By default network errors, 429 and 5xx responses are.
+	MetricsGuard         func(previous, next MetricsPayload) error                         // Rejects PostMetrics payload when it returns error (or panics, with *PanicError), e.g. on absurd jumps in server count. Called only after first successful post.
+	MetricsSink          MetricsSink                                                       // Receives instrumentation of client, limiter, webhooks and metrics poster, see NewStatsdSink.
+	Debug                io.Writer                                                         // When set, every request & response (including retries) is dumped here with the token masked.
+	Token                string
+	APIVersion           string // Version of Top.gg API used by typed methods, defaults to DefaultAPIVersion. Lets users opt into new API version once its endpoints are compatible, without waiting for SDK release.
+	Expvar               string // When set, basic counters (see ExpvarAPIRequests etc.) are published under this expvar name, e.g. "topgg". Clients using the same name share counters.
+	RateLimiterOptions   RateLimiterOptions
+	MaxWaitTime          time.Duration
+	VoteValidity         time.Duration // How long a vote lasts, defaults to DefaultVoteValidity. Shared by RewardEngine, VoteChecker reconciliation and votes lacking expiry time, so they stay in agreement if Top.gg changes the rules.
+	CaptureTranscripts   int           // Number of latest redacted request/response transcripts retained for Client.DumpTranscripts, 0 disables capture.
+	CompressionThreshold int           // Request bodies of at least this many bytes are sent gzip compressed, 0 disables compression.
+	IdleConnTimeout      time.Duration // Defaults to 90 seconds.
+	MaxIdleConns         int           // Defaults to 256.
+	MaxIdleConnsPerHost  int           // Defaults to 2 (http.DefaultMaxIdleConnsPerHost), all SDK traffic goes to a single host.
+	MaxConnsPerHost      int           // Defaults to 0 (no limit).
+	RetryBudgetWindow    time.Duration // Defaults to 1 minute.
+	RetryThreshold       uint32
+	RetryBudget          uint32 // Max retries across all calls per RetryBudgetWindow, so an outage isn't amplified by retries. 0 means no budget.
+	MaxRetries           uint8
+	StrictDecoding       bool // Applies WithStrictDecoding to every call of typed methods.
+	Trace                bool
+}
+
+// Lightweight callbacks invoked for each attempt of an API call, e.g. to emit custom metrics.
+// Path excludes query string, attempts are counted from 1.
+// Hooks are part of default transport, so they're not called when custom HTTPClient is used.
+type ClientHooks struct {
+	OnRequest  func(method, path string, attempt int)
+	OnResponse func(method, path string, status, attempt int, duration time.Duration)
+	OnRetry    func(method, path string, attempt int, err error) // Called before waiting for next attempt, with err of the failed one.
+}
+
+// Hooks run within requests, so their panics are recovered and traced instead of failing the request.
+func (h ClientHooks) request(traceLogger *log.Logger, req *http.Request, attempt int) {
+	if h.OnRequest != nil {
+		h.recovered(traceLogger, "OnRequest", func() { h.OnRequest(req.Method, req.URL.Path, attempt) })
+	}
+}
+
+func (h ClientHooks) response(traceLogger *log.Logger, req *http.Request, status, attempt int, duration time.Duration) {
+	if h.OnResponse != nil {
+		h.recovered(traceLogger, "OnResponse", func() { h.OnResponse(req.Method, req.URL.Path, status, attempt, duration) })
+	}
+}
+
+func (h ClientHooks) retry(traceLogger *log.Logger, req *http.Request, attempt int, err error) {
+	if h.OnRetry != nil {
+		h.recovered(traceLogger, "OnRetry", func() { h.OnRetry(req.Method, req.URL.Path, attempt, err) })
+	}
+}
+
+func (h ClientHooks) recovered(traceLogger *log.Logger, callback string, fn func()) {
+	if err := safeCall("client", callback, fn); err != nil {
+		traceLogger.Printf("[CLIENT] %v", err)
+	}
 }
 
 func NewClient(opt ClientOptions) *Client {
@@ -36,6 +114,11 @@ func NewClient(opt ClientOptions) *Client {
 		maxTimeout = opt.MaxWaitTime
 	}
 
+	retryBudgetWindow := opt.RetryBudgetWindow
+	if retryBudgetWindow == 0 {
+		retryBudgetWindow = time.Minute
+	}
+
 	maxRetries := opt.MaxRetries
 	if opt.MaxRetries == 0 {
 		maxRetries = 3
@@ -53,56 +136,261 @@ func NewClient(opt ClientOptions) *Client {
 	}
 	opt.RateLimiterOptions.TraceLogger = traceLogger
 
-	limiter := NewRateLimiter(opt.RateLimiterOptions)
+	var sink MetricsSink = nopSink{}
+	if opt.MetricsSink != nil {
+		sink = opt.MetricsSink
+	}
+
+	if opt.RateLimiterOptions.MetricsSink == nil {
+		opt.RateLimiterOptions.MetricsSink = sink
+	}
+
+	limiter := opt.Limiter
+	if limiter == nil {
+		limiter = NewRateLimiter(opt.RateLimiterOptions)
+	}
+
+	counters := newExpvarCounters(opt.Expvar)
+	latencies := newEndpointLatencies()
 
+	var transcripts *transcriptRing
 	clientCopy := http.Client{}
 	if opt.HTTPClient != nil {
 		clientCopy = *opt.HTTPClient
 	} else {
-		transport := &http.Transport{
-			Proxy: http.ProxyFromEnvironment,
-			DialContext: (&net.Dialer{
-				Timeout:   5 * time.Second,
-				KeepAlive: 30 * time.Second,
-			}).DialContext,
-			ForceAttemptHTTP2: false,
-			MaxIdleConns:      256,
-			IdleConnTimeout:   90 * time.Second,
+		transport := opt.Transport
+		if transport == nil {
+			proxy := opt.Proxy
+			if proxy == nil {
+				proxy = http.ProxyFromEnvironment
+			}
+
+			dialContext := opt.DialContext
+			if dialContext == nil {
+				dialContext = (&net.Dialer{
+					Timeout:   5 * time.Second,
+					KeepAlive: 30 * time.Second,
+				}).DialContext
+			}
+
+			maxIdleConns := opt.MaxIdleConns
+			if maxIdleConns == 0 {
+				maxIdleConns = 256
+			}
+
+			idleConnTimeout := opt.IdleConnTimeout
+			if idleConnTimeout == 0 {
+				idleConnTimeout = 90 * time.Second
+			}
+
+			transport = &http.Transport{
+				Proxy:               proxy,
+				DialContext:         dialContext,
+				ForceAttemptHTTP2:   false,
+				MaxIdleConns:        maxIdleConns,
+				MaxIdleConnsPerHost: opt.MaxIdleConnsPerHost,
+				MaxConnsPerHost:     opt.MaxConnsPerHost,
+				IdleConnTimeout:     idleConnTimeout,
+			}
+		}
+
+		if opt.CaptureTranscripts > 0 {
+			transcripts = newTranscriptRing(opt.CaptureTranscripts)
+		}
+
+		if opt.Debug != nil || transcripts != nil {
+			transport = &debugTransport{innerTransport: transport, w: opt.Debug, transcripts: transcripts}
 		}
 
 		clientCopy.Transport = &rateLimitTransport{
 			limiter:        limiter,
 			innerTransport: transport,
+			hooks:          opt.Hooks,
+			traceLogger:    traceLogger,
+			counters:       counters,
+			latencies:      latencies,
+			sink:           sink,
+			shouldRetry:    opt.ShouldRetry,
 			maxRetries:     maxRetries,
 			retryThreshold: int64(retryThreshold),
+
+			retryBudget:       opt.RetryBudget,
+			retryBudgetWindow: retryBudgetWindow,
 		}
 		clientCopy.Timeout = maxTimeout
 	}
 
+	var codec Codec = stdCodec{}
+	if opt.Codec != nil {
+		codec = opt.Codec
+	}
+
+	voteValidity := opt.VoteValidity
+	if voteValidity <= 0 {
+		voteValidity = DefaultVoteValidity
+	}
+
+	var scheduler Scheduler = TickerScheduler{}
+	if opt.Scheduler != nil {
+		scheduler = opt.Scheduler
+	}
+
+	apiVersion := opt.APIVersion
+	if apiVersion == "" {
+		apiVersion = DefaultAPIVersion
+	}
+
 	return &Client{
-		limiter:     limiter,
-		HTTPClient:  clientCopy,
-		token:       opt.Token,
-		traceLogger: traceLogger,
+		limiter:              limiter,
+		codec:                codec,
+		scheduler:            scheduler,
+		HTTPClient:           clientCopy,
+		token:                opt.Token,
+		apiVersion:           apiVersion,
+		traceLogger:          traceLogger,
+		compressionThreshold: opt.CompressionThreshold,
+		voteValidity:         voteValidity,
+		metricsGuard:         opt.MetricsGuard,
+		onUnavailable:        opt.OnUnavailable,
+		counters:             counters,
+		latencies:            latencies,
+		sink:                 sink,
+		transcripts:          transcripts,
+		bulkheads:            newBulkheads(opt.Bulkheads),
+		strictDecoding:       opt.StrictDecoding,
 	}
 }
 
+// Writes retained request/response transcripts (oldest first, Authorization header masked) to w,
+// e.g. to attach them to a bug report. Writes nothing unless ClientOptions.CaptureTranscripts is set.
+func (c *Client) DumpTranscripts(w io.Writer) error {
+	if c.transcripts == nil {
+		return nil
+	}
+
+	return c.transcripts.dump(w)
+}
+
+// Returns latency distributions of API calls since client creation, keyed by endpoint (e.g. "GET /v1/projects/@me/votes/:id").
+// Each attempt is measured separately and excludes rate limiter waits, so it reflects Top.gg response times.
+// Empty when custom HTTPClient is used.
+func (c *Client) EndpointLatencies() map[string]EndpointLatency {
+	return c.latencies.snapshot()
+}
+
+// Returns route of given endpoint in configured API version, e.g. "/projects/@me" becomes "/v1/projects/@me".
+func (c *Client) endpoint(path string) string {
+	return "/" + c.apiVersion + path
+}
+
+// Decodes response of typed method, strictly when client or call asks for it.
+func (c *Client) decode(b []byte, v any, opts []RequestOption) error {
+	strict := c.strictDecoding
+	if !strict {
+		var cfg requestConfig
+		for _, opt := range opts {
+			opt(&cfg)
+		}
+		strict = cfg.strict
+	}
+
+	return decodeJSON(c.codec, b, v, strict)
+}
+
 func (c *Client) tracef(format string, v ...any) {
 	c.traceLogger.Printf("[CLIENT] "+format, v...)
 }
 
-func (c *Client) request(ctx context.Context, method, route string, jsonPayload any) (response []byte, err error) {
-	var body io.Reader
+// Sends API request. Identical concurrent GET requests are collapsed into one call, unless NoCoalesce is used.
+func (c *Client) request(ctx context.Context, method, route string, jsonPayload any, opts ...RequestOption) ([]byte, error) {
+	b, err := c.send(ctx, method, route, jsonPayload, opts...)
+	if c.onUnavailable != nil && IsUnavailable(err) {
+		if err := safeCall("client", "OnUnavailable", func() { c.onUnavailable(err) }); err != nil {
+			c.tracef("%v", err)
+		}
+	}
+
+	return b, err
+}
+
+func (c *Client) send(ctx context.Context, method, route string, jsonPayload any, opts ...RequestOption) ([]byte, error) {
+	var cfg requestConfig
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	if cfg.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, cfg.timeout)
+		defer cancel()
+	}
+
+	key := route
+	if cfg.waitLimits != (waitLimits{}) {
+		ctx = context.WithValue(ctx, waitLimitsKey{}, cfg.waitLimits)
+	}
+
+	if cfg.noRetry {
+		ctx = context.WithValue(ctx, noRetryKey{}, true)
+		// Callers without NoRetry shouldn't get result of a call that gave up after one attempt.
+		key = "noretry:" + route
+	}
+
+	if method == http.MethodGet && jsonPayload == nil && !cfg.noCoalesce {
+		return c.inflight.do(ctx, key, func(ctx context.Context) ([]byte, error) {
+			return c.doRequest(ctx, method, route, nil, nil, nil)
+		})
+	}
+
+	return c.doRequest(ctx, method, route, jsonPayload, cfg.meta, cfg.stream)
+}
+
+// When stream is set, successful response body is passed to it instead of being read into memory.
+func (c *Client) doRequest(ctx context.Context, method, route string, jsonPayload any, meta *ResponseMeta, stream func(r io.Reader) error) (response []byte, err error) {
+	// Coalesced calls share single slot, since only one request is sent for them.
+	if bulkhead := c.bulkheads[endpointGroupOf(route)]; bulkhead != nil {
+		release, err := bulkhead.acquire(ctx)
+		if err != nil {
+			return nil, err
+		}
+		defer release()
+	}
+
+	var (
+		body       io.Reader
+		compressed bool
+	)
+
 	if jsonPayload != nil {
-		var buf bytes.Buffer
-		encoder := json.NewEncoder(&buf)
-		encoder.SetEscapeHTML(false)
-		if err := encoder.Encode(jsonPayload); err != nil {
+		encoded, err := c.codec.Marshal(jsonPayload)
+		if err != nil {
 			return nil, fmt.Errorf("failed to encode JSON payload: %w", err)
 		}
-		body = &buf
+
+		buf := bytes.NewBuffer(encoded)
+		if c.compressionThreshold > 0 && buf.Len() >= c.compressionThreshold {
+			var gzBuf bytes.Buffer
+			gz := gzip.NewWriter(&gzBuf)
+			if _, err := gz.Write(buf.Bytes()); err != nil {
+				return nil, fmt.Errorf("failed to compress JSON payload: %w", err)
+			}
+
+			if err := gz.Close(); err != nil {
+				return nil, fmt.Errorf("failed to compress JSON payload: %w", err)
+			}
+
+			buf = &gzBuf
+			compressed = true
+		}
+
+		body = buf
+	}
+
+	if stream != nil {
+		ctx = withStreamedResponse(ctx)
 	}
 
+	ctx, timedOut := withRoundTripTimeout(ctx)
 	url := BaseURL + route
 	req, err := http.NewRequestWithContext(ctx, method, url, body)
 	if err != nil {
@@ -111,15 +399,26 @@ func (c *Client) request(ctx context.Context, method, route string, jsonPayload
 
 	if body != nil {
 		req.Header.Set("Content-Type", "application/json")
+		if compressed {
+			req.Header.Set("Content-Encoding", "gzip")
+		}
 	}
 
+	// Setting it explicitly disables transparent decompression of http.Transport,
+	// so we handle it below the same way for default and user provided transports.
+	req.Header.Set("Accept-Encoding", "gzip")
 	req.Header.Set("User-Agent", UserAgent)
 	req.Header.Set("Authorization", "Bearer "+c.token)
 
-	c.tracef("Making API request: %s :: %s/%s", method, BaseURL, route)
+	c.tracef("Making API request: %s :: %s%s", method, BaseURL, route)
 
+	start := time.Now()
 	res, err := c.HTTPClient.Do(req)
 	if err != nil {
+		if timedOut.Load() && !errors.Is(err, ErrUnavailable) {
+			err = &unavailableError{err: err}
+		}
+
 		return nil, err
 	}
 
@@ -129,35 +428,134 @@ func (c *Client) request(ctx context.Context, method, route string, jsonPayload
 		}
 	}()
 
-	responseBody, err := io.ReadAll(res.Body)
+	var reader io.Reader = res.Body
+	if res.Header.Get("Content-Encoding") == "gzip" {
+		gz, err := gzip.NewReader(res.Body)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode gzip response body: %w", err)
+		}
+
+		defer gz.Close()
+		reader = gz
+	}
+
+	if stream != nil && res.StatusCode >= http.StatusOK && res.StatusCode < http.StatusMultipleChoices {
+		err := stream(reader)
+		if meta != nil {
+			meta.fill(res, time.Since(start))
+		}
+
+		return nil, err
+	}
+
+	responseBody, err := io.ReadAll(reader)
 	if err != nil {
 		return nil, fmt.Errorf("failed to read response body: %w", err)
 	}
 
+	if meta != nil {
+		meta.fill(res, time.Since(start))
+	}
+
 	if res.StatusCode >= http.StatusOK && res.StatusCode < http.StatusMultipleChoices {
 		return responseBody, nil
 	}
 
-	if res.StatusCode == http.StatusUnauthorized || res.StatusCode == http.StatusForbidden {
-		return nil, ErrUnauthorizedRequest
+	return nil, newAPIError(res.StatusCode, responseBody)
+}
+
+// Makes a cheap authenticated call to verify that the token is valid and Top.gg API is reachable,
+// so bots can fail fast at startup. Invalid token is reported with error matching ErrUnauthorizedRequest,
+// any other error means API couldn't be reached or misbehaved.
+func (c *Client) Ping(ctx context.Context, opts ...RequestOption) error {
+	_, err := c.request(ctx, http.MethodGet, c.endpoint("/projects/@me"), nil, opts...)
+	if err == nil || errors.Is(err, ErrUnauthorizedRequest) {
+		return err
 	}
 
-	return nil, fmt.Errorf("request failed with status %s: %s", res.Status, string(responseBody))
+	return fmt.Errorf("failed to reach top.gg API: %w", err)
 }
 
-func (c *Client) NewWebhookHandler(opt WebhookOptions) http.Handler {
+func (c *Client) NewWebhookHandler(opt WebhookOptions) *Webhook {
 	window := opt.TimestampWindow
 	if window == 0 {
 		window = 30 * time.Second
 	}
 
-	return &Webhook{
-		secret:              opt.Secret,
+	signatureHeader := opt.SignatureHeader
+	if signatureHeader == "" {
+		signatureHeader = "x-topgg-signature"
+	}
+
+	maxBodySize := opt.MaxBodySize
+	if maxBodySize <= 0 {
+		maxBodySize = defaultMaxBodySize
+	}
+
+	w := &Webhook{
+		signatureHeader:     signatureHeader,
+		signatureQueryParam: opt.SignatureQueryParam,
 		timestampWindow:     window,
+		maxBodySize:         maxBodySize,
 		onVote:              opt.OnVote,
 		onIntegrationCreate: opt.OnIntegrationCreate,
 		onIntegrationDelete: opt.OnIntegrationDelete,
 		onTest:              opt.OnTest,
+		onRejected:          opt.OnRejected,
+		onError:             opt.OnError,
 		traceLogger:         c.traceLogger,
+		counters:            c.counters,
+		sink:                c.sink,
+		codec:               c.codec,
+		auditLog:            opt.AuditLog,
+		replayStore:         opt.ReplayStore,
+		drainStore:          opt.DrainStore,
+		abandoned:           make(chan struct{}),
+		voteValidity:        c.voteValidity,
+		metrics:             &webhookMetrics{handlerLatency: newLatencyHistogram()},
+		ipLimiter:           newIPRateLimiter(opt.MaxRequestsPerIP),
+		queryParams:         queryParamSet(opt.QueryParams),
+		queuePolicy:         opt.QueuePolicy,
+		ackMode:             opt.AckMode,
+		strictDecoding:      opt.StrictDecoding,
+		requireContentType:  opt.RequireContentType,
+	}
+
+	if opt.RejectReplays && window > 0 {
+		w.replays = newReplayCache(window, opt.MaxReplayEntries)
 	}
+
+	for _, proxy := range opt.TrustedProxies {
+		network, err := parseCIDR(proxy)
+		if err != nil {
+			w.tracef("Ignored invalid trusted proxy %q: %v", proxy, err)
+			continue
+		}
+
+		w.trustedProxies = append(w.trustedProxies, network)
+	}
+
+	w.SetSecrets(append([]string{opt.Secret}, opt.Secrets...)...)
+
+	if w.ackMode == AckDefault {
+		w.ackMode = AckAfterProcess
+		if opt.Workers > 0 {
+			w.ackMode = AckBeforeProcess
+		}
+	}
+
+	if opt.Workers > 0 {
+		queueSize := opt.QueueSize
+		if queueSize <= 0 {
+			queueSize = 100
+		}
+
+		w.queue = make(chan webhookJob, queueSize)
+		for i := 0; i < opt.Workers; i++ {
+			w.pending.add()
+			go w.work()
+		}
+	}
+
+	return w
 }
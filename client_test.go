@@ -0,0 +1,109 @@
+package topgg
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"testing"
+)
+
+func gzipped(t *testing.T, data string) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	if _, err := gz.Write([]byte(data)); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := gz.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	return buf.Bytes()
+}
+
+func TestClientDecodesGzipResponse(t *testing.T) {
+	tests := []struct {
+		name    string
+		body    []byte
+		gzip    bool
+		wantErr bool
+	}{
+		{name: "gzip", body: gzipped(t, voteResponse), gzip: true},
+		{name: "plain", body: []byte(voteResponse)},
+		{name: "corrupted gzip", body: []byte("not gzip"), gzip: true, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if got := r.Header.Get("Accept-Encoding"); got != "gzip" {
+					t.Errorf("got Accept-Encoding %q, want gzip", got)
+				}
+
+				if tt.gzip {
+					w.Header().Set("Content-Encoding", "gzip")
+				}
+				_, _ = w.Write(tt.body)
+			}), ClientOptions{})
+
+			vote, err := client.GetVote(context.Background(), 1, PlatformDiscord)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("corrupted body was accepted")
+				}
+				return
+			}
+
+			if err != nil || vote.Weight != 1 {
+				t.Fatalf("GetVote = %+v, %v", vote, err)
+			}
+		})
+	}
+}
+
+func TestClientCompressesRequests(t *testing.T) {
+	tests := []struct {
+		name           string
+		threshold      int
+		wantCompressed bool
+	}{
+		{name: "disabled", threshold: 0},
+		{name: "body below threshold", threshold: 1 << 20},
+		{name: "body above threshold", threshold: 1, wantCompressed: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				var body io.Reader = r.Body
+				compressed := r.Header.Get("Content-Encoding") == "gzip"
+				if compressed {
+					gz, err := gzip.NewReader(r.Body)
+					if err != nil {
+						t.Errorf("invalid gzip body: %v", err)
+						return
+					}
+					body = gz
+				}
+
+				if compressed != tt.wantCompressed {
+					t.Errorf("body compressed: %v, want %v", compressed, tt.wantCompressed)
+				}
+
+				var payload map[string]int
+				if err := json.NewDecoder(body).Decode(&payload); err != nil || payload["server_count"] != 42 {
+					t.Errorf("server decoded %v, %v", payload, err)
+				}
+			}), ClientOptions{CompressionThreshold: tt.threshold})
+
+			if err := client.PostMetrics(context.Background(), MetricsPayload{ServerCount: 42}); err != nil {
+				t.Fatalf("PostMetrics: %v", err)
+			}
+		})
+	}
+}
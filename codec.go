@@ -0,0 +1,32 @@
+package topgg
+
+import (
+	"bytes"
+	"encoding/json"
+)
+
+// Encodes and decodes JSON of API requests, responses and webhook deliveries, e.g. to swap encoding/json
+// for faster implementation (json-iterator, sonic) in large bots. Implementations must honour encoding/json
+// struct tags and Marshaler/Unmarshaler methods, which Snowflake relies on.
+type Codec interface {
+	Marshal(v any) ([]byte, error)
+	Unmarshal(data []byte, v any) error
+}
+
+// Default Codec, backed by encoding/json. HTML characters aren't escaped, Top.gg renders content itself.
+type stdCodec struct{}
+
+func (stdCodec) Marshal(v any) ([]byte, error) {
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(v); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+func (stdCodec) Unmarshal(data []byte, v any) error {
+	return json.Unmarshal(data, v)
+}
@@ -1,8 +1,13 @@
 package topgg
 
+import "time"
+
 const (
 	BaseURL      = "https://top.gg/api"
 	SdkVersion   = "1.0.0" // Version of this library on github.
 	UserAgent    = "Top.gg SDK/" + SdkVersion + " https://github.com/top-gg-community/go-sdk"
 	DiscordEpoch = 1420070400000
+
+	DefaultAPIVersion   = "v1"
+	DefaultVoteValidity = 12 * time.Hour // How long votes last, see ClientOptions.VoteValidity.
 )
@@ -0,0 +1,59 @@
+package topgg
+
+import (
+	"sync"
+	"time"
+)
+
+// Allows one use per duration per user, e.g. to let users run vote check command once every 5 minutes.
+type Cooldown struct {
+	readyAt  *expiringMap[Snowflake, time.Time] // When user can use it again, dropped once that passes.
+	parent   *RateLimiter                       // Budget shared by all users, nil when there's none.
+	duration time.Duration
+	mu       sync.Mutex
+}
+
+func NewCooldown(duration time.Duration) *Cooldown {
+	return &Cooldown{
+		readyAt:  newExpiringMap[Snowflake, time.Time](0),
+		duration: duration,
+	}
+}
+
+// Like NewCooldown, but every use also takes one from parent, which caps uses across all users,
+// e.g. per-user vote checks on top of overall limit of API-backed checks.
+// Uses rejected by parent don't start user's cooldown.
+func NewScopedCooldown(duration time.Duration, parent *RateLimiter) *Cooldown {
+	c := NewCooldown(duration)
+	c.parent = parent
+	return c
+}
+
+// Takes use of given user. When user is still cooling down (or parent budget is exhausted), returns false and how long until next use.
+func (c *Cooldown) Check(userID Snowflake) (ok bool, retryIn time.Duration) {
+	now := time.Now()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if readyAt, found := c.readyAt.get(userID, now); found {
+		return false, readyAt.Sub(now)
+	}
+
+	if c.parent != nil {
+		if ok, retryIn := c.parent.AllowWithDelay(); !ok {
+			return false, retryIn
+		}
+	}
+
+	readyAt := now.Add(c.duration)
+	c.readyAt.set(userID, readyAt, readyAt, now)
+	return true, 0
+}
+
+// Ends cooldown of given user, e.g. when command failed and shouldn't count.
+func (c *Cooldown) Reset(userID Snowflake) {
+	c.mu.Lock()
+	c.readyAt.delete(userID)
+	c.mu.Unlock()
+}
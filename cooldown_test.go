@@ -0,0 +1,84 @@
+package topgg
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCooldown(t *testing.T) {
+	const duration = 50 * time.Millisecond
+
+	type check struct {
+		user   Snowflake
+		wait   time.Duration // Slept before check.
+		reset  bool          // Resets user before check.
+		wantOK bool
+	}
+
+	tests := []struct {
+		name        string
+		checks      []check
+		parentLimit int // 0 means unscoped cooldown.
+	}{
+		{
+			name:   "second use within duration is rejected",
+			checks: []check{{user: 1, wantOK: true}, {user: 1}},
+		},
+		{
+			name:   "users cool down independently",
+			checks: []check{{user: 1, wantOK: true}, {user: 2, wantOK: true}, {user: 1}},
+		},
+		{
+			name:   "use is allowed again after duration",
+			checks: []check{{user: 1, wantOK: true}, {user: 1, wait: duration, wantOK: true}, {user: 1}},
+		},
+		{
+			name:   "reset ends cooldown",
+			checks: []check{{user: 1, wantOK: true}, {user: 1, reset: true, wantOK: true}},
+		},
+		{
+			name:        "parent budget caps all users",
+			parentLimit: 1,
+			checks:      []check{{user: 1, wantOK: true}, {user: 2}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := NewCooldown(duration)
+			if tt.parentLimit > 0 {
+				c = NewScopedCooldown(duration, NewRateLimiter(RateLimiterOptions{Limit: tt.parentLimit}))
+			}
+
+			for i, check := range tt.checks {
+				time.Sleep(check.wait)
+				if check.reset {
+					c.Reset(check.user)
+				}
+
+				ok, retryIn := c.Check(check.user)
+				if ok != check.wantOK {
+					t.Fatalf("check %d of user %s got %v, want %v", i, check.user, ok, check.wantOK)
+				}
+
+				if !ok && (retryIn <= 0 || retryIn > time.Second) {
+					t.Fatalf("check %d got retry in %s", i, retryIn)
+				}
+			}
+		})
+	}
+}
+
+func TestCooldownDropsExpiredUsers(t *testing.T) {
+	c := NewCooldown(time.Millisecond)
+	for i := 0; i < 10; i++ {
+		c.Check(Snowflake(i))
+	}
+
+	time.Sleep(2 * time.Millisecond)
+	c.Check(100)
+
+	if n := c.readyAt.len(); n != 1 {
+		t.Fatalf("cooldown holds %d users, want 1", n)
+	}
+}
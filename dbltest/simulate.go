@@ -0,0 +1,207 @@
+// Package dbltest contains development tools for exercising Top.gg webhook handlers locally.
+package dbltest
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	topgg "github.com/top-gg-community/go-sdk"
+)
+
+type SimulateOptions struct {
+	HTTPClient      *http.Client         // Defaults to http.DefaultClient.
+	Secret          string               // Webhook secret used to sign deliveries, required.
+	SignatureHeader string               // Defaults to "x-topgg-signature".
+	Project         topgg.PartialProject // Project put into payloads.
+	Count           int                  // Total number of distinct deliveries, defaults to 100.
+	BurstSize       int                  // Deliveries sent concurrently with shuffled vote times, defaults to 1.
+	TestRatio       float64              // Fraction (0-1) of webhook.test deliveries among votes.
+	DuplicateRatio  float64              // Fraction (0-1) of deliveries sent again with identical body and signature.
+	Seed            int64                // Seed of generated data, 0 uses current time.
+}
+
+// Outcome of a simulation.
+type SimulateReport struct {
+	Statuses   map[int]int // Number of responses per status code.
+	Sent       int         // Requests sent, including duplicates.
+	Tests      int
+	Duplicates int
+	Failed     int // Requests that got no response.
+}
+
+type delivery struct {
+	header string
+	body   []byte
+}
+
+// Fires signed webhook deliveries at target URL, rate deliveries per second in total, until
+// opt.Count is sent or ctx is done. Returns ctx error in the latter case along with partial report.
+func SimulateVotes(ctx context.Context, target string, rate float64, opt SimulateOptions) (SimulateReport, error) {
+	report := SimulateReport{Statuses: make(map[int]int)}
+	if opt.Secret == "" {
+		return report, errors.New("dbltest: SimulateOptions.Secret is required")
+	}
+
+	if rate <= 0 {
+		return report, errors.New("dbltest: rate must be positive")
+	}
+
+	if opt.Count < 0 {
+		return report, errors.New("dbltest: SimulateOptions.Count must not be negative")
+	}
+
+	client := opt.HTTPClient
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	header := opt.SignatureHeader
+	if header == "" {
+		header = "x-topgg-signature"
+	}
+
+	count := opt.Count
+	if count == 0 {
+		count = 100
+	}
+
+	burstSize := opt.BurstSize
+	if burstSize <= 0 {
+		burstSize = 1
+	}
+
+	seed := opt.Seed
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+	rng := rand.New(rand.NewSource(seed))
+
+	ticker := time.NewTicker(time.Duration(float64(burstSize) / rate * float64(time.Second)))
+	defer ticker.Stop()
+
+	var mu sync.Mutex
+	for sent := 0; sent < count; {
+		var burst []delivery
+		now := time.Now()
+		for i := 0; i < burstSize && sent < count; i++ {
+			d, isTest, err := newDelivery(rng, opt, now)
+			if err != nil {
+				return report, err
+			}
+
+			burst = append(burst, d)
+			if isTest {
+				report.Tests++
+			}
+
+			if rng.Float64() < opt.DuplicateRatio {
+				burst = append(burst, d)
+				report.Duplicates++
+			}
+
+			sent++
+		}
+
+		rng.Shuffle(len(burst), func(i, j int) { burst[i], burst[j] = burst[j], burst[i] })
+
+		var wg sync.WaitGroup
+		for _, d := range burst {
+			wg.Add(1)
+			go func(d delivery) {
+				defer wg.Done()
+				status, err := send(ctx, client, target, header, d)
+
+				mu.Lock()
+				report.Sent++
+				if err != nil {
+					report.Failed++
+				} else {
+					report.Statuses[status]++
+				}
+				mu.Unlock()
+			}(d)
+		}
+		wg.Wait()
+
+		if sent == count {
+			break
+		}
+
+		select {
+		case <-ctx.Done():
+			return report, ctx.Err()
+		case <-ticker.C:
+		}
+	}
+
+	return report, nil
+}
+
+// Builds a signed delivery. Vote times are spread over the last minute, so bursts arrive out of order.
+func newDelivery(rng *rand.Rand, opt SimulateOptions, now time.Time) (delivery, bool, error) {
+	user := topgg.WebhookUser{
+		Name:       "user" + strconv.Itoa(rng.Intn(10000)),
+		ID:         topgg.Snowflake(rng.Int63()),
+		PlatformID: topgg.Snowflake(rng.Int63()),
+	}
+
+	var payload struct {
+		Data any         `json:"data"`
+		Type topgg.Scope `json:"type"`
+	}
+
+	isTest := rng.Float64() < opt.TestRatio
+	if isTest {
+		payload.Type = topgg.ScopeWebhookTest
+		payload.Data = topgg.WebhookTestPayload{Project: opt.Project, User: user}
+	} else {
+		votedAt := now.Add(-time.Duration(rng.Int63n(int64(time.Minute)))).UTC()
+		payload.Type = topgg.ScopeVoteCreate
+		payload.Data = topgg.VoteCreatePayload{
+			ID:        topgg.Snowflake(rng.Int63()),
+			VotedAt:   votedAt,
+			ExpiresAt: votedAt.Add(topgg.DefaultVoteValidity),
+			Weight:    1,
+			Project:   opt.Project,
+			User:      user,
+		}
+	}
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return delivery{}, false, fmt.Errorf("failed to marshal payload: %w", err)
+	}
+
+	signature, err := topgg.SignWebhook(opt.Secret, body, now)
+	if err != nil {
+		return delivery{}, false, err
+	}
+
+	return delivery{header: signature, body: body}, isTest, nil
+}
+
+func send(ctx context.Context, client *http.Client, target, header string, d delivery) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, target, bytes.NewReader(d.body))
+	if err != nil {
+		return 0, err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(header, d.header)
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+
+	resp.Body.Close()
+	return resp.StatusCode, nil
+}
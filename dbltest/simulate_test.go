@@ -0,0 +1,106 @@
+package dbltest
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	topgg "github.com/top-gg-community/go-sdk"
+)
+
+const testSecret = "test-secret"
+
+func TestSimulateVotes(t *testing.T) {
+	var votes, tests atomic.Int32
+	webhook := topgg.NewClient(topgg.ClientOptions{}).NewWebhookHandler(topgg.WebhookOptions{
+		Secret:        testSecret,
+		RejectReplays: true,
+		OnVote:        func(vote topgg.VoteCreatePayload) { votes.Add(1) },
+		OnTest:        func(test topgg.WebhookTestPayload) { tests.Add(1) },
+	})
+
+	srv := httptest.NewServer(webhook)
+	defer srv.Close()
+
+	opt := SimulateOptions{
+		Secret:         testSecret,
+		Count:          40,
+		BurstSize:      4,
+		TestRatio:      0.3,
+		DuplicateRatio: 0.3,
+		Seed:           1,
+	}
+
+	report, err := SimulateVotes(context.Background(), srv.URL, 1000, opt)
+	if err != nil {
+		t.Fatalf("SimulateVotes: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := webhook.Shutdown(ctx); err != nil {
+		t.Fatalf("Shutdown: %v", err)
+	}
+
+	if report.Tests == 0 || report.Duplicates == 0 {
+		t.Fatalf("report %+v has no tests or duplicates, pick another seed", report)
+	}
+
+	if report.Sent != opt.Count+report.Duplicates || report.Failed != 0 {
+		t.Errorf("sent %d requests with %d failures, want %d without failures", report.Sent, report.Failed, opt.Count+report.Duplicates)
+	}
+
+	// Duplicates are acknowledged too, but callbacks run only once per distinct delivery.
+	if len(report.Statuses) != 1 || report.Statuses[http.StatusOK] != report.Sent {
+		t.Errorf("got statuses %v, want all %d deliveries accepted", report.Statuses, report.Sent)
+	}
+
+	if int(tests.Load()) != report.Tests || int(votes.Load()) != opt.Count-report.Tests {
+		t.Errorf("handler got %d votes and %d tests, report has %d tests of %d deliveries", votes.Load(), tests.Load(), report.Tests, opt.Count)
+	}
+}
+
+func TestSimulateVotesWrongSecret(t *testing.T) {
+	webhook := topgg.NewClient(topgg.ClientOptions{}).NewWebhookHandler(topgg.WebhookOptions{Secret: testSecret})
+	srv := httptest.NewServer(webhook)
+	defer srv.Close()
+
+	report, err := SimulateVotes(context.Background(), srv.URL, 1000, SimulateOptions{Secret: "wrong-secret", Count: 5})
+	if err != nil {
+		t.Fatalf("SimulateVotes: %v", err)
+	}
+
+	if report.Statuses[http.StatusUnauthorized] != 5 {
+		t.Fatalf("got statuses %v, want 5 deliveries rejected with 401", report.Statuses)
+	}
+}
+
+func TestSimulateVotesInvalidOptions(t *testing.T) {
+	tests := []struct {
+		name string
+		opt  SimulateOptions
+		rate float64
+	}{
+		{name: "missing secret", rate: 10, opt: SimulateOptions{}},
+		{name: "zero rate", rate: 0, opt: SimulateOptions{Secret: testSecret}},
+		{name: "negative rate", rate: -1, opt: SimulateOptions{Secret: testSecret}},
+		{name: "negative count", rate: 10, opt: SimulateOptions{Secret: testSecret, Count: -1}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Nothing listens there, options must be rejected before anything is sent.
+			report, err := SimulateVotes(context.Background(), "http://127.0.0.1:1", tt.rate, tt.opt)
+			if err == nil {
+				t.Fatal("invalid options were accepted")
+			}
+
+			if report.Sent != 0 {
+				t.Fatalf("sent %d requests", report.Sent)
+			}
+		})
+	}
+}
@@ -0,0 +1,129 @@
+package topgg
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httputil"
+	"sync"
+	"time"
+)
+
+// Dumps every request/response pair going through it, with Authorization header masked.
+// Dumps are written to w and/or retained in transcripts, whichever is set.
+type debugTransport struct {
+	innerTransport http.RoundTripper
+	w              io.Writer
+	transcripts    *transcriptRing
+	mu             sync.Mutex
+}
+
+type streamedResponseKey struct{}
+
+// Marks ctx of a call whose response body is streamed, so debugTransport doesn't read it up front.
+func withStreamedResponse(ctx context.Context) context.Context {
+	return context.WithValue(ctx, streamedResponseKey{}, true)
+}
+
+func streamedResponse(ctx context.Context) bool {
+	v, _ := ctx.Value(streamedResponseKey{}).(bool)
+	return v
+}
+
+func (t *debugTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	// Compressed bodies aren't readable anyway, so only headers are dumped for them.
+	reqDump, dumpErr := httputil.DumpRequestOut(req, req.Header.Get("Content-Encoding") == "")
+	if dumpErr != nil {
+		reqDump = []byte(fmt.Sprintf("failed to dump request: %v\n", dumpErr))
+	}
+
+	start := time.Now()
+	resp, err := t.innerTransport.RoundTrip(req)
+	elapsed := time.Since(start)
+
+	var resDump []byte
+	switch {
+	case err != nil:
+		resDump = []byte(fmt.Sprintf("request failed: %v\n", err))
+	default:
+		// Streamed bodies would have to be buffered whole to be dumped, defeating the point of streaming.
+		body := resp.Header.Get("Content-Encoding") == "" && !streamedResponse(req.Context())
+		resDump, dumpErr = httputil.DumpResponse(resp, body)
+		if dumpErr != nil {
+			resDump = []byte(fmt.Sprintf("failed to dump response: %v\n", dumpErr))
+		}
+	}
+
+	dump := fmt.Sprintf("---- %s %s (%s) ----\n%s\n%s\n", req.Method, req.URL.Path, elapsed.Round(time.Millisecond), redactAuthorization(reqDump), resDump)
+	if t.transcripts != nil {
+		t.transcripts.add(start, dump)
+	}
+
+	if t.w != nil {
+		t.mu.Lock()
+		_, _ = io.WriteString(t.w, dump)
+		t.mu.Unlock()
+	}
+
+	return resp, err
+}
+
+// Masks value of Authorization header in raw HTTP dump.
+func redactAuthorization(dump []byte) []byte {
+	lines := bytes.Split(dump, []byte("\n"))
+	for i, line := range lines {
+		if len(line) > len("Authorization:") && bytes.EqualFold(line[:len("Authorization:")], []byte("Authorization:")) {
+			lines[i] = []byte("Authorization: [REDACTED]\r")
+		}
+	}
+
+	return bytes.Join(lines, []byte("\n"))
+}
+
+type transcript struct {
+	at   time.Time
+	dump string
+}
+
+// Fixed-size ring buffer of the latest redacted transcripts.
+type transcriptRing struct {
+	entries []transcript
+	next    int
+	mu      sync.Mutex
+	full    bool
+}
+
+func newTranscriptRing(size int) *transcriptRing {
+	return &transcriptRing{entries: make([]transcript, size)}
+}
+
+func (r *transcriptRing) add(at time.Time, dump string) {
+	r.mu.Lock()
+	r.entries[r.next] = transcript{at: at, dump: dump}
+	r.next++
+	if r.next == len(r.entries) {
+		r.next = 0
+		r.full = true
+	}
+	r.mu.Unlock()
+}
+
+// Writes retained transcripts to w, oldest first.
+func (r *transcriptRing) dump(w io.Writer) error {
+	r.mu.Lock()
+	entries := append([]transcript(nil), r.entries[:r.next]...)
+	if r.full {
+		entries = append(append([]transcript(nil), r.entries[r.next:]...), entries...)
+	}
+	r.mu.Unlock()
+
+	for _, entry := range entries {
+		if _, err := fmt.Fprintf(w, "[%s] %s\n", entry.at.UTC().Format(time.RFC3339Nano), entry.dump); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,67 @@
+package topgg
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestDebugDumpBodies(t *testing.T) {
+	const page = `{"cursor":"marker-cursor","data":[{"user_id":"1","platform_id":"1","created_at":"2026-01-01T00:00:00Z","expires_at":"2026-01-01T12:00:00Z","weight":1}]}`
+
+	tests := []struct {
+		name     string
+		stream   bool
+		gzip     bool
+		wantBody bool
+	}{
+		{name: "buffered", wantBody: true},
+		{name: "streamed", stream: true},
+		{name: "gzip", gzip: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var debug bytes.Buffer
+			client := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if !tt.gzip {
+					_, _ = w.Write([]byte(page))
+					return
+				}
+
+				w.Header().Set("Content-Encoding", "gzip")
+				gz := gzip.NewWriter(w)
+				_, _ = gz.Write([]byte(page))
+				_ = gz.Close()
+			}), ClientOptions{Debug: &debug})
+
+			var cursor string
+			var err error
+			if tt.stream {
+				cursor, err = client.StreamVotes(context.Background(), "", nil, func(vote Vote) bool { return true })
+			} else {
+				var votes *PaginatedVotes
+				votes, err = client.GetVotes(context.Background(), "", nil)
+				if votes != nil {
+					cursor = votes.Cursor
+				}
+			}
+
+			if err != nil || cursor != "marker-cursor" {
+				t.Fatalf("got cursor %q, %v", cursor, err)
+			}
+
+			dump := debug.String()
+			if !strings.Contains(dump, "200 OK") {
+				t.Fatalf("response headers weren't dumped:\n%s", dump)
+			}
+
+			if got := strings.Contains(dump, "marker-cursor"); got != tt.wantBody {
+				t.Fatalf("body dumped: %v, want %v:\n%s", got, tt.wantBody, dump)
+			}
+		})
+	}
+}
@@ -0,0 +1,130 @@
+package topgg
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// Default DiscordAnnouncerOptions.Template.
+const DefaultAnnouncementTemplate = "Thanks for voting, {{.User.Name}}!"
+
+type DiscordAnnouncerOptions struct {
+	HTTPClient *http.Client    // Defaults to client with 10 second timeout.
+	OnError    func(err error) // Called when announcement couldn't be rendered or posted.
+	WebhookURL string          // Discord channel webhook URL, required.
+	Template   string          // text/template rendered with VoteCreatePayload, defaults to DefaultAnnouncementTemplate.
+	Color      int             // Color of the embed, e.g. 0xFF3366.
+	Embed      bool            // Sends message as embed description instead of plain content.
+}
+
+// Posts a message to Discord channel for every vote, the classic "thanks for voting" feed.
+// Pass Announce as WebhookOptions.OnVote (or call it from your own callback). Mentions in messages are disabled.
+type DiscordAnnouncer struct {
+	httpClient  *http.Client
+	template    *template.Template
+	onError     func(err error)
+	traceLogger *log.Logger
+	webhookURL  string
+	color       int
+	embed       bool
+}
+
+func (c *Client) NewDiscordAnnouncer(opt DiscordAnnouncerOptions) (*DiscordAnnouncer, error) {
+	if opt.WebhookURL == "" {
+		return nil, errors.New("topgg: DiscordAnnouncerOptions.WebhookURL is required")
+	}
+
+	text := opt.Template
+	if text == "" {
+		text = DefaultAnnouncementTemplate
+	}
+
+	tmpl, err := template.New("announcement").Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse announcement template: %w", err)
+	}
+
+	httpClient := opt.HTTPClient
+	if httpClient == nil {
+		httpClient = &http.Client{Timeout: 10 * time.Second}
+	}
+
+	return &DiscordAnnouncer{
+		httpClient:  httpClient,
+		template:    tmpl,
+		onError:     opt.OnError,
+		traceLogger: c.traceLogger,
+		webhookURL:  opt.WebhookURL,
+		color:       opt.Color,
+		embed:       opt.Embed,
+	}, nil
+}
+
+func (a *DiscordAnnouncer) tracef(format string, v ...any) {
+	a.traceLogger.Printf("[DISCORD ANNOUNCER] "+format, v...)
+}
+
+// Posts announcement of given vote. Blocks until Discord responds, so with synchronous webhook
+// callbacks it delays acknowledging the delivery; consider WebhookOptions.Workers.
+func (a *DiscordAnnouncer) Announce(vote VoteCreatePayload) {
+	if err := a.announce(context.Background(), vote); err != nil {
+		a.tracef("Failed to announce vote of user %s: %v", vote.User.PlatformID, err)
+		if a.onError != nil {
+			if err := safeCall("discord announcer", "OnError", func() { a.onError(err) }); err != nil {
+				a.tracef("%v", err)
+			}
+		}
+	}
+}
+
+func (a *DiscordAnnouncer) announce(ctx context.Context, vote VoteCreatePayload) error {
+	var text strings.Builder
+	if err := a.template.Execute(&text, vote); err != nil {
+		return fmt.Errorf("failed to render announcement: %w", err)
+	}
+
+	message := map[string]any{
+		"allowed_mentions": map[string]any{"parse": []string{}},
+	}
+
+	if a.embed {
+		embed := map[string]any{"description": text.String()}
+		if a.color != 0 {
+			embed["color"] = a.color
+		}
+		message["embeds"] = []any{embed}
+	} else {
+		message["content"] = text.String()
+	}
+
+	body, err := json.Marshal(message)
+	if err != nil {
+		return fmt.Errorf("failed to encode announcement: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, a.webhookURL, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := a.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= http.StatusMultipleChoices {
+		return fmt.Errorf("discord responded with status %d", resp.StatusCode)
+	}
+
+	return nil
+}
@@ -0,0 +1,160 @@
+package topgg
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// Discord webhook message, as far as announcer fills it.
+type discordMessage struct {
+	Content string `json:"content"`
+	Embeds  []struct {
+		Description string `json:"description"`
+		Color       int    `json:"color"`
+	} `json:"embeds"`
+	AllowedMentions struct {
+		Parse []string `json:"parse"`
+	} `json:"allowed_mentions"`
+}
+
+func discordServer(t *testing.T, status int) (*httptest.Server, <-chan discordMessage) {
+	t.Helper()
+	messages := make(chan discordMessage, 10)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var message discordMessage
+		if err := json.NewDecoder(r.Body).Decode(&message); err != nil {
+			t.Errorf("failed to decode message: %v", err)
+		}
+
+		if ct := r.Header.Get("Content-Type"); ct != "application/json" {
+			t.Errorf("got Content-Type %q, want application/json", ct)
+		}
+
+		messages <- message
+		w.WriteHeader(status)
+	}))
+	t.Cleanup(srv.Close)
+
+	return srv, messages
+}
+
+func TestDiscordAnnouncer(t *testing.T) {
+	vote := VoteCreatePayload{User: WebhookUser{Name: "@everyone", PlatformID: 1}, Weight: 2}
+
+	tests := []struct {
+		name        string
+		template    string
+		wantContent string
+		wantEmbed   string
+		color       int
+		embed       bool
+	}{
+		{name: "default template", wantContent: "Thanks for voting, @everyone!"},
+		{name: "custom template", template: "{{.User.Name}} voted with weight {{.Weight}}", wantContent: "@everyone voted with weight 2"},
+		{name: "embed", embed: true, wantEmbed: "Thanks for voting, @everyone!"},
+		{name: "embed with color", embed: true, color: 0xFF3366, wantEmbed: "Thanks for voting, @everyone!"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv, messages := discordServer(t, http.StatusNoContent)
+			a, err := NewClient(ClientOptions{}).NewDiscordAnnouncer(DiscordAnnouncerOptions{
+				WebhookURL: srv.URL,
+				Template:   tt.template,
+				Embed:      tt.embed,
+				Color:      tt.color,
+				OnError:    func(err error) { t.Errorf("unexpected error: %v", err) },
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			a.Announce(vote)
+			message := <-messages
+
+			if message.AllowedMentions.Parse == nil || len(message.AllowedMentions.Parse) != 0 {
+				t.Errorf("mentions aren't disabled: %+v", message.AllowedMentions)
+			}
+
+			if message.Content != tt.wantContent {
+				t.Errorf("got content %q, want %q", message.Content, tt.wantContent)
+			}
+
+			if !tt.embed {
+				if len(message.Embeds) != 0 {
+					t.Errorf("plain message has embeds %+v", message.Embeds)
+				}
+				return
+			}
+
+			if len(message.Embeds) != 1 || message.Embeds[0].Description != tt.wantEmbed || message.Embeds[0].Color != tt.color {
+				t.Errorf("got embeds %+v, want %q with color %d", message.Embeds, tt.wantEmbed, tt.color)
+			}
+		})
+	}
+}
+
+func TestDiscordAnnouncerErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		template string
+		status   int
+	}{
+		{name: "discord error", status: http.StatusTooManyRequests},
+		{name: "template error", template: "{{.Missing}}", status: http.StatusNoContent},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv, _ := discordServer(t, tt.status)
+			var errs []error
+			a, err := NewClient(ClientOptions{}).NewDiscordAnnouncer(DiscordAnnouncerOptions{
+				WebhookURL: srv.URL,
+				Template:   tt.template,
+				OnError:    func(err error) { errs = append(errs, err) },
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			a.Announce(VoteCreatePayload{})
+			if len(errs) != 1 {
+				t.Fatalf("got errors %v, want 1", errs)
+			}
+		})
+	}
+}
+
+func TestDiscordAnnouncerOnErrorPanic(t *testing.T) {
+	srv, _ := discordServer(t, http.StatusInternalServerError)
+	a, err := NewClient(ClientOptions{}).NewDiscordAnnouncer(DiscordAnnouncerOptions{
+		WebhookURL: srv.URL,
+		OnError:    func(err error) { panic("boom") },
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Panic of OnError must not reach the webhook handler calling Announce.
+	a.Announce(VoteCreatePayload{})
+}
+
+func TestNewDiscordAnnouncerInvalidOptions(t *testing.T) {
+	tests := []struct {
+		name string
+		opt  DiscordAnnouncerOptions
+	}{
+		{name: "missing URL", opt: DiscordAnnouncerOptions{}},
+		{name: "broken template", opt: DiscordAnnouncerOptions{WebhookURL: "http://discord.invalid", Template: "{{.User"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := NewClient(ClientOptions{}).NewDiscordAnnouncer(tt.opt); err == nil {
+				t.Fatal("invalid options were accepted")
+			}
+		})
+	}
+}
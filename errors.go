@@ -1,10 +1,253 @@
 package topgg
 
-import "errors"
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"sync/atomic"
+	"time"
+)
 
 var (
-	ErrRequestFailed       = errors.New("remote request failed with non 200 status code")
-	ErrLocalRatelimit      = errors.New("exceeded local rate limit")
-	ErrRemoteRatelimit     = errors.New("exceeded remote rate limit")
-	ErrUnauthorizedRequest = errors.New("unauthorized request")
+	ErrRequestFailed       error = &sdkError{msg: "remote request failed with non 200 status code", temporary: true}
+	ErrLocalRatelimit      error = &sdkError{msg: "exceeded local rate limit", temporary: true}
+	ErrRemoteRatelimit     error = &sdkError{msg: "exceeded remote rate limit", temporary: true}
+	ErrUnauthorizedRequest error = &sdkError{msg: "unauthorized request"}
+	ErrInvalidPayload      error = &sdkError{msg: "invalid payload"}
+	ErrSchemaMismatch      error = &sdkError{msg: "payload doesn't match expected schema"}         // Only returned in strict decoding mode.
+	ErrBulkheadFull        error = &sdkError{msg: "endpoint group queue is full", temporary: true} // Returned when bulkhead of called endpoint has no free slot nor queue space, see ClientOptions.Bulkheads.
+	ErrUnavailable         error = &sdkError{msg: "top.gg is unavailable", temporary: true}        // Matched by errors of calls that kept failing with 5xx and of VoteChecker.HasVoted when Top.gg is unavailable, see IsUnavailable.
 )
+
+// Implemented by SDK errors that know whether retrying failed operation may succeed.
+type Retryable interface {
+	error
+	Temporary() bool
+}
+
+type sdkError struct {
+	msg       string
+	temporary bool
+}
+
+func (e *sdkError) Error() string {
+	return e.msg
+}
+
+func (e *sdkError) Temporary() bool {
+	return e.temporary
+}
+
+// Returned when call would have to wait longer than allowed by WithMaxRetryAfter or WithMaxTotalWait.
+// It wraps ErrRemoteRatelimit when wait was requested by Top.gg and ErrLocalRatelimit otherwise.
+type WaitLimitError struct {
+	Wait       time.Duration // Wait that exceeded the limit. For rate limiter waits cut short by the limit, it's the time waited so far.
+	Limit      time.Duration
+	RetryAfter bool // Whether wait was requested by Retry-After header of 429 response.
+}
+
+func (e *WaitLimitError) Error() string {
+	if e.RetryAfter {
+		return fmt.Sprintf("Top.gg asked to retry after %s, exceeding wait limit of %s", e.Wait, e.Limit)
+	}
+
+	return fmt.Sprintf("waiting %s exceeded wait limit of %s", e.Wait, e.Limit)
+}
+
+func (e *WaitLimitError) Unwrap() error {
+	if e.RetryAfter {
+		return ErrRemoteRatelimit
+	}
+
+	return ErrLocalRatelimit
+}
+
+// Reports whether err is worth retrying. SDK errors decide for themselves, other errors are temporary
+// when they're network timeouts or unavailability of Top.gg (see IsUnavailable).
+func IsTemporary(err error) bool {
+	// Checked by type rather than through Retryable, since errors of other packages (e.g. *url.Error)
+	// implement Temporary too and its answer is unreliable.
+	var unavailable *unavailableError
+	if errors.As(err, &unavailable) {
+		return true
+	}
+
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		return apiErr.Temporary()
+	}
+
+	var sdkErr *sdkError
+	if errors.As(err, &sdkErr) {
+		return sdkErr.Temporary()
+	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return true
+	}
+
+	return IsUnavailable(err)
+}
+
+// Reports whether err comes from Top.gg (or network path to it) being unavailable, rather than from
+// the request itself: timeouts of network round trips, 5xx responses, DNS and connection failures.
+// Cancelled calls and deadlines hit while waiting locally (rate limiter, bulkhead) aren't.
+func IsUnavailable(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) {
+		return false
+	}
+
+	if errors.Is(err, ErrUnavailable) {
+		return true
+	}
+
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		return apiErr.StatusCode >= http.StatusInternalServerError
+	}
+
+	var dnsErr *net.DNSError
+	var opErr *net.OpError
+	return errors.As(err, &dnsErr) || errors.As(err, &opErr)
+}
+
+type roundTripTimeoutKey struct{}
+
+// Marks ctx of a call, so rateLimitTransport can note that one of its round trips timed out.
+// Timeouts lose their cause once http.Client reports them, hence the side channel.
+func withRoundTripTimeout(ctx context.Context) (context.Context, *atomic.Bool) {
+	timedOut := &atomic.Bool{}
+	return context.WithValue(ctx, roundTripTimeoutKey{}, timedOut), timedOut
+}
+
+// Notes round trip failing with err as timed out, when it did.
+func markRoundTripTimeout(req *http.Request, err error) {
+	timedOut, ok := req.Context().Value(roundTripTimeoutKey{}).(*atomic.Bool)
+	if !ok {
+		return
+	}
+
+	var netErr net.Error
+	if errors.Is(err, context.DeadlineExceeded) || (errors.As(err, &netErr) && netErr.Timeout()) || errors.Is(req.Context().Err(), context.DeadlineExceeded) {
+		timedOut.Store(true)
+	}
+}
+
+// Marks err as caused by Top.gg availability, keeping it inspectable with errors.Is/As.
+type unavailableError struct {
+	err error
+}
+
+func (e *unavailableError) Error() string {
+	return "top.gg is unavailable: " + e.err.Error()
+}
+
+func (e *unavailableError) Temporary() bool {
+	return true
+}
+
+func (e *unavailableError) Is(target error) bool {
+	return target == ErrUnavailable
+}
+
+func (e *unavailableError) Unwrap() error {
+	return e.err
+}
+
+// Returned when retry budget ran out, wrapping error of the last attempt so e.g. IsUnavailable
+// still recognizes an outage. Matches ErrLocalRatelimit.
+type retryBudgetError struct {
+	last   error
+	window time.Duration
+	budget uint32
+}
+
+func (e *retryBudgetError) Error() string {
+	return fmt.Sprintf("%v: retry budget (%d per %s) exhausted: %v", ErrLocalRatelimit, e.budget, e.window, e.last)
+}
+
+func (e *retryBudgetError) Is(target error) bool {
+	return target == ErrLocalRatelimit
+}
+
+func (e *retryBudgetError) Unwrap() error {
+	return e.last
+}
+
+// Represents an error response returned by the Top.gg API.
+// It wraps either ErrUnauthorizedRequest or ErrRequestFailed, so errors.Is keeps working.
+type APIError struct {
+	Message    string          `json:"message"`
+	Errors     json.RawMessage `json:"errors,omitempty"`
+	Code       int             `json:"code"`
+	StatusCode int             `json:"-"`
+}
+
+func (e *APIError) Error() string {
+	msg := fmt.Sprintf("top.gg API error (%d %s)", e.StatusCode, http.StatusText(e.StatusCode))
+	if e.Message != "" {
+		msg += ": " + e.Message
+	}
+
+	if len(e.Errors) != 0 {
+		msg += " " + string(e.Errors)
+	}
+
+	return msg
+}
+
+// Rate limited and server side failures are temporary, other errors will fail again unless request changes.
+func (e *APIError) Temporary() bool {
+	return e.StatusCode == http.StatusTooManyRequests || e.StatusCode >= http.StatusInternalServerError
+}
+
+func (e *APIError) Unwrap() error {
+	if e.StatusCode == http.StatusUnauthorized || e.StatusCode == http.StatusForbidden {
+		return ErrUnauthorizedRequest
+	}
+
+	return ErrRequestFailed
+}
+
+// Builds APIError from raw response body. Top.gg may respond with either a plain
+// {"message": ...} object or a problem document ({"title": ..., "detail": ...}), and
+// in rare cases (proxies, gateways) with no JSON at all.
+func newAPIError(statusCode int, body []byte) *APIError {
+	apiErr := &APIError{StatusCode: statusCode, Code: statusCode}
+
+	var raw struct {
+		Message string          `json:"message"`
+		Detail  string          `json:"detail"`
+		Title   string          `json:"title"`
+		Errors  json.RawMessage `json:"errors"`
+		Code    int             `json:"code"`
+	}
+
+	if err := json.Unmarshal(body, &raw); err != nil {
+		apiErr.Message = string(body)
+		return apiErr
+	}
+
+	switch {
+	case raw.Message != "":
+		apiErr.Message = raw.Message
+	case raw.Detail != "":
+		apiErr.Message = raw.Detail
+	default:
+		apiErr.Message = raw.Title
+	}
+
+	if raw.Code != 0 {
+		apiErr.Code = raw.Code
+	}
+
+	if len(raw.Errors) != 0 && string(raw.Errors) != "null" {
+		apiErr.Errors = raw.Errors
+	}
+
+	return apiErr
+}
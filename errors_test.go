@@ -0,0 +1,195 @@
+package topgg
+
+import (
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+)
+
+// Network error claiming to be temporary without being a timeout, like some *url.Error values do.
+type temporaryNetError struct{}
+
+func (temporaryNetError) Error() string   { return "temporary" }
+func (temporaryNetError) Timeout() bool   { return false }
+func (temporaryNetError) Temporary() bool { return true }
+
+func urlError(err error) error {
+	return &url.Error{Op: "Get", URL: BaseURL, Err: err}
+}
+
+func TestIsTemporary(t *testing.T) {
+	tests := []struct {
+		err  error
+		name string
+		want bool
+	}{
+		{name: "nil", err: nil, want: false},
+		{name: "local rate limit", err: ErrLocalRatelimit, want: true},
+		{name: "unauthorized", err: ErrUnauthorizedRequest, want: false},
+		{name: "invalid payload", err: fmt.Errorf("%w: server_count cannot be negative", ErrInvalidPayload), want: false},
+		{name: "API error 400", err: newAPIError(400, nil), want: false},
+		{name: "API error 429", err: newAPIError(429, nil), want: true},
+		{name: "API error 503", err: newAPIError(503, nil), want: true},
+		{name: "wait limit", err: &WaitLimitError{Wait: time.Minute, Limit: time.Second}, want: true},
+		{name: "unavailable", err: &unavailableError{err: errors.New("boom")}, want: true},
+		{name: "SDK error inside url.Error", err: urlError(ErrUnauthorizedRequest), want: false},
+		{name: "url.Error claiming temporary", err: urlError(temporaryNetError{}), want: false},
+		{name: "connection refused", err: urlError(&net.OpError{Op: "dial", Err: errors.New("connection refused")}), want: true},
+		{name: "network timeout", err: urlError(context.DeadlineExceeded), want: true},
+		{name: "unrelated error", err: errors.New("boom"), want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IsTemporary(tt.err); got != tt.want {
+				t.Fatalf("IsTemporary(%v) = %v, want %v", tt.err, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIsUnavailable(t *testing.T) {
+	tests := []struct {
+		err  error
+		name string
+		want bool
+	}{
+		{name: "nil", err: nil, want: false},
+		{name: "cancelled", err: urlError(context.Canceled), want: false},
+		{name: "bare deadline", err: context.DeadlineExceeded, want: false},
+		{name: "unavailable", err: &unavailableError{err: context.DeadlineExceeded}, want: true},
+		{name: "API error 404", err: newAPIError(404, nil), want: false},
+		{name: "API error 502", err: newAPIError(502, nil), want: true},
+		{name: "DNS failure", err: urlError(&net.DNSError{Err: "no such host", Name: "top.gg"}), want: true},
+		{name: "connection refused", err: urlError(&net.OpError{Op: "dial", Err: errors.New("connection refused")}), want: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IsUnavailable(tt.err); got != tt.want {
+				t.Fatalf("IsUnavailable(%v) = %v, want %v", tt.err, got, tt.want)
+			}
+		})
+	}
+}
+
+// Limiter holding every call until its context is done.
+type blockingLimiter struct {
+	noopLimiter
+}
+
+func (blockingLimiter) Wait(ctx context.Context) error {
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+func TestIsUnavailableDeadlines(t *testing.T) {
+	slow := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-time.After(time.Second):
+		}
+	})
+
+	tests := []struct {
+		limiter Limiter
+		name    string
+		want    bool
+	}{
+		{name: "deadline during round trip", limiter: noopLimiter{}, want: true},
+		{name: "deadline in rate limiter", limiter: blockingLimiter{}, want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := newTestClient(t, slow, ClientOptions{Limiter: tt.limiter})
+			_, err := client.GetVote(context.Background(), 1, PlatformDiscord, NoRetry(), WithTimeout(50*time.Millisecond))
+			if !errors.Is(err, context.DeadlineExceeded) {
+				t.Fatalf("got %v, want deadline exceeded", err)
+			}
+
+			if got := IsUnavailable(err); got != tt.want {
+				t.Fatalf("IsUnavailable(%v) = %v, want %v", err, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewAPIError(t *testing.T) {
+	tests := []struct {
+		wantErrors string
+		name       string
+		body       string
+		wantMsg    string
+		status     int
+		wantCode   int
+	}{
+		{name: "message object", status: 400, body: `{"message":"bad things"}`, wantMsg: "bad things", wantCode: 400},
+		{name: "message with code and errors", status: 422, body: `{"message":"invalid","code":10,"errors":{"server_count":"required"}}`, wantMsg: "invalid", wantCode: 10, wantErrors: `{"server_count":"required"}`},
+		{name: "problem document detail", status: 404, body: `{"title":"Not Found","detail":"no such project"}`, wantMsg: "no such project", wantCode: 404},
+		{name: "problem document title", status: 403, body: `{"title":"Forbidden"}`, wantMsg: "Forbidden", wantCode: 403},
+		{name: "null errors are dropped", status: 400, body: `{"message":"bad","errors":null}`, wantMsg: "bad", wantCode: 400},
+		{name: "not JSON", status: 502, body: "<html>Bad Gateway</html>", wantMsg: "<html>Bad Gateway</html>", wantCode: 502},
+		{name: "empty body", status: 503, wantCode: 503},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			apiErr := newAPIError(tt.status, []byte(tt.body))
+			if apiErr.StatusCode != tt.status || apiErr.Code != tt.wantCode || apiErr.Message != tt.wantMsg || string(apiErr.Errors) != tt.wantErrors {
+				t.Fatalf("got %+v, want message %q, code %d, errors %q", apiErr, tt.wantMsg, tt.wantCode, tt.wantErrors)
+			}
+		})
+	}
+}
+
+func TestServerErrorKeepsAPIError(t *testing.T) {
+	const body = `{"message":"database is on fire"}`
+
+	tests := []struct {
+		name       string
+		compressed bool
+	}{
+		{name: "plain body"},
+		{name: "gzip body", compressed: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if !tt.compressed {
+					w.WriteHeader(http.StatusServiceUnavailable)
+					_, _ = w.Write([]byte(body))
+					return
+				}
+
+				w.Header().Set("Content-Encoding", "gzip")
+				w.WriteHeader(http.StatusServiceUnavailable)
+				gz := gzip.NewWriter(w)
+				_, _ = gz.Write([]byte(body))
+				_ = gz.Close()
+			}), ClientOptions{})
+
+			_, err := client.GetVote(context.Background(), 1, PlatformDiscord, NoRetry())
+
+			var apiErr *APIError
+			if !errors.As(err, &apiErr) {
+				t.Fatalf("got %v, want *APIError", err)
+			}
+
+			if apiErr.StatusCode != http.StatusServiceUnavailable || apiErr.Message != "database is on fire" {
+				t.Fatalf("got %+v, want 503 with message of Top.gg", apiErr)
+			}
+
+			if !IsUnavailable(err) || !errors.Is(err, ErrRequestFailed) {
+				t.Fatalf("got %v, want unavailable error matching ErrRequestFailed", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,121 @@
+package topgg
+
+import (
+	"container/heap"
+	"time"
+)
+
+// Map whose entries expire, optionally capped in size. Entries are kept in a min-heap by expiry,
+// so expired ones are dropped from the top and full map evicts the one closest to expiry,
+// both in O(log n) per entry, no matter how many entries there are. Not safe for concurrent use.
+type expiringMap[K comparable, V any] struct {
+	entries    map[K]*expiringEntry[K, V]
+	byExpiry   expiryHeap[K, V]
+	evictions  uint64
+	maxEntries int // 0 means no limit.
+}
+
+type expiringEntry[K comparable, V any] struct {
+	expiresAt time.Time
+	value     V
+	key       K
+	index     int // Position in heap.
+}
+
+func newExpiringMap[K comparable, V any](maxEntries int) *expiringMap[K, V] {
+	return &expiringMap[K, V]{
+		entries:    make(map[K]*expiringEntry[K, V]),
+		maxEntries: maxEntries,
+	}
+}
+
+// Returns value of key, unless it's missing or expired at now.
+func (m *expiringMap[K, V]) get(key K, now time.Time) (V, bool) {
+	entry, ok := m.entries[key]
+	if !ok || !now.Before(entry.expiresAt) {
+		var zero V
+		return zero, false
+	}
+
+	return entry.value, true
+}
+
+// Stores value of key until expiresAt, dropping expired entries and, when map is full, the one closest to expiry.
+func (m *expiringMap[K, V]) set(key K, value V, expiresAt, now time.Time) {
+	if entry, ok := m.entries[key]; ok {
+		entry.value = value
+		entry.expiresAt = expiresAt
+		heap.Fix(&m.byExpiry, entry.index)
+		return
+	}
+
+	m.sweep(now)
+	if m.maxEntries > 0 && len(m.entries) >= m.maxEntries {
+		m.remove(m.byExpiry[0])
+		m.evictions++
+	}
+
+	entry := &expiringEntry[K, V]{expiresAt: expiresAt, value: value, key: key}
+	heap.Push(&m.byExpiry, entry)
+	m.entries[key] = entry
+}
+
+// Extends expiry of existing key, doing nothing when it's missing.
+func (m *expiringMap[K, V]) touch(key K, expiresAt time.Time) {
+	if entry, ok := m.entries[key]; ok {
+		entry.expiresAt = expiresAt
+		heap.Fix(&m.byExpiry, entry.index)
+	}
+}
+
+func (m *expiringMap[K, V]) delete(key K) {
+	if entry, ok := m.entries[key]; ok {
+		m.remove(entry)
+	}
+}
+
+// Drops entries expired at now.
+func (m *expiringMap[K, V]) sweep(now time.Time) {
+	for len(m.byExpiry) > 0 && !now.Before(m.byExpiry[0].expiresAt) {
+		m.remove(m.byExpiry[0])
+	}
+}
+
+func (m *expiringMap[K, V]) remove(entry *expiringEntry[K, V]) {
+	heap.Remove(&m.byExpiry, entry.index)
+	delete(m.entries, entry.key)
+}
+
+func (m *expiringMap[K, V]) len() int {
+	return len(m.entries)
+}
+
+func (m *expiringMap[K, V]) memoryStats() MemoryStats {
+	return MemoryStats{Entries: len(m.entries), MaxEntries: m.maxEntries, Evictions: m.evictions}
+}
+
+// Implements heap.Interface, ordered by expiry.
+type expiryHeap[K comparable, V any] []*expiringEntry[K, V]
+
+func (h expiryHeap[K, V]) Len() int           { return len(h) }
+func (h expiryHeap[K, V]) Less(i, j int) bool { return h[i].expiresAt.Before(h[j].expiresAt) }
+
+func (h expiryHeap[K, V]) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+	h[i].index = i
+	h[j].index = j
+}
+
+func (h *expiryHeap[K, V]) Push(x any) {
+	entry := x.(*expiringEntry[K, V])
+	entry.index = len(*h)
+	*h = append(*h, entry)
+}
+
+func (h *expiryHeap[K, V]) Pop() any {
+	old := *h
+	entry := old[len(old)-1]
+	old[len(old)-1] = nil
+	*h = old[:len(old)-1]
+	return entry
+}
@@ -0,0 +1,125 @@
+package topgg
+
+import (
+	"testing"
+	"time"
+)
+
+func TestExpiringMap(t *testing.T) {
+	base := time.Unix(1_700_000_000, 0)
+	at := func(seconds int) time.Time { return base.Add(time.Duration(seconds) * time.Second) }
+
+	type op struct {
+		key       string
+		expiresAt int // Seconds after base.
+		touch     bool
+		now       int
+	}
+
+	tests := []struct {
+		name       string
+		ops        []op
+		present    []string
+		missing    []string
+		maxEntries int
+		checkAt    int
+		evictions  uint64
+	}{
+		{
+			name:    "expired entries are missing",
+			ops:     []op{{key: "a", expiresAt: 5}, {key: "b", expiresAt: 15}},
+			checkAt: 10,
+			present: []string{"b"},
+			missing: []string{"a"},
+		},
+		{
+			name: "sweep drops expired entries on insert",
+			ops: []op{
+				{key: "a", expiresAt: 5},
+				{key: "b", expiresAt: 6},
+				{key: "c", expiresAt: 20, now: 10},
+			},
+			checkAt: 10,
+			present: []string{"c"},
+			missing: []string{"a", "b"},
+		},
+		{
+			name:       "full map evicts entry closest to expiry",
+			maxEntries: 2,
+			ops: []op{
+				{key: "a", expiresAt: 30},
+				{key: "b", expiresAt: 10},
+				{key: "c", expiresAt: 20},
+			},
+			present:   []string{"a", "c"},
+			missing:   []string{"b"},
+			evictions: 1,
+		},
+		{
+			name:       "touch moves entry away from eviction",
+			maxEntries: 2,
+			ops: []op{
+				{key: "a", expiresAt: 10},
+				{key: "b", expiresAt: 20},
+				{key: "a", expiresAt: 30, touch: true},
+				{key: "c", expiresAt: 25},
+			},
+			present:   []string{"a", "c"},
+			missing:   []string{"b"},
+			evictions: 1,
+		},
+		{
+			name: "set replaces value and expiry of existing key",
+			ops: []op{
+				{key: "a", expiresAt: 5},
+				{key: "a", expiresAt: 50},
+			},
+			checkAt: 10,
+			present: []string{"a"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := newExpiringMap[string, string](tt.maxEntries)
+			for _, op := range tt.ops {
+				if op.touch {
+					m.touch(op.key, at(op.expiresAt))
+				} else {
+					m.set(op.key, op.key, at(op.expiresAt), at(op.now))
+				}
+			}
+
+			for _, key := range tt.present {
+				if value, ok := m.get(key, at(tt.checkAt)); !ok || value != key {
+					t.Errorf("get(%q) = %q, %v; want present", key, value, ok)
+				}
+			}
+
+			for _, key := range tt.missing {
+				if _, ok := m.get(key, at(tt.checkAt)); ok {
+					t.Errorf("get(%q) found entry that should be gone", key)
+				}
+			}
+
+			if stats := m.memoryStats(); stats.Evictions != tt.evictions {
+				t.Errorf("got %d evictions, want %d", stats.Evictions, tt.evictions)
+			}
+		})
+	}
+}
+
+func TestExpiringMapUniqueKeyFlood(t *testing.T) {
+	m := newExpiringMap[int, struct{}](0)
+	now := time.Unix(1_700_000_000, 0)
+
+	// Every key lives for a second; map only ever holds keys of the last second.
+	for i := 0; i < 100_000; i++ {
+		now = now.Add(time.Millisecond)
+		m.set(i, struct{}{}, now.Add(time.Second), now)
+	}
+
+	if n := m.len(); n > 1001 {
+		t.Fatalf("map holds %d entries, want at most 1001", n)
+	}
+}
@@ -0,0 +1,41 @@
+package topgg
+
+import "expvar"
+
+// Names of counters published when ClientOptions.Expvar is set.
+const (
+	ExpvarAPIRequests      = "api_requests"      // Attempts sent to Top.gg API, including retries.
+	ExpvarAPI429s          = "api_429s"          // Attempts rejected with 429 Too Many Requests.
+	ExpvarWebhookVotes     = "webhook_votes"     // Verified vote.create webhooks.
+	ExpvarAutopostFailures = "autopost_failures" // Failed MetricsPoster posts.
+)
+
+// Counters published via expvar, nil when disabled.
+type expvarCounters struct {
+	m *expvar.Map
+}
+
+// Publishes counters under given name. Existing map of the same name is reused,
+// since expvar doesn't allow publishing a name twice.
+func newExpvarCounters(name string) *expvarCounters {
+	if name == "" {
+		return nil
+	}
+
+	m, ok := expvar.Get(name).(*expvar.Map)
+	if !ok {
+		m = expvar.NewMap(name)
+	}
+
+	for _, key := range []string{ExpvarAPIRequests, ExpvarAPI429s, ExpvarWebhookVotes, ExpvarAutopostFailures} {
+		m.Add(key, 0)
+	}
+
+	return &expvarCounters{m: m}
+}
+
+func (c *expvarCounters) add(key string) {
+	if c != nil {
+		c.m.Add(key, 1)
+	}
+}
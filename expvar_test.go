@@ -0,0 +1,111 @@
+package topgg
+
+import (
+	"context"
+	"expvar"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+var expvarRuns atomic.Int64
+
+// Returns name not published yet, since expvar names can't be removed between runs of -count.
+func expvarName(prefix string) string {
+	return prefix + "_" + strconv.FormatInt(expvarRuns.Add(1), 10)
+}
+
+func expvarValue(t *testing.T, name, key string) int64 {
+	t.Helper()
+	m, ok := expvar.Get(name).(*expvar.Map)
+	if !ok {
+		t.Fatalf("expvar %q isn't published", name)
+	}
+
+	counter, ok := m.Get(key).(*expvar.Int)
+	if !ok {
+		t.Fatalf("counter %q isn't published under %q", key, name)
+	}
+
+	return counter.Value()
+}
+
+func TestExpvarCounters(t *testing.T) {
+	name := expvarName("topgg_test_counters")
+	client := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodPatch:
+			w.WriteHeader(http.StatusInternalServerError)
+		case strings.HasSuffix(r.URL.Path, "/votes/2"):
+			w.WriteHeader(http.StatusTooManyRequests)
+		default:
+			_, _ = w.Write([]byte(`{"created_at":"2026-01-01T00:00:00Z","expires_at":"2026-01-01T12:00:00Z","weight":1}`))
+		}
+	}), ClientOptions{
+		Expvar:      name,
+		ShouldRetry: func(resp *http.Response, err error, attempt int) bool { return false },
+	})
+
+	for _, key := range []string{ExpvarAPIRequests, ExpvarAPI429s, ExpvarWebhookVotes, ExpvarAutopostFailures} {
+		if v := expvarValue(t, name, key); v != 0 {
+			t.Fatalf("fresh counter %q is %d, want 0", key, v)
+		}
+	}
+
+	_, _ = client.GetVote(context.Background(), 1, PlatformDiscord)
+	_, _ = client.GetVote(context.Background(), 2, PlatformDiscord)
+	if requests, limited := expvarValue(t, name, ExpvarAPIRequests), expvarValue(t, name, ExpvarAPI429s); requests != 2 || limited != 1 {
+		t.Fatalf("got %d requests and %d 429s, want 2 and 1", requests, limited)
+	}
+
+	w := client.NewWebhookHandler(WebhookOptions{Secret: testSecret})
+	deliver(w, signedRequest(t, testSecret, voteBody(1), time.Now()))
+	deliver(w, signedRequest(t, "wrong-secret", voteBody(2), time.Now()))
+	if votes := expvarValue(t, name, ExpvarWebhookVotes); votes != 1 {
+		t.Fatalf("got %d webhook votes, want only the verified one", votes)
+	}
+
+	poster := client.NewMetricsPoster(MetricsPosterOptions{Interval: time.Hour})
+	defer poster.Close(context.Background())
+	poster.Submit(MetricsPayload{ServerCount: 1})
+
+	deadline := time.Now().Add(5 * time.Second)
+	for expvarValue(t, name, ExpvarAutopostFailures) != 1 {
+		if time.Now().After(deadline) {
+			t.Fatal("failed post wasn't counted")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestExpvarCountersShared(t *testing.T) {
+	name := expvarName("topgg_test_shared")
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"id":"1","name":"bot"}`))
+	})
+
+	// Second client must not panic on publishing the same name again.
+	for i := 0; i < 2; i++ {
+		client := newTestClient(t, handler, ClientOptions{Expvar: name})
+		if _, err := client.GetProject(context.Background()); err != nil {
+			t.Fatalf("GetProject: %v", err)
+		}
+	}
+
+	if requests := expvarValue(t, name, ExpvarAPIRequests); requests != 2 {
+		t.Fatalf("got %d requests, want both clients counted", requests)
+	}
+}
+
+func TestExpvarDisabled(t *testing.T) {
+	if c := newExpvarCounters(""); c != nil {
+		t.Fatalf("got counters %+v without name", c)
+	}
+
+	// Disabled counters are nil and must be safe to use.
+	var c *expvarCounters
+	c.add(ExpvarAPIRequests)
+}
@@ -0,0 +1,178 @@
+package topgg
+
+import (
+	"bufio"
+	"crypto/subtle"
+	"fmt"
+	"net"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+type MetricsHandlerOptions struct {
+	Webhooks       map[string]*Webhook // Handlers whose delivery stats are exported, keyed by value of their "webhook" label.
+	Username       string              // Enables basic auth when set.
+	Password       string
+	AllowedIPs     []string // CIDRs (or single IPs) allowed to scrape, empty allows everyone. Checked against client IP resolved the same way as Webhook.ClientIP.
+	TrustedProxies []string // Like WebhookOptions.TrustedProxies, proxies whose forwarding headers are honoured when checking AllowedIPs.
+}
+
+// Serves client latencies, rate limiter state and webhook stats in Prometheus text format.
+// Mount it next to your webhook handler, e.g. http.Handle("/metrics", handler).
+type MetricsHandler struct {
+	client     *Client
+	webhooks   map[string]*Webhook
+	username   string
+	password   string
+	allowedIPs []*net.IPNet
+	proxies    []*net.IPNet
+}
+
+func (c *Client) NewMetricsHandler(opt MetricsHandlerOptions) *MetricsHandler {
+	h := &MetricsHandler{
+		client:   c,
+		webhooks: opt.Webhooks,
+		username: opt.Username,
+		password: opt.Password,
+	}
+
+	for _, entry := range opt.AllowedIPs {
+		network, err := parseCIDR(entry)
+		if err != nil {
+			c.traceLogger.Printf("[METRICS HANDLER] Ignored invalid allowed IP %q: %v", entry, err)
+			continue
+		}
+
+		h.allowedIPs = append(h.allowedIPs, network)
+	}
+
+	for _, proxy := range opt.TrustedProxies {
+		network, err := parseCIDR(proxy)
+		if err != nil {
+			c.traceLogger.Printf("[METRICS HANDLER] Ignored invalid trusted proxy %q: %v", proxy, err)
+			continue
+		}
+
+		h.proxies = append(h.proxies, network)
+	}
+
+	return h
+}
+
+func (h *MetricsHandler) ServeHTTP(rw http.ResponseWriter, r *http.Request) {
+	if !h.allowed(r) {
+		http.Error(rw, http.StatusText(http.StatusForbidden), http.StatusForbidden)
+		return
+	}
+
+	if h.username != "" {
+		username, password, ok := r.BasicAuth()
+		if !ok || subtle.ConstantTimeCompare([]byte(username), []byte(h.username)) != 1 || subtle.ConstantTimeCompare([]byte(password), []byte(h.password)) != 1 {
+			rw.Header().Set("WWW-Authenticate", `Basic realm="metrics"`)
+			http.Error(rw, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+			return
+		}
+	}
+
+	rw.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	bw := bufio.NewWriter(rw)
+	h.write(bw)
+	_ = bw.Flush()
+}
+
+func (h *MetricsHandler) allowed(r *http.Request) bool {
+	if len(h.allowedIPs) == 0 {
+		return true
+	}
+
+	return inNetworks(h.allowedIPs, clientIP(r, h.proxies))
+}
+
+func (h *MetricsHandler) write(w *bufio.Writer) {
+	latencies := h.client.EndpointLatencies()
+	endpoints := make([]string, 0, len(latencies))
+	for endpoint := range latencies {
+		endpoints = append(endpoints, endpoint)
+	}
+	sort.Strings(endpoints)
+
+	writeHeader(w, "topgg_api_request_duration_seconds", "histogram", "Latency of Top.gg API attempts.")
+	for _, endpoint := range endpoints {
+		writeHistogram(w, "topgg_api_request_duration_seconds", `endpoint="`+escapeLabel(endpoint)+`"`, latencies[endpoint].Histogram)
+	}
+
+	if rl, ok := h.client.limiter.(*RateLimiter); ok {
+		snapshot := rl.Snapshot()
+		writeHeader(w, "topgg_rate_limit_remaining", "gauge", "Requests left in current rate limit window.")
+		fmt.Fprintf(w, "topgg_rate_limit_remaining %d\n", snapshot.Remaining)
+
+		suspended := 0
+		if !snapshot.GlobalWaitUntil.IsZero() {
+			suspended = 1
+		}
+		writeHeader(w, "topgg_rate_limit_suspended", "gauge", "Whether requests are suspended after 429 response.")
+		fmt.Fprintf(w, "topgg_rate_limit_suspended %d\n", suspended)
+	}
+
+	if len(h.webhooks) == 0 {
+		return
+	}
+
+	names := make([]string, 0, len(h.webhooks))
+	for name := range h.webhooks {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	stats := make([]WebhookStats, len(names))
+	for i, name := range names {
+		stats[i] = h.webhooks[name].Stats()
+	}
+
+	writeHeader(w, "topgg_webhook_deliveries_total", "counter", "Webhook deliveries by outcome.")
+	for i, name := range names {
+		label := `webhook="` + escapeLabel(name) + `"`
+		fmt.Fprintf(w, "topgg_webhook_deliveries_total{%s,result=\"received\"} %d\n", label, stats[i].Received)
+		fmt.Fprintf(w, "topgg_webhook_deliveries_total{%s,result=\"accepted\"} %d\n", label, stats[i].Accepted)
+		fmt.Fprintf(w, "topgg_webhook_deliveries_total{%s,result=\"rejected\"} %d\n", label, stats[i].Rejected)
+		fmt.Fprintf(w, "topgg_webhook_deliveries_total{%s,result=\"dropped\"} %d\n", label, stats[i].Dropped)
+	}
+
+	writeHeader(w, "topgg_webhook_queue_depth", "gauge", "Deliveries waiting in async dispatch queue.")
+	for i, name := range names {
+		fmt.Fprintf(w, "topgg_webhook_queue_depth{webhook=\"%s\"} %d\n", escapeLabel(name), stats[i].QueueDepth)
+	}
+
+	writeHeader(w, "topgg_webhook_callback_duration_seconds", "histogram", "Time spent in webhook callbacks.")
+	for i, name := range names {
+		writeHistogram(w, "topgg_webhook_callback_duration_seconds", `webhook="`+escapeLabel(name)+`"`, stats[i].HandlerLatency)
+	}
+}
+
+func writeHeader(w *bufio.Writer, name, kind, help string) {
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, kind)
+}
+
+// Writes histogram with cumulative buckets, as Prometheus expects.
+func writeHistogram(w *bufio.Writer, name, labels string, s HistogramSnapshot) {
+	var cumulative uint64
+	for _, bucket := range s.Buckets {
+		cumulative += bucket.Count
+		le := "+Inf"
+		if bucket.UpperBound != 0 {
+			le = strconv.FormatFloat(bucket.UpperBound.Seconds(), 'g', -1, 64)
+		}
+		fmt.Fprintf(w, "%s_bucket{%s,le=\"%s\"} %d\n", name, labels, le, cumulative)
+	}
+
+	fmt.Fprintf(w, "%s_sum{%s} %s\n", name, labels, strconv.FormatFloat(s.Sum.Seconds(), 'g', -1, 64))
+	fmt.Fprintf(w, "%s_count{%s} %d\n", name, labels, s.Count)
+}
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func escapeLabel(value string) string {
+	return labelEscaper.Replace(value)
+}
@@ -0,0 +1,128 @@
+package topgg
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func scrape(h http.Handler, req *http.Request) *httptest.ResponseRecorder {
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestMetricsHandlerFormat(t *testing.T) {
+	client := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(voteResponse))
+	}), ClientOptions{Limiter: NewRateLimiter(RateLimiterOptions{Limit: 10})})
+
+	if _, err := client.GetVote(context.Background(), 1, PlatformDiscord); err != nil {
+		t.Fatal(err)
+	}
+
+	webhook := client.NewWebhookHandler(WebhookOptions{Secret: testSecret})
+	deliver(webhook, signedRequest(t, testSecret, voteBody(1), time.Now()))
+	deliver(webhook, signedRequest(t, "wrong-secret", voteBody(2), time.Now()))
+
+	h := client.NewMetricsHandler(MetricsHandlerOptions{Webhooks: map[string]*Webhook{`main "bot"`: webhook}})
+	rec := scrape(h, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("got status %d, want 200", rec.Code)
+	}
+
+	if ct := rec.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/plain; version=0.0.4") {
+		t.Errorf("got Content-Type %q, want Prometheus text format", ct)
+	}
+
+	body := rec.Body.String()
+	for _, want := range []string{
+		"# TYPE topgg_api_request_duration_seconds histogram\n",
+		"topgg_api_request_duration_seconds_count{endpoint=",
+		`le="+Inf"} 1` + "\n",
+		"# TYPE topgg_rate_limit_remaining gauge\ntopgg_rate_limit_remaining 9\n",
+		"topgg_rate_limit_suspended 0\n",
+		"# TYPE topgg_webhook_deliveries_total counter\n",
+		`topgg_webhook_deliveries_total{webhook="main \"bot\"",result="received"} 2` + "\n",
+		`topgg_webhook_deliveries_total{webhook="main \"bot\"",result="accepted"} 1` + "\n",
+		`topgg_webhook_deliveries_total{webhook="main \"bot\"",result="rejected"} 1` + "\n",
+		`topgg_webhook_queue_depth{webhook="main \"bot\""} 0` + "\n",
+		"# TYPE topgg_webhook_callback_duration_seconds histogram\n",
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("metrics lack %q:\n%s", want, body)
+		}
+	}
+}
+
+func TestMetricsHandlerBasicAuth(t *testing.T) {
+	h := NewClient(ClientOptions{}).NewMetricsHandler(MetricsHandlerOptions{Username: "prometheus", Password: "hunter2"})
+
+	tests := []struct {
+		name     string
+		username string
+		password string
+		noAuth   bool
+		want     int
+	}{
+		{name: "valid credentials", username: "prometheus", password: "hunter2", want: http.StatusOK},
+		{name: "wrong password", username: "prometheus", password: "hunter3", want: http.StatusUnauthorized},
+		{name: "wrong username", username: "grafana", password: "hunter2", want: http.StatusUnauthorized},
+		{name: "no credentials", noAuth: true, want: http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+			if !tt.noAuth {
+				req.SetBasicAuth(tt.username, tt.password)
+			}
+
+			rec := scrape(h, req)
+			if rec.Code != tt.want {
+				t.Fatalf("got status %d, want %d", rec.Code, tt.want)
+			}
+
+			if tt.want == http.StatusUnauthorized && rec.Header().Get("WWW-Authenticate") == "" {
+				t.Error("challenge header is missing")
+			}
+		})
+	}
+}
+
+func TestMetricsHandlerAllowedIPs(t *testing.T) {
+	opt := MetricsHandlerOptions{AllowedIPs: []string{"10.0.0.0/8", "192.0.2.7", "not an ip"}, TrustedProxies: []string{"172.16.0.1"}}
+	h := NewClient(ClientOptions{}).NewMetricsHandler(opt)
+
+	tests := []struct {
+		header     map[string]string
+		name       string
+		remoteAddr string
+		want       int
+	}{
+		{name: "allowed network", remoteAddr: "10.1.2.3:1234", want: http.StatusOK},
+		{name: "allowed single IP", remoteAddr: "192.0.2.7:1234", want: http.StatusOK},
+		{name: "other IP", remoteAddr: "192.0.2.8:1234", want: http.StatusForbidden},
+		{name: "allowed client behind trusted proxy", remoteAddr: "172.16.0.1:1234", header: map[string]string{"X-Forwarded-For": "10.1.2.3"}, want: http.StatusOK},
+		{name: "other client behind trusted proxy", remoteAddr: "172.16.0.1:1234", header: map[string]string{"X-Forwarded-For": "10.1.2.3, 198.51.100.1"}, want: http.StatusForbidden},
+		{name: "X-Real-IP from trusted proxy", remoteAddr: "172.16.0.1:1234", header: map[string]string{"X-Real-IP": "10.1.2.3"}, want: http.StatusOK},
+		{name: "spoofed header from untrusted client", remoteAddr: "198.51.100.1:1234", header: map[string]string{"X-Forwarded-For": "10.1.2.3"}, want: http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+			req.RemoteAddr = tt.remoteAddr
+			for key, value := range tt.header {
+				req.Header.Set(key, value)
+			}
+
+			if rec := scrape(h, req); rec.Code != tt.want {
+				t.Fatalf("got status %d, want %d", rec.Code, tt.want)
+			}
+		})
+	}
+}
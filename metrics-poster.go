@@ -0,0 +1,394 @@
+package topgg
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+type MetricsPosterOptions struct {
+	OnError        func(err error) // Called when posting coalesced metrics fails.
+	OutboxFile     string          // When set, latest payload that failed to post is saved here and resent on next interval or next start, so outages don't leave stale counts behind.
+	QuietHours     []QuietWindow   // Posting is deferred while any window is active, latest submitted payload is sent once it ends. Close still posts right away.
+	Interval       time.Duration   // Minimal delay between posts, defaults to 1 minute.
+	Debounce       time.Duration   // When set, post waits until no payload was submitted for this long (but at most Interval), so bursts of guild join/leave events are sent once with the final value.
+	MinInterval    time.Duration   // Enables adaptive interval: delay halves (down to MinInterval) after posts that changed server count by at least ChurnThreshold, and doubles back (up to Interval) after ones that didn't.
+	ChurnThreshold float64         // Relative change of server count counted as churn by adaptive interval, defaults to 0.01 (1%).
+}
+
+// Daily time window, e.g. maintenance, during which MetricsPoster doesn't post.
+// Start and End are offsets from midnight, window crosses midnight when End is before Start.
+type QuietWindow struct {
+	Location *time.Location // Defaults to time.Local.
+	Start    time.Duration
+	End      time.Duration
+}
+
+// Reports whether window is active at given time and when it ends.
+func (w QuietWindow) end(now time.Time) (time.Time, bool) {
+	loc := w.Location
+	if loc == nil {
+		loc = time.Local
+	}
+
+	// Offsets are wall clock times, so days with DST change don't shift the window.
+	now = now.In(loc)
+	year, month, day := now.Date()
+	hour, min, sec := now.Clock()
+	offset := time.Duration(hour)*time.Hour + time.Duration(min)*time.Minute + time.Duration(sec)*time.Second + time.Duration(now.Nanosecond())
+
+	switch {
+	case w.Start <= w.End:
+		return atOffset(year, month, day, w.End, loc), offset >= w.Start && offset < w.End
+	case offset >= w.Start:
+		return atOffset(year, month, day+1, w.End, loc), true
+	default:
+		return atOffset(year, month, day, w.End, loc), offset < w.End
+	}
+}
+
+// Returns wall clock time of given day at offset from midnight.
+func atOffset(year int, month time.Month, day int, offset time.Duration, loc *time.Location) time.Time {
+	return time.Date(year, month, day, int(offset/time.Hour), int(offset%time.Hour/time.Minute), int(offset%time.Minute/time.Second), int(offset%time.Second), loc)
+}
+
+// Coalesces frequent metric updates (e.g. on every guild join/leave) so only the latest
+// payload is sent, at most once per interval, without blocking callers on the rate limiter.
+type MetricsPoster struct {
+	client      *Client
+	onError     func(err error)
+	pending     *MetricsPayload
+	traceLogger *log.Logger
+	ctx         context.Context
+	cancel      context.CancelFunc
+	waitCtx     context.Context // Cancelled on Close, ends delay between posts.
+	stopWaiting context.CancelFunc
+	notify      chan struct{}
+	done        chan struct{}
+	stopped     chan struct{}
+	outboxFile  string
+	quietHours  []QuietWindow
+	interval    time.Duration
+	debounce    time.Duration
+	minInterval time.Duration
+	current     time.Duration // Delay after latest post, only accessed by run goroutine.
+	churn       float64
+	lastCount   int // Server count of latest successful post.
+	closeOnce   sync.Once
+	mu          sync.Mutex
+	closed      bool
+}
+
+func (c *Client) NewMetricsPoster(opt MetricsPosterOptions) *MetricsPoster {
+	interval := opt.Interval
+	if interval <= 0 {
+		interval = time.Minute
+	}
+
+	churn := opt.ChurnThreshold
+	if churn <= 0 {
+		churn = 0.01
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	waitCtx, stopWaiting := context.WithCancel(ctx)
+	p := &MetricsPoster{
+		client:      c,
+		onError:     opt.OnError,
+		traceLogger: c.traceLogger,
+		ctx:         ctx,
+		cancel:      cancel,
+		waitCtx:     waitCtx,
+		stopWaiting: stopWaiting,
+		notify:      make(chan struct{}, 1),
+		done:        make(chan struct{}),
+		stopped:     make(chan struct{}),
+		interval:    interval,
+		debounce:    opt.Debounce,
+		current:     interval,
+		churn:       churn,
+		quietHours:  opt.QuietHours,
+		outboxFile:  opt.OutboxFile,
+	}
+
+	if opt.MinInterval > 0 && opt.MinInterval < interval {
+		p.minInterval = opt.MinInterval
+	}
+
+	if p.outboxFile != "" {
+		if err := p.loadOutbox(); err != nil {
+			p.tracef("Failed to load metrics outbox: %v", err)
+		}
+	}
+
+	go p.run()
+	return p
+}
+
+func (p *MetricsPoster) tracef(format string, v ...any) {
+	p.traceLogger.Printf("[METRICS POSTER] "+format, v...)
+}
+
+// Queues payload, replacing any payload that wasn't sent yet. Never blocks.
+func (p *MetricsPoster) Submit(payload MetricsPayload) {
+	p.mu.Lock()
+	if p.closed {
+		p.mu.Unlock()
+		p.tracef("Ignored metrics submitted after Close")
+		return
+	}
+
+	p.pending = &payload
+	p.mu.Unlock()
+
+	select {
+	case p.notify <- struct{}{}:
+	default:
+	}
+}
+
+// Stops the poster, sending latest pending payload first.
+// If ctx is done before that final post finishes, it's cancelled and ctx error is returned.
+func (p *MetricsPoster) Close(ctx context.Context) error {
+	p.closeOnce.Do(func() {
+		p.mu.Lock()
+		p.closed = true
+		p.mu.Unlock()
+		close(p.done)
+		p.stopWaiting()
+	})
+
+	select {
+	case <-p.stopped:
+		p.cancel()
+		return nil
+	case <-ctx.Done():
+		p.cancel()
+		return ctx.Err()
+	}
+}
+
+func (p *MetricsPoster) run() {
+	defer close(p.stopped)
+
+	for {
+		select {
+		case <-p.notify:
+		case <-p.done:
+			p.flush()
+			return
+		}
+
+		if p.debounce > 0 && !p.settle() {
+			p.flush()
+			return
+		}
+
+		if !p.waitQuietHours() {
+			p.flush()
+			return
+		}
+
+		p.flush()
+
+		if !p.wait(p.current) {
+			p.flush()
+			return
+		}
+	}
+}
+
+// Waits for d through Scheduler of the client, so posts follow ClientOptions.Scheduler like other
+// periodic work. When scheduler fails or returns early, rest of d is waited on a timer so posting
+// doesn't stop. Returns false when poster was closed meanwhile.
+func (p *MetricsPoster) wait(d time.Duration) bool {
+	start := time.Now()
+	var elapsed atomic.Bool
+	err := p.client.scheduler.After(p.waitCtx, d, func(ctx context.Context) {
+		elapsed.Store(true)
+	})
+
+	if elapsed.Load() {
+		return true
+	}
+
+	if p.waitCtx.Err() != nil {
+		return false
+	}
+
+	p.tracef("Scheduler returned before post delay passed (%v), waiting on timer instead", err)
+	timer := time.NewTimer(d - time.Since(start))
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return true
+	case <-p.done:
+		return false
+	}
+}
+
+// Waits until submissions stop for debounce duration, or interval passes.
+// Returns false when poster was closed meanwhile.
+func (p *MetricsPoster) settle() bool {
+	deadline := time.NewTimer(p.interval)
+	defer deadline.Stop()
+
+	for {
+		quiet := time.NewTimer(p.debounce)
+		select {
+		case <-p.notify:
+			quiet.Stop()
+		case <-quiet.C:
+			return true
+		case <-deadline.C:
+			quiet.Stop()
+			return true
+		case <-p.done:
+			quiet.Stop()
+			return false
+		}
+	}
+}
+
+// Waits until no quiet window is active. Returns false when poster was closed meanwhile.
+func (p *MetricsPoster) waitQuietHours() bool {
+	for {
+		var end time.Time
+		now := time.Now()
+		for _, window := range p.quietHours {
+			if windowEnd, active := window.end(now); active && windowEnd.After(end) {
+				end = windowEnd
+			}
+		}
+
+		if end.IsZero() {
+			return true
+		}
+
+		p.tracef("Deferring post until quiet hours end at %s", end.Format(time.RFC3339))
+		timer := time.NewTimer(end.Sub(now))
+		select {
+		case <-timer.C:
+		case <-p.done:
+			timer.Stop()
+			return false
+		}
+	}
+}
+
+func (p *MetricsPoster) flush() {
+	p.mu.Lock()
+	payload := p.pending
+	p.pending = nil
+	p.mu.Unlock()
+
+	if payload == nil {
+		return
+	}
+
+	if err := p.client.PostMetrics(p.ctx, *payload); err != nil {
+		p.tracef("Failed to post metrics: %v", err)
+		p.client.counters.add(ExpvarAutopostFailures)
+		p.client.sink.Count(MetricMetricsPosterPosts, 1, "result:error")
+		p.client.sink.Count(MetricMetricsPosterFailed, 1)
+		if p.onError != nil {
+			if err := safeCall("metrics poster", "OnError", func() { p.onError(err) }); err != nil {
+				p.tracef("%v", err)
+			}
+		}
+
+		if p.outboxFile != "" && !errors.Is(err, ErrInvalidPayload) && !errors.Is(err, ErrUnauthorizedRequest) {
+			p.requeue(*payload)
+		}
+		return
+	}
+
+	p.client.sink.Count(MetricMetricsPosterPosts, 1, "result:ok")
+	p.adapt(*payload)
+	if p.outboxFile != "" {
+		if err := os.Remove(p.outboxFile); err != nil && !errors.Is(err, os.ErrNotExist) {
+			p.tracef("Failed to clear metrics outbox: %v", err)
+		}
+	}
+}
+
+// Adjusts delay before next post to churn of server count, when adaptive interval is enabled.
+func (p *MetricsPoster) adapt(payload MetricsPayload) {
+	previous := p.lastCount
+	p.lastCount = payload.ServerCount
+	if p.minInterval == 0 || previous == 0 {
+		return
+	}
+
+	change := math.Abs(float64(payload.ServerCount-previous)) / float64(previous)
+	next := p.current
+	if change >= p.churn {
+		next /= 2
+		if next < p.minInterval {
+			next = p.minInterval
+		}
+	} else {
+		next *= 2
+		if next > p.interval {
+			next = p.interval
+		}
+	}
+
+	if next != p.current {
+		p.tracef("Posting interval changed from %s to %s", p.current, next)
+		p.current = next
+	}
+}
+
+// Puts failed payload back unless newer one was submitted meanwhile and saves it to the outbox.
+func (p *MetricsPoster) requeue(payload MetricsPayload) {
+	p.mu.Lock()
+	if p.pending == nil {
+		p.pending = &payload
+	}
+	latest := *p.pending
+	p.mu.Unlock()
+
+	b, err := json.Marshal(latest)
+	if err == nil {
+		err = writeFileAtomic(p.outboxFile, b)
+	}
+
+	if err != nil {
+		p.tracef("Failed to save metrics outbox: %v", err)
+	}
+
+	select {
+	case p.notify <- struct{}{}:
+	default:
+	}
+}
+
+// Queues payload left in the outbox by previous run.
+func (p *MetricsPoster) loadOutbox() error {
+	b, err := os.ReadFile(p.outboxFile)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		return err
+	}
+
+	var payload MetricsPayload
+	if err := json.Unmarshal(b, &payload); err != nil {
+		return fmt.Errorf("failed to decode metrics outbox: %w", err)
+	}
+
+	p.tracef("Resending metrics left in outbox")
+	p.pending = &payload
+	p.notify <- struct{}{}
+	return nil
+}
@@ -0,0 +1,519 @@
+package topgg
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+	_ "time/tzdata"
+)
+
+// Scheduler recording requested intervals and delays, running every job right away, once.
+type instantScheduler struct {
+	intervals []time.Duration
+	mu        sync.Mutex
+}
+
+func (s *instantScheduler) record(d time.Duration) {
+	s.mu.Lock()
+	s.intervals = append(s.intervals, d)
+	s.mu.Unlock()
+}
+
+func (s *instantScheduler) Every(ctx context.Context, interval time.Duration, job func(ctx context.Context)) error {
+	s.record(interval)
+	job(ctx)
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+func (s *instantScheduler) After(ctx context.Context, delay time.Duration, job func(ctx context.Context)) error {
+	s.record(delay)
+	job(ctx)
+	return nil
+}
+
+// Scheduler failing every job, like one whose backend went away.
+type failingScheduler struct{}
+
+var errSchedulerDown = errors.New("scheduler is down")
+
+func (failingScheduler) Every(ctx context.Context, interval time.Duration, job func(ctx context.Context)) error {
+	return errSchedulerDown
+}
+
+func (failingScheduler) After(ctx context.Context, delay time.Duration, job func(ctx context.Context)) error {
+	return errSchedulerDown
+}
+
+func TestMetricsPosterUsesScheduler(t *testing.T) {
+	posted := make(chan struct{}, 10)
+	scheduler := &instantScheduler{}
+	client := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		posted <- struct{}{}
+	}), ClientOptions{Scheduler: scheduler})
+
+	poster := client.NewMetricsPoster(MetricsPosterOptions{Interval: time.Hour})
+	defer poster.Close(context.Background())
+
+	// Second post would wait an hour on a real timer.
+	for i := 1; i <= 2; i++ {
+		poster.Submit(MetricsPayload{ServerCount: i})
+		select {
+		case <-posted:
+		case <-time.After(time.Second):
+			t.Fatalf("post %d wasn't sent", i)
+		}
+	}
+
+	scheduler.mu.Lock()
+	defer scheduler.mu.Unlock()
+	if len(scheduler.intervals) == 0 || scheduler.intervals[0] != time.Hour {
+		t.Fatalf("scheduler got intervals %v, want 1h", scheduler.intervals)
+	}
+}
+
+func TestMetricsPosterSurvivesFailingScheduler(t *testing.T) {
+	posted := make(chan struct{}, 10)
+	client := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		posted <- struct{}{}
+	}), ClientOptions{Scheduler: failingScheduler{}})
+
+	poster := client.NewMetricsPoster(MetricsPosterOptions{Interval: 20 * time.Millisecond})
+	defer poster.Close(context.Background())
+
+	for i := 1; i <= 3; i++ {
+		poster.Submit(MetricsPayload{ServerCount: i})
+		select {
+		case <-posted:
+		case <-time.After(time.Second):
+			t.Fatalf("post %d wasn't sent", i)
+		}
+	}
+}
+
+func TestMetricsPosterCloseDuringDelay(t *testing.T) {
+	client := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}), ClientOptions{})
+	poster := client.NewMetricsPoster(MetricsPosterOptions{Interval: time.Hour})
+	poster.Submit(MetricsPayload{ServerCount: 1})
+
+	// Gives the first post time to go out, so run is waiting out the hour.
+	time.Sleep(50 * time.Millisecond)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := poster.Close(ctx); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+}
+
+func TestQuietWindowEnd(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	at := func(day, hour, min int) time.Time { return time.Date(2026, time.March, day, hour, min, 0, 0, berlin) }
+	window := QuietWindow{Location: berlin, Start: 4 * time.Hour, End: 5 * time.Hour}
+	overnight := QuietWindow{Location: berlin, Start: 23 * time.Hour, End: 4 * time.Hour}
+
+	tests := []struct {
+		now        time.Time
+		wantEnd    time.Time
+		name       string
+		window     QuietWindow
+		wantActive bool
+	}{
+		{name: "inside window", window: window, now: at(28, 4, 30), wantEnd: at(28, 5, 0), wantActive: true},
+		{name: "before window", window: window, now: at(28, 3, 30), wantEnd: at(28, 5, 0)},
+		{name: "after window", window: window, now: at(28, 5, 0), wantEnd: at(28, 5, 0)},
+		// Clocks jump from 2:00 to 3:00 on 29 March, so only 3.5 hours have passed since midnight at 4:30.
+		{name: "inside window on DST day", window: window, now: at(29, 4, 30), wantEnd: at(29, 5, 0), wantActive: true},
+		{name: "before window on DST day", window: window, now: at(29, 3, 30), wantEnd: at(29, 5, 0)},
+		{name: "overnight before midnight", window: overnight, now: at(28, 23, 30), wantEnd: at(29, 4, 0), wantActive: true},
+		{name: "overnight after midnight", window: overnight, now: at(29, 3, 30), wantEnd: at(29, 4, 0), wantActive: true},
+		{name: "overnight outside", window: overnight, now: at(29, 12, 0), wantEnd: at(29, 4, 0)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			end, active := tt.window.end(tt.now)
+			if active != tt.wantActive || !end.Equal(tt.wantEnd) {
+				t.Fatalf("got end %s, active %v; want %s, %v", end, active, tt.wantEnd, tt.wantActive)
+			}
+		})
+	}
+}
+
+// Handler of PostMetrics responding with given status, sending decoded payloads to the channel.
+func metricsHandler(t *testing.T, status int, posted chan<- MetricsPayload) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var payload MetricsPayload
+		if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+			t.Errorf("failed to decode payload: %v", err)
+		}
+
+		posted <- payload
+		w.WriteHeader(status)
+	})
+}
+
+func readOutbox(t *testing.T, path string) (MetricsPayload, bool) {
+	t.Helper()
+	b, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return MetricsPayload{}, false
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var payload MetricsPayload
+	if err := json.Unmarshal(b, &payload); err != nil {
+		t.Fatalf("outbox holds %q: %v", b, err)
+	}
+
+	return payload, true
+}
+
+func TestMetricsPosterOutbox(t *testing.T) {
+	outbox := filepath.Join(t.TempDir(), "outbox.json")
+	noRetry := func(resp *http.Response, err error, attempt int) bool { return false }
+
+	// First run can't reach Top.gg, payload stays in the outbox.
+	failed := make(chan MetricsPayload, 10)
+	client := newTestClient(t, metricsHandler(t, http.StatusInternalServerError, failed), ClientOptions{ShouldRetry: noRetry})
+	poster := client.NewMetricsPoster(MetricsPosterOptions{Interval: time.Hour, OutboxFile: outbox})
+	poster.Submit(MetricsPayload{ServerCount: 42})
+	<-failed
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		if payload, ok := readOutbox(t, outbox); ok {
+			if payload.ServerCount != 42 {
+				t.Fatalf("outbox holds %+v, want 42 servers", payload)
+			}
+			break
+		}
+
+		if time.Now().After(deadline) {
+			t.Fatal("failed payload wasn't saved to outbox")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := poster.Close(ctx); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	// Next start resends it without anything being submitted, then clears the outbox.
+	posted := make(chan MetricsPayload, 10)
+	client = newTestClient(t, metricsHandler(t, http.StatusOK, posted), ClientOptions{})
+	poster = client.NewMetricsPoster(MetricsPosterOptions{Interval: time.Hour, OutboxFile: outbox})
+	defer poster.Close(context.Background())
+
+	select {
+	case payload := <-posted:
+		if payload.ServerCount != 42 {
+			t.Fatalf("resent %+v, want 42 servers", payload)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("outbox wasn't resent on start")
+	}
+
+	deadline = time.Now().Add(5 * time.Second)
+	for {
+		if _, ok := readOutbox(t, outbox); !ok {
+			break
+		}
+
+		if time.Now().After(deadline) {
+			t.Fatal("outbox wasn't cleared after successful post")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestMetricsPosterOutboxSkipsPermanentErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		payload MetricsPayload
+		status  int
+	}{
+		{name: "invalid payload", payload: MetricsPayload{ServerCount: -1}, status: http.StatusOK},
+		{name: "unauthorized", payload: MetricsPayload{ServerCount: 1}, status: http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			outbox := filepath.Join(t.TempDir(), "outbox.json")
+			failed := make(chan error, 10)
+			client := newTestClient(t, metricsHandler(t, tt.status, make(chan MetricsPayload, 10)), ClientOptions{})
+			poster := client.NewMetricsPoster(MetricsPosterOptions{
+				Interval:   time.Hour,
+				OutboxFile: outbox,
+				OnError:    func(err error) { failed <- err },
+			})
+			defer poster.Close(context.Background())
+
+			poster.Submit(tt.payload)
+			select {
+			case <-failed:
+			case <-time.After(5 * time.Second):
+				t.Fatal("post didn't fail")
+			}
+
+			// Requeue happens right after OnError, on the same goroutine.
+			time.Sleep(50 * time.Millisecond)
+			if payload, ok := readOutbox(t, outbox); ok {
+				t.Fatalf("payload %+v that can never succeed was saved to outbox", payload)
+			}
+		})
+	}
+}
+
+func TestMetricsPosterRequeueKeepsNewerPayload(t *testing.T) {
+	tests := []struct {
+		pending *MetricsPayload
+		name    string
+		want    int
+	}{
+		{name: "nothing newer", want: 1},
+		{name: "newer submitted", pending: &MetricsPayload{ServerCount: 2}, want: 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			outbox := filepath.Join(t.TempDir(), "outbox.json")
+			p := &MetricsPoster{
+				traceLogger: NewClient(ClientOptions{}).traceLogger,
+				notify:      make(chan struct{}, 1),
+				outboxFile:  outbox,
+				pending:     tt.pending,
+			}
+
+			p.requeue(MetricsPayload{ServerCount: 1})
+			if p.pending == nil || p.pending.ServerCount != tt.want {
+				t.Fatalf("pending payload is %+v, want %d servers", p.pending, tt.want)
+			}
+
+			if payload, ok := readOutbox(t, outbox); !ok || payload.ServerCount != tt.want {
+				t.Fatalf("outbox holds %+v, want %d servers", payload, tt.want)
+			}
+
+			select {
+			case <-p.notify:
+			default:
+				t.Fatal("requeue didn't wake up the poster")
+			}
+		})
+	}
+}
+
+func TestMetricsPosterCorruptedOutbox(t *testing.T) {
+	outbox := filepath.Join(t.TempDir(), "outbox.json")
+	if err := os.WriteFile(outbox, []byte("{broken"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	posted := make(chan MetricsPayload, 10)
+	client := newTestClient(t, metricsHandler(t, http.StatusOK, posted), ClientOptions{})
+	poster := client.NewMetricsPoster(MetricsPosterOptions{Interval: time.Hour, OutboxFile: outbox})
+	defer poster.Close(context.Background())
+
+	select {
+	case payload := <-posted:
+		t.Fatalf("posted %+v from corrupted outbox", payload)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	poster.Submit(MetricsPayload{ServerCount: 3})
+	select {
+	case payload := <-posted:
+		if payload.ServerCount != 3 {
+			t.Fatalf("posted %+v, want 3 servers", payload)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("poster with corrupted outbox stopped posting")
+	}
+}
+
+func TestMetricsPosterDebounce(t *testing.T) {
+	posted := make(chan MetricsPayload, 10)
+	client := newTestClient(t, metricsHandler(t, http.StatusOK, posted), ClientOptions{})
+	poster := client.NewMetricsPoster(MetricsPosterOptions{Interval: time.Hour, Debounce: 50 * time.Millisecond})
+	defer poster.Close(context.Background())
+
+	// Burst of guild joins is sent once, with the final count.
+	for i := 1; i <= 5; i++ {
+		poster.Submit(MetricsPayload{ServerCount: i})
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	select {
+	case payload := <-posted:
+		if payload.ServerCount != 5 {
+			t.Fatalf("posted %+v, want final count of the burst", payload)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("burst wasn't posted after settling")
+	}
+
+	select {
+	case payload := <-posted:
+		t.Fatalf("burst was posted again with %+v", payload)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+func TestMetricsPosterDebounceCappedByInterval(t *testing.T) {
+	posted := make(chan MetricsPayload, 10)
+	client := newTestClient(t, metricsHandler(t, http.StatusOK, posted), ClientOptions{})
+	poster := client.NewMetricsPoster(MetricsPosterOptions{Interval: 100 * time.Millisecond, Debounce: 50 * time.Millisecond})
+	defer poster.Close(context.Background())
+
+	// Submissions never pause for the debounce duration, post still goes out after interval.
+	stop := time.After(2 * time.Second)
+	for i := 1; ; i++ {
+		poster.Submit(MetricsPayload{ServerCount: i})
+		select {
+		case <-posted:
+			return
+		case <-stop:
+			t.Fatal("constant submissions held back posting past interval")
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+}
+
+func TestMetricsPosterCloseWhileSettling(t *testing.T) {
+	posted := make(chan MetricsPayload, 10)
+	client := newTestClient(t, metricsHandler(t, http.StatusOK, posted), ClientOptions{})
+	poster := client.NewMetricsPoster(MetricsPosterOptions{Interval: time.Hour, Debounce: time.Hour})
+	poster.Submit(MetricsPayload{ServerCount: 7})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := poster.Close(ctx); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	select {
+	case payload := <-posted:
+		if payload.ServerCount != 7 {
+			t.Fatalf("posted %+v on Close, want 7 servers", payload)
+		}
+	default:
+		t.Fatal("Close didn't post payload that was settling")
+	}
+}
+
+func TestMetricsPosterAdapt(t *testing.T) {
+	tests := []struct {
+		name        string
+		counts      []int // Server counts of consecutive successful posts.
+		minInterval time.Duration
+		want        time.Duration
+	}{
+		{name: "first post keeps interval", counts: []int{100}, minInterval: time.Minute, want: 8 * time.Minute},
+		{name: "churn halves", counts: []int{100, 110}, minInterval: time.Minute, want: 4 * time.Minute},
+		{name: "churn down halves", counts: []int{100, 90}, minInterval: time.Minute, want: 4 * time.Minute},
+		{name: "halving stops at minimum", counts: []int{100, 200, 400, 800, 1600, 3200}, minInterval: time.Minute, want: time.Minute},
+		{name: "change below threshold", counts: []int{1000, 1005}, minInterval: time.Minute, want: 8 * time.Minute},
+		{name: "calm doubles back", counts: []int{100, 200, 400, 400}, minInterval: time.Minute, want: 4 * time.Minute},
+		{name: "doubling stops at interval", counts: []int{100, 200, 200, 200, 200}, minInterval: time.Minute, want: 8 * time.Minute},
+		{name: "disabled", counts: []int{100, 200}, want: 8 * time.Minute},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := &MetricsPoster{
+				traceLogger: NewClient(ClientOptions{}).traceLogger,
+				interval:    8 * time.Minute,
+				current:     8 * time.Minute,
+				minInterval: tt.minInterval,
+				churn:       0.01,
+			}
+
+			for _, count := range tt.counts {
+				p.adapt(MetricsPayload{ServerCount: count})
+			}
+
+			if p.current != tt.want {
+				t.Fatalf("interval is %s, want %s", p.current, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewMetricsPosterAdaptiveOptions(t *testing.T) {
+	tests := []struct {
+		name            string
+		opt             MetricsPosterOptions
+		wantMinInterval time.Duration
+		wantChurn       float64
+	}{
+		{name: "enabled", opt: MetricsPosterOptions{Interval: time.Hour, MinInterval: time.Minute}, wantMinInterval: time.Minute, wantChurn: 0.01},
+		{name: "custom threshold", opt: MetricsPosterOptions{Interval: time.Hour, MinInterval: time.Minute, ChurnThreshold: 0.1}, wantMinInterval: time.Minute, wantChurn: 0.1},
+		{name: "minimum not below interval", opt: MetricsPosterOptions{Interval: time.Minute, MinInterval: time.Hour}, wantChurn: 0.01},
+		{name: "disabled", opt: MetricsPosterOptions{Interval: time.Hour}, wantChurn: 0.01},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := NewClient(ClientOptions{}).NewMetricsPoster(tt.opt)
+			defer p.Close(context.Background())
+
+			if p.minInterval != tt.wantMinInterval || p.churn != tt.wantChurn {
+				t.Fatalf("got minimum %s and threshold %v, want %s and %v", p.minInterval, p.churn, tt.wantMinInterval, tt.wantChurn)
+			}
+		})
+	}
+}
+
+func TestMetricsPosterAdaptiveInterval(t *testing.T) {
+	scheduler := &instantScheduler{}
+	posted := make(chan MetricsPayload, 10)
+	client := newTestClient(t, metricsHandler(t, http.StatusOK, posted), ClientOptions{Scheduler: scheduler})
+	poster := client.NewMetricsPoster(MetricsPosterOptions{Interval: time.Hour, MinInterval: time.Minute})
+	defer poster.Close(context.Background())
+
+	for _, count := range []int{100, 200, 400} {
+		poster.Submit(MetricsPayload{ServerCount: count})
+		select {
+		case <-posted:
+		case <-time.After(5 * time.Second):
+			t.Fatalf("post of %d servers wasn't sent", count)
+		}
+	}
+
+	// Delay after each post comes from the interval adapted to that post.
+	want := []time.Duration{time.Hour, 30 * time.Minute, 15 * time.Minute}
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		scheduler.mu.Lock()
+		intervals := append([]time.Duration(nil), scheduler.intervals...)
+		scheduler.mu.Unlock()
+
+		if len(intervals) >= len(want) {
+			for i := range want {
+				if intervals[i] != want[i] {
+					t.Fatalf("waited %v between posts, want %v", intervals, want)
+				}
+			}
+			return
+		}
+
+		if time.Now().After(deadline) {
+			t.Fatalf("waited %v between posts, want %v", intervals, want)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
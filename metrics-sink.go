@@ -0,0 +1,99 @@
+package topgg
+
+import (
+	"net"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Receives SDK instrumentation, for teams using StatsD, Datadog or similar instead of Prometheus.
+// Tags are "key:value" pairs. Implementations must be safe for concurrent use and shouldn't block.
+type MetricsSink interface {
+	Count(name string, value int64, tags ...string)
+	Gauge(name string, value float64, tags ...string)
+	Timing(name string, d time.Duration, tags ...string)
+}
+
+// Names of metrics reported to MetricsSink.
+const (
+	MetricAPIRequests         = "api.requests"          // Count per attempt, tagged with method, path (IDs replaced with ":id") and status.
+	MetricAPILatency          = "api.latency"           // Timing per attempt, tagged with method and path.
+	MetricAPIRetries          = "api.retries"           // Count of retried attempts, tagged with method and path.
+	MetricRateLimitWait       = "ratelimit.wait"        // Timing of time spent in RateLimiter.Wait.
+	MetricRateLimitSuspended  = "ratelimit.suspended"   // Count of 429 suspensions.
+	MetricWebhookRequests     = "webhook.requests"      // Count of deliveries, tagged with response status.
+	MetricWebhookRejections   = "webhook.rejections"    // Count of rejected deliveries, tagged with RejectionReason.
+	MetricWebhookLatency      = "webhook.latency"       // Timing of webhook callbacks.
+	MetricWebhookQueueDepth   = "webhook.queue_depth"   // Gauge of async dispatch queue depth.
+	MetricWebhookDropped      = "webhook.dropped"       // Count of deliveries dropped from full queue.
+	MetricMetricsPosterPosts  = "metrics_poster.posts"  // Count of MetricsPoster posts, tagged with result (ok or error).
+	MetricMetricsPosterFailed = "metrics_poster.failed" // Count of failed MetricsPoster posts.
+)
+
+type nopSink struct{}
+
+func (nopSink) Count(string, int64, ...string)          {}
+func (nopSink) Gauge(string, float64, ...string)        {}
+func (nopSink) Timing(string, time.Duration, ...string) {}
+
+// MetricsSink sending metrics over UDP in StatsD line format.
+// Datadog flavour (DogStatsD) also sends tags, plain StatsD ignores them.
+type StatsdSink struct {
+	conn   net.Conn
+	prefix string
+	tags   bool
+}
+
+// Creates sink sending plain StatsD metrics to given address (e.g. "127.0.0.1:8125").
+// Prefix is prepended to every metric name, e.g. "mybot.topgg.".
+func NewStatsdSink(addr, prefix string) (*StatsdSink, error) {
+	return newStatsdSink(addr, prefix, false)
+}
+
+// Creates sink sending DogStatsD metrics (StatsD with tags) to Datadog agent at given address.
+func NewDatadogSink(addr, prefix string) (*StatsdSink, error) {
+	return newStatsdSink(addr, prefix, true)
+}
+
+func newStatsdSink(addr, prefix string, tags bool) (*StatsdSink, error) {
+	conn, err := net.Dial("udp", addr)
+	if err != nil {
+		return nil, err
+	}
+
+	return &StatsdSink{conn: conn, prefix: prefix, tags: tags}, nil
+}
+
+func (s *StatsdSink) Count(name string, value int64, tags ...string) {
+	s.send(name, strconv.FormatInt(value, 10), "c", tags)
+}
+
+func (s *StatsdSink) Gauge(name string, value float64, tags ...string) {
+	s.send(name, strconv.FormatFloat(value, 'f', -1, 64), "g", tags)
+}
+
+func (s *StatsdSink) Timing(name string, d time.Duration, tags ...string) {
+	s.send(name, strconv.FormatFloat(float64(d)/float64(time.Millisecond), 'f', -1, 64), "ms", tags)
+}
+
+func (s *StatsdSink) Close() error {
+	return s.conn.Close()
+}
+
+// Writes single metric as its own datagram. Delivery errors are ignored, as usual for StatsD.
+func (s *StatsdSink) send(name, value, kind string, tags []string) {
+	var b strings.Builder
+	b.WriteString(s.prefix)
+	b.WriteString(name)
+	b.WriteByte(':')
+	b.WriteString(value)
+	b.WriteByte('|')
+	b.WriteString(kind)
+	if s.tags && len(tags) > 0 {
+		b.WriteString("|#")
+		b.WriteString(strings.Join(tags, ","))
+	}
+
+	_, _ = s.conn.Write([]byte(b.String()))
+}
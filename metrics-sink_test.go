@@ -0,0 +1,234 @@
+package topgg
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// Sends requests aimed at Top.gg to test server instead.
+type redirectTransport struct {
+	target *url.URL
+}
+
+func (rt redirectTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	req.URL.Scheme = rt.target.Scheme
+	req.URL.Host = rt.target.Host
+	return http.DefaultTransport.RoundTrip(req)
+}
+
+// Client talking to given handler, with rate limiter out of the way.
+func newTestClient(t *testing.T, handler http.Handler, opt ClientOptions) *Client {
+	t.Helper()
+
+	srv := httptest.NewServer(handler)
+	t.Cleanup(srv.Close)
+
+	target, err := url.Parse(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	opt.Token = "test-token"
+	opt.Transport = redirectTransport{target: target}
+	if opt.Limiter == nil {
+		opt.Limiter = noopLimiter{}
+	}
+
+	return NewClient(opt)
+}
+
+type noopLimiter struct{}
+
+func (noopLimiter) Wait(ctx context.Context) error { return nil }
+func (noopLimiter) SetGlobalWait(d time.Duration)  {}
+
+type recordingSink struct {
+	names []string
+	tags  []string
+	mu    sync.Mutex
+}
+
+func (s *recordingSink) record(name string, tags []string) {
+	s.mu.Lock()
+	s.names = append(s.names, name)
+	s.tags = append(s.tags, tags...)
+	s.mu.Unlock()
+}
+
+// Reports how many times metric was recorded.
+func (s *recordingSink) count(name string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var n int
+	for _, recorded := range s.names {
+		if recorded == name {
+			n++
+		}
+	}
+
+	return n
+}
+
+func (s *recordingSink) hasTag(tag string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, recorded := range s.tags {
+		if recorded == tag {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (s *recordingSink) Count(name string, value int64, tags ...string)      { s.record(name, tags) }
+func (s *recordingSink) Gauge(name string, value float64, tags ...string)    { s.record(name, tags) }
+func (s *recordingSink) Timing(name string, d time.Duration, tags ...string) { s.record(name, tags) }
+
+func TestMetricTagsTemplatePath(t *testing.T) {
+	sink := &recordingSink{}
+	client := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"created_at":"2026-01-01T00:00:00Z","expires_at":"2026-01-01T12:00:00Z","weight":1}`))
+	}), ClientOptions{MetricsSink: sink})
+
+	for _, userID := range []Snowflake{1, 2, 3} {
+		if _, err := client.GetVote(context.Background(), userID, PlatformDiscord); err != nil {
+			t.Fatalf("GetVote: %v", err)
+		}
+	}
+
+	sink.mu.Lock()
+	defer sink.mu.Unlock()
+
+	want := "path:/api/" + DefaultAPIVersion + "/projects/@me/votes/:id"
+	seen := false
+	for _, tag := range sink.tags {
+		if strings.HasPrefix(tag, "path:") {
+			if tag != want {
+				t.Fatalf("got path tag %q, want %q", tag, want)
+			}
+			seen = true
+		}
+	}
+
+	if !seen {
+		t.Fatal("no path tag reported")
+	}
+}
+
+func TestStatsdSink(t *testing.T) {
+	tests := []struct {
+		newSink func(addr, prefix string) (*StatsdSink, error)
+		name    string
+		want    []string
+	}{
+		{name: "statsd", newSink: NewStatsdSink, want: []string{
+			"bot.api.requests:3|c",
+			"bot.webhook.queue_depth:2.5|g",
+			"bot.api.latency:1.5|ms",
+			"bot.webhook.dropped:1|c",
+		}},
+		{name: "datadog", newSink: NewDatadogSink, want: []string{
+			"bot.api.requests:3|c|#method:GET,status:200",
+			"bot.webhook.queue_depth:2.5|g",
+			"bot.api.latency:1.5|ms|#path:/api/v1/projects/@me",
+			"bot.webhook.dropped:1|c",
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer conn.Close()
+
+			sink, err := tt.newSink(conn.LocalAddr().String(), "bot.")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer sink.Close()
+
+			sink.Count(MetricAPIRequests, 3, "method:GET", "status:200")
+			sink.Gauge(MetricWebhookQueueDepth, 2.5)
+			sink.Timing(MetricAPILatency, 1500*time.Microsecond, "path:/api/v1/projects/@me")
+			sink.Count(MetricWebhookDropped, 1)
+
+			buf := make([]byte, 512)
+			for _, want := range tt.want {
+				_ = conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+				n, _, err := conn.ReadFrom(buf)
+				if err != nil {
+					t.Fatalf("no datagram for %q: %v", want, err)
+				}
+
+				if got := string(buf[:n]); got != want {
+					t.Fatalf("got datagram %q, want %q", got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestNewStatsdSinkInvalidAddress(t *testing.T) {
+	if _, err := NewStatsdSink("not an address", ""); err == nil {
+		t.Fatal("invalid address was accepted")
+	}
+}
+
+func TestClientReportsMetrics(t *testing.T) {
+	sink := &recordingSink{}
+	client := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"id":"1","name":"bot"}`))
+	}), ClientOptions{MetricsSink: sink})
+
+	if _, err := client.GetProject(context.Background()); err != nil {
+		t.Fatalf("GetProject: %v", err)
+	}
+
+	if sink.count(MetricAPIRequests) != 1 || sink.count(MetricAPILatency) != 1 {
+		t.Fatalf("got metrics %v, want one request and its latency", sink.names)
+	}
+
+	if !sink.hasTag("method:GET") || !sink.hasTag("status:200") {
+		t.Fatalf("got tags %v, want method and status", sink.tags)
+	}
+}
+
+func TestWebhookReportsMetrics(t *testing.T) {
+	sink := &recordingSink{}
+	w := NewClient(ClientOptions{MetricsSink: sink}).NewWebhookHandler(WebhookOptions{
+		Secret: testSecret,
+		OnVote: func(vote VoteCreatePayload) {},
+	})
+
+	deliver(w, signedRequest(t, testSecret, voteBody(1), time.Now()))
+	deliver(w, signedRequest(t, "wrong-secret", voteBody(2), time.Now()))
+
+	if n := sink.count(MetricWebhookRequests); n != 2 {
+		t.Errorf("got %d webhook requests, want 2", n)
+	}
+
+	if n := sink.count(MetricWebhookRejections); n != 1 {
+		t.Errorf("got %d rejections, want 1", n)
+	}
+
+	if n := sink.count(MetricWebhookLatency); n != 1 {
+		t.Errorf("got %d callback timings, want 1", n)
+	}
+
+	if !sink.hasTag("status:200") || !sink.hasTag("status:401") || !sink.hasTag("reason:"+string(RejectBadAuth)) {
+		t.Errorf("got tags %v, want both statuses and rejection reason", sink.tags)
+	}
+}
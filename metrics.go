@@ -0,0 +1,197 @@
+package topgg
+
+import (
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Default upper bounds of latency histogram buckets.
+var latencyBuckets = []time.Duration{
+	time.Millisecond,
+	5 * time.Millisecond,
+	10 * time.Millisecond,
+	25 * time.Millisecond,
+	50 * time.Millisecond,
+	100 * time.Millisecond,
+	250 * time.Millisecond,
+	500 * time.Millisecond,
+	time.Second,
+	2500 * time.Millisecond,
+	5 * time.Second,
+	10 * time.Second,
+}
+
+// Lock-free latency histogram with fixed buckets.
+type Histogram struct {
+	bounds []time.Duration
+	counts []atomic.Uint64 // len(bounds)+1, last one counts observations above highest bound.
+	count  atomic.Uint64
+	sum    atomic.Int64
+}
+
+type HistogramBucket struct {
+	UpperBound time.Duration // Zero for the overflow bucket.
+	Count      uint64        // Observations in this bucket only (not cumulative).
+}
+
+type HistogramSnapshot struct {
+	Buckets []HistogramBucket
+	Sum     time.Duration
+	Count   uint64
+}
+
+func newLatencyHistogram() *Histogram {
+	return &Histogram{
+		bounds: latencyBuckets,
+		counts: make([]atomic.Uint64, len(latencyBuckets)+1),
+	}
+}
+
+func (h *Histogram) Observe(d time.Duration) {
+	i := 0
+	for i < len(h.bounds) && d > h.bounds[i] {
+		i++
+	}
+
+	h.counts[i].Add(1)
+	h.count.Add(1)
+	h.sum.Add(int64(d))
+}
+
+func (h *Histogram) Snapshot() HistogramSnapshot {
+	snapshot := HistogramSnapshot{
+		Buckets: make([]HistogramBucket, len(h.counts)),
+		Count:   h.count.Load(),
+		Sum:     time.Duration(h.sum.Load()),
+	}
+
+	for i := range h.counts {
+		if i < len(h.bounds) {
+			snapshot.Buckets[i].UpperBound = h.bounds[i]
+		}
+		snapshot.Buckets[i].Count = h.counts[i].Load()
+	}
+
+	return snapshot
+}
+
+// Size of in-memory store or cache, see MemoryVoteStore.MemoryStats and Webhook.MemoryStats.
+type MemoryStats struct {
+	Entries    int
+	MaxEntries int    // 0 means no limit.
+	Evictions  uint64 // Entries dropped to stay within MaxEntries.
+}
+
+// Counters of webhook deliveries since handler creation.
+type WebhookStats struct {
+	HandlerLatency HistogramSnapshot // Time spent in user callbacks.
+	Received       uint64
+	Accepted       uint64
+	Rejected       uint64
+	Dropped        uint64 // Deliveries dropped from full async dispatch queue.
+	QueueDepth     int
+}
+
+type webhookMetrics struct {
+	handlerLatency *Histogram
+	received       atomic.Uint64
+	accepted       atomic.Uint64
+	rejected       atomic.Uint64
+	dropped        atomic.Uint64
+}
+
+// Estimates latency below which given fraction (0-1) of observations fall, interpolating within bucket.
+// Observations above highest bound are reported as that bound.
+func (s HistogramSnapshot) Quantile(q float64) time.Duration {
+	if s.Count == 0 {
+		return 0
+	}
+
+	rank := q * float64(s.Count)
+	var seen float64
+	var lower time.Duration
+	for _, bucket := range s.Buckets {
+		if bucket.UpperBound == 0 {
+			return lower
+		}
+
+		if bucket.Count > 0 && seen+float64(bucket.Count) >= rank {
+			fraction := (rank - seen) / float64(bucket.Count)
+			return lower + time.Duration(fraction*float64(bucket.UpperBound-lower))
+		}
+
+		seen += float64(bucket.Count)
+		lower = bucket.UpperBound
+	}
+
+	return lower
+}
+
+// Latency distribution of API calls to a single endpoint.
+type EndpointLatency struct {
+	Histogram HistogramSnapshot
+	P50       time.Duration
+	P95       time.Duration
+	P99       time.Duration
+}
+
+// Per-endpoint latency histograms, keyed by method and path with IDs replaced, e.g. "GET /v1/projects/@me/votes/:id".
+type endpointLatencies struct {
+	histograms map[string]*Histogram
+	mu         sync.RWMutex
+}
+
+func newEndpointLatencies() *endpointLatencies {
+	return &endpointLatencies{histograms: make(map[string]*Histogram)}
+}
+
+func (l *endpointLatencies) observe(method, path string, d time.Duration) {
+	key := method + " " + endpointTemplate(path)
+
+	l.mu.RLock()
+	h, ok := l.histograms[key]
+	l.mu.RUnlock()
+
+	if !ok {
+		l.mu.Lock()
+		if h, ok = l.histograms[key]; !ok {
+			h = newLatencyHistogram()
+			l.histograms[key] = h
+		}
+		l.mu.Unlock()
+	}
+
+	h.Observe(d)
+}
+
+func (l *endpointLatencies) snapshot() map[string]EndpointLatency {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	stats := make(map[string]EndpointLatency, len(l.histograms))
+	for key, h := range l.histograms {
+		snapshot := h.Snapshot()
+		stats[key] = EndpointLatency{
+			Histogram: snapshot,
+			P50:       snapshot.Quantile(0.5),
+			P95:       snapshot.Quantile(0.95),
+			P99:       snapshot.Quantile(0.99),
+		}
+	}
+
+	return stats
+}
+
+// Replaces numeric path segments (user IDs) with ":id", so histograms don't grow with every voter.
+func endpointTemplate(path string) string {
+	segments := strings.Split(path, "/")
+	for i, segment := range segments {
+		if segment != "" && strings.Trim(segment, "0123456789") == "" {
+			segments[i] = ":id"
+		}
+	}
+
+	return strings.Join(segments, "/")
+}
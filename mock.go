@@ -0,0 +1,124 @@
+package topgg
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// Methods of Client calling Top.gg API. Depend on it instead of *Client to swap in MockClient in tests.
+type TopGGClient interface {
+	Ping(ctx context.Context, opts ...RequestOption) error
+	GetProject(ctx context.Context, opts ...RequestOption) (*Project, error)
+	EditProject(ctx context.Context, payload ProjectPayload, opts ...RequestOption) error
+	PostApplicationCommands(ctx context.Context, commands []any, opts ...RequestOption) error
+	PostAnnouncement(ctx context.Context, title, content, category string, opts ...RequestOption) (*Announcement, error)
+	PostMetrics(ctx context.Context, payload MetricsPayload, opts ...RequestOption) error
+	PostMetricsInBatch(ctx context.Context, payload []BatchMetricsPayload, opts ...RequestOption) error
+	GetVote(ctx context.Context, userID Snowflake, source Platform, opts ...RequestOption) (*PartialVote, error)
+	GetVotes(ctx context.Context, cursor string, startDate *time.Time, opts ...RequestOption) (*PaginatedVotes, error)
+	StreamVotes(ctx context.Context, cursor string, startDate *time.Time, fn func(vote Vote) bool, opts ...RequestOption) (string, error)
+	Do(ctx context.Context, req *Request, opts ...RequestOption) (*Response, error)
+}
+
+var (
+	_ TopGGClient = (*Client)(nil)
+	_ TopGGClient = (*MockClient)(nil)
+)
+
+// TopGGClient implementation for tests. Each method calls its matching function field,
+// methods without one return an error. RequestOptions are ignored.
+type MockClient struct {
+	PingFunc                    func(ctx context.Context) error
+	GetProjectFunc              func(ctx context.Context) (*Project, error)
+	EditProjectFunc             func(ctx context.Context, payload ProjectPayload) error
+	PostApplicationCommandsFunc func(ctx context.Context, commands []any) error
+	PostAnnouncementFunc        func(ctx context.Context, title, content, category string) (*Announcement, error)
+	PostMetricsFunc             func(ctx context.Context, payload MetricsPayload) error
+	PostMetricsInBatchFunc      func(ctx context.Context, payload []BatchMetricsPayload) error
+	GetVoteFunc                 func(ctx context.Context, userID Snowflake, source Platform) (*PartialVote, error)
+	GetVotesFunc                func(ctx context.Context, cursor string, startDate *time.Time) (*PaginatedVotes, error)
+	StreamVotesFunc             func(ctx context.Context, cursor string, startDate *time.Time, fn func(vote Vote) bool) (string, error)
+	DoFunc                      func(ctx context.Context, req *Request) (*Response, error)
+}
+
+func errNotMocked(method string) error {
+	return fmt.Errorf("topgg: MockClient.%s is not mocked", method)
+}
+
+func (m *MockClient) Ping(ctx context.Context, opts ...RequestOption) error {
+	if m.PingFunc == nil {
+		return errNotMocked("Ping")
+	}
+	return m.PingFunc(ctx)
+}
+
+func (m *MockClient) GetProject(ctx context.Context, opts ...RequestOption) (*Project, error) {
+	if m.GetProjectFunc == nil {
+		return nil, errNotMocked("GetProject")
+	}
+	return m.GetProjectFunc(ctx)
+}
+
+func (m *MockClient) EditProject(ctx context.Context, payload ProjectPayload, opts ...RequestOption) error {
+	if m.EditProjectFunc == nil {
+		return errNotMocked("EditProject")
+	}
+	return m.EditProjectFunc(ctx, payload)
+}
+
+func (m *MockClient) PostApplicationCommands(ctx context.Context, commands []any, opts ...RequestOption) error {
+	if m.PostApplicationCommandsFunc == nil {
+		return errNotMocked("PostApplicationCommands")
+	}
+	return m.PostApplicationCommandsFunc(ctx, commands)
+}
+
+func (m *MockClient) PostAnnouncement(ctx context.Context, title, content, category string, opts ...RequestOption) (*Announcement, error) {
+	if m.PostAnnouncementFunc == nil {
+		return nil, errNotMocked("PostAnnouncement")
+	}
+	return m.PostAnnouncementFunc(ctx, title, content, category)
+}
+
+func (m *MockClient) PostMetrics(ctx context.Context, payload MetricsPayload, opts ...RequestOption) error {
+	if m.PostMetricsFunc == nil {
+		return errNotMocked("PostMetrics")
+	}
+	return m.PostMetricsFunc(ctx, payload)
+}
+
+func (m *MockClient) PostMetricsInBatch(ctx context.Context, payload []BatchMetricsPayload, opts ...RequestOption) error {
+	if m.PostMetricsInBatchFunc == nil {
+		return errNotMocked("PostMetricsInBatch")
+	}
+	return m.PostMetricsInBatchFunc(ctx, payload)
+}
+
+func (m *MockClient) GetVote(ctx context.Context, userID Snowflake, source Platform, opts ...RequestOption) (*PartialVote, error) {
+	if m.GetVoteFunc == nil {
+		return nil, errNotMocked("GetVote")
+	}
+	return m.GetVoteFunc(ctx, userID, source)
+}
+
+func (m *MockClient) GetVotes(ctx context.Context, cursor string, startDate *time.Time, opts ...RequestOption) (*PaginatedVotes, error) {
+	if m.GetVotesFunc == nil {
+		return nil, errNotMocked("GetVotes")
+	}
+	return m.GetVotesFunc(ctx, cursor, startDate)
+}
+
+func (m *MockClient) StreamVotes(ctx context.Context, cursor string, startDate *time.Time, fn func(vote Vote) bool, opts ...RequestOption) (string, error) {
+	if m.StreamVotesFunc == nil {
+		return "", errNotMocked("StreamVotes")
+	}
+	return m.StreamVotesFunc(ctx, cursor, startDate, fn)
+}
+
+func (m *MockClient) Do(ctx context.Context, req *Request, opts ...RequestOption) (*Response, error) {
+	if m.DoFunc == nil {
+		return nil, errNotMocked("Do")
+	}
+	return m.DoFunc(ctx, req)
+}
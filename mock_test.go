@@ -0,0 +1,51 @@
+package topgg
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestMockClientNotMocked(t *testing.T) {
+	ctx := context.Background()
+	tests := []struct {
+		call   func(api TopGGClient) error
+		method string
+	}{
+		{method: "GetVote", call: func(api TopGGClient) error {
+			_, err := api.GetVote(ctx, 1, PlatformDiscord)
+			return err
+		}},
+		{method: "StreamVotes", call: func(api TopGGClient) error {
+			_, err := api.StreamVotes(ctx, "", nil, func(vote Vote) bool { return true })
+			return err
+		}},
+		{method: "Do", call: func(api TopGGClient) error {
+			_, err := api.Do(ctx, &Request{Method: "GET", Path: "/v1/projects/@me"})
+			return err
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.method, func(t *testing.T) {
+			err := tt.call(&MockClient{})
+			if err == nil || !strings.Contains(err.Error(), "MockClient."+tt.method) {
+				t.Fatalf("got %v, want not mocked error of %s", err, tt.method)
+			}
+		})
+	}
+}
+
+func TestMockClientDo(t *testing.T) {
+	var got *Request
+	api := &MockClient{DoFunc: func(ctx context.Context, req *Request) (*Response, error) {
+		got = req
+		return &Response{StatusCode: 200}, nil
+	}}
+
+	req := &Request{Method: "GET", Path: "/v1/projects/@me"}
+	resp, err := TopGGClient(api).Do(context.Background(), req)
+	if err != nil || resp.StatusCode != 200 || got != req {
+		t.Fatalf("got %+v, %v", resp, err)
+	}
+}
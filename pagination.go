@@ -0,0 +1,57 @@
+package topgg
+
+import (
+	"context"
+	"time"
+)
+
+// Single page of cursor-paginated results. Empty Cursor means it's the last page.
+type Page[T any] struct {
+	Cursor string
+	Items  []T
+}
+
+// Fetches page at given cursor, empty cursor means the first page.
+type PageFetcher[T any] func(ctx context.Context, cursor string) (*Page[T], error)
+
+// Calls fn for every item across all pages, stopping at the last page or at first error
+// (from fetch or fn), which is returned.
+func EachItem[T any](ctx context.Context, fetch PageFetcher[T], fn func(item T) error) error {
+	cursor := ""
+	for {
+		page, err := fetch(ctx, cursor)
+		if err != nil {
+			return err
+		}
+
+		for _, item := range page.Items {
+			if err := fn(item); err != nil {
+				return err
+			}
+		}
+
+		if page.Cursor == "" || len(page.Items) == 0 {
+			return nil
+		}
+
+		cursor = page.Cursor
+	}
+}
+
+// PageFetcher over GetVotes of given client, listing votes cast since startDate (nil means all).
+func VotePages(api TopGGClient, startDate *time.Time, opts ...RequestOption) PageFetcher[Vote] {
+	return func(ctx context.Context, cursor string) (*Page[Vote], error) {
+		// Cursor already carries the start date, API only accepts it with the first page.
+		since := startDate
+		if cursor != "" {
+			since = nil
+		}
+
+		res, err := api.GetVotes(ctx, cursor, since, opts...)
+		if err != nil {
+			return nil, err
+		}
+
+		return &Page[Vote]{Cursor: res.Cursor, Items: res.Votes}, nil
+	}
+}
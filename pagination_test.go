@@ -0,0 +1,151 @@
+package topgg
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// PageFetcher over fixed pages, keyed by cursor. Records requested cursors.
+func fixedPages(pages map[string]Page[int], cursors *[]string) PageFetcher[int] {
+	return func(ctx context.Context, cursor string) (*Page[int], error) {
+		*cursors = append(*cursors, cursor)
+		page, ok := pages[cursor]
+		if !ok {
+			return nil, errors.New("unknown cursor " + cursor)
+		}
+
+		return &page, nil
+	}
+}
+
+func TestEachItem(t *testing.T) {
+	errStop := errors.New("stop")
+
+	tests := []struct {
+		fn          func(item int) error
+		pages       map[string]Page[int]
+		wantErr     error
+		name        string
+		wantItems   []int
+		wantCursors []string
+	}{
+		{
+			name:        "single page",
+			pages:       map[string]Page[int]{"": {Items: []int{1, 2}}},
+			wantItems:   []int{1, 2},
+			wantCursors: []string{""},
+		},
+		{
+			name:        "follows cursors",
+			pages:       map[string]Page[int]{"": {Items: []int{1}, Cursor: "a"}, "a": {Items: []int{2, 3}, Cursor: "b"}, "b": {Items: []int{4}}},
+			wantItems:   []int{1, 2, 3, 4},
+			wantCursors: []string{"", "a", "b"},
+		},
+		{
+			name:        "empty page with cursor ends",
+			pages:       map[string]Page[int]{"": {Items: []int{1}, Cursor: "a"}, "a": {Cursor: "a"}},
+			wantItems:   []int{1},
+			wantCursors: []string{"", "a"},
+		},
+		{
+			name:        "fn error stops",
+			pages:       map[string]Page[int]{"": {Items: []int{1, 2}, Cursor: "a"}},
+			fn:          func(item int) error { return errStop },
+			wantErr:     errStop,
+			wantItems:   []int{1},
+			wantCursors: []string{""},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var cursors []string
+			var items []int
+			err := EachItem(context.Background(), fixedPages(tt.pages, &cursors), func(item int) error {
+				items = append(items, item)
+				if tt.fn != nil {
+					return tt.fn(item)
+				}
+				return nil
+			})
+
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("got error %v, want %v", err, tt.wantErr)
+			}
+
+			if !equalInts(items, tt.wantItems) {
+				t.Errorf("got items %v, want %v", items, tt.wantItems)
+			}
+
+			if len(cursors) != len(tt.wantCursors) {
+				t.Fatalf("fetched cursors %q, want %q", cursors, tt.wantCursors)
+			}
+			for i := range cursors {
+				if cursors[i] != tt.wantCursors[i] {
+					t.Fatalf("fetched cursors %q, want %q", cursors, tt.wantCursors)
+				}
+			}
+		})
+	}
+}
+
+func TestEachItemFetchError(t *testing.T) {
+	var cursors []string
+	var items []int
+	fetch := fixedPages(map[string]Page[int]{"": {Items: []int{1}, Cursor: "gone"}}, &cursors)
+
+	err := EachItem(context.Background(), fetch, func(item int) error {
+		items = append(items, item)
+		return nil
+	})
+	if err == nil || !equalInts(items, []int{1}) {
+		t.Fatalf("got items %v and error %v, want first page and error", items, err)
+	}
+}
+
+func TestVotePages(t *testing.T) {
+	since := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	var starts []*time.Time
+	api := &MockClient{GetVotesFunc: func(ctx context.Context, cursor string, startDate *time.Time) (*PaginatedVotes, error) {
+		starts = append(starts, startDate)
+		if cursor == "" {
+			return &PaginatedVotes{Cursor: "next", Votes: []Vote{{PlatformID: 1}, {PlatformID: 2}}}, nil
+		}
+
+		return &PaginatedVotes{Votes: []Vote{{PlatformID: 3}}}, nil
+	}}
+
+	var voters []Snowflake
+	err := EachItem(context.Background(), VotePages(api, &since), func(vote Vote) error {
+		voters = append(voters, vote.PlatformID)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("EachItem: %v", err)
+	}
+
+	if !equalIDs(voters, []Snowflake{1, 2, 3}) {
+		t.Fatalf("got voters %v, want [1 2 3]", voters)
+	}
+
+	// Start date goes only with the first page, cursor carries it afterwards.
+	if len(starts) != 2 || starts[0] == nil || !starts[0].Equal(since) || starts[1] != nil {
+		t.Fatalf("got start dates %v, want %s then none", starts, since)
+	}
+}
+
+func equalInts(a, b []int) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+
+	return true
+}
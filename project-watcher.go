@@ -0,0 +1,123 @@
+package topgg
+
+import (
+	"context"
+	"log"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// Difference between two consecutive polls of the project.
+type ProjectChange struct {
+	Fields   []string // JSON names of changed fields, e.g. "votes" or "review_score".
+	Previous Project
+	Current  Project
+}
+
+type ProjectWatcherOptions struct {
+	API      TopGGClient // Used for polls instead of the client creating ProjectWatcher, e.g. MockClient in tests.
+	OnChange func(change ProjectChange)
+	OnError  func(err error) // Called when poll fails or OnChange panics (with *PanicError), watcher keeps polling.
+	Interval time.Duration   // Delay between polls, defaults to 5 minutes.
+}
+
+// Polls project and reports changes of its fields, e.g. for dashboards reacting to new votes or reviews.
+// Polls go through client's rate limiter, so they don't starve other calls.
+type ProjectWatcher struct {
+	client      TopGGClient
+	onChange    func(change ProjectChange)
+	onError     func(err error)
+	traceLogger *log.Logger
+	scheduler   Scheduler
+	last        *Project
+	interval    time.Duration
+}
+
+func (c *Client) NewProjectWatcher(opt ProjectWatcherOptions) *ProjectWatcher {
+	interval := opt.Interval
+	if interval <= 0 {
+		interval = 5 * time.Minute
+	}
+
+	var api TopGGClient = c
+	if opt.API != nil {
+		api = opt.API
+	}
+
+	return &ProjectWatcher{
+		client:      api,
+		onChange:    opt.OnChange,
+		onError:     opt.OnError,
+		traceLogger: c.traceLogger,
+		scheduler:   c.scheduler,
+		interval:    interval,
+	}
+}
+
+func (pw *ProjectWatcher) tracef(format string, v ...any) {
+	pw.traceLogger.Printf("[PROJECT WATCHER] "+format, v...)
+}
+
+// Polls project every interval until ctx is done. First poll only records the state to compare against.
+func (pw *ProjectWatcher) Run(ctx context.Context) error {
+	pw.poll(ctx)
+	return pw.scheduler.Every(ctx, pw.interval, pw.poll)
+}
+
+func (pw *ProjectWatcher) poll(ctx context.Context) {
+	current, err := pw.client.GetProject(ctx)
+	if err != nil {
+		if ctx.Err() != nil {
+			return
+		}
+
+		pw.tracef("Failed to poll project: %v", err)
+		pw.fail(err)
+		return
+	}
+
+	previous := pw.last
+	pw.last = current
+	if previous == nil {
+		return
+	}
+
+	fields := changedFields(*previous, *current)
+	if len(fields) == 0 {
+		return
+	}
+
+	pw.tracef("Project changed: %s", strings.Join(fields, ", "))
+	if pw.onChange != nil {
+		change := ProjectChange{Fields: fields, Previous: *previous, Current: *current}
+		if err := safeCall("project watcher", "OnChange", func() { pw.onChange(change) }); err != nil {
+			pw.tracef("%v", err)
+			pw.fail(err)
+		}
+	}
+}
+
+func (pw *ProjectWatcher) fail(err error) {
+	if pw.onError != nil {
+		if err := safeCall("project watcher", "OnError", func() { pw.onError(err) }); err != nil {
+			pw.tracef("%v", err)
+		}
+	}
+}
+
+// Returns JSON names of fields that differ between a and b.
+func changedFields(a, b Project) []string {
+	va, vb := reflect.ValueOf(a), reflect.ValueOf(b)
+	t := va.Type()
+
+	var fields []string
+	for i := 0; i < t.NumField(); i++ {
+		if !reflect.DeepEqual(va.Field(i).Interface(), vb.Field(i).Interface()) {
+			name, _, _ := strings.Cut(t.Field(i).Tag.Get("json"), ",")
+			fields = append(fields, name)
+		}
+	}
+
+	return fields
+}
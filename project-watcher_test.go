@@ -0,0 +1,158 @@
+package topgg
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+// MockClient returning projects and errors in order, repeating the last one.
+func projectSequence(results ...any) *MockClient {
+	var calls int
+	return &MockClient{GetProjectFunc: func(ctx context.Context) (*Project, error) {
+		result := results[len(results)-1]
+		if calls < len(results) {
+			result = results[calls]
+		}
+		calls++
+
+		if err, ok := result.(error); ok {
+			return nil, err
+		}
+
+		project := result.(Project)
+		return &project, nil
+	}}
+}
+
+func TestChangedFields(t *testing.T) {
+	base := Project{ID: 1, Name: "bot", Tags: []string{"fun"}, Votes: 10, ReviewScore: 4.5}
+
+	tests := []struct {
+		change func(p *Project)
+		name   string
+		want   []string
+	}{
+		{name: "unchanged", change: func(p *Project) {}},
+		{name: "votes", change: func(p *Project) { p.Votes++; p.VotesTotal++ }, want: []string{"votes", "votes_total"}},
+		{name: "review score", change: func(p *Project) { p.ReviewScore = 4.8 }, want: []string{"review_score"}},
+		{name: "tags", change: func(p *Project) { p.Tags = []string{"fun", "music"} }, want: []string{"tags"}},
+		{name: "same tags in new slice", change: func(p *Project) { p.Tags = []string{"fun"} }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			current := base
+			tt.change(&current)
+
+			if got := changedFields(base, current); strings.Join(got, ",") != strings.Join(tt.want, ",") {
+				t.Fatalf("got fields %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestProjectWatcherReportsChanges(t *testing.T) {
+	first := Project{ID: 1, Name: "bot", Votes: 10}
+	voted := first
+	voted.Votes = 11
+
+	var changes []ProjectChange
+	pw := NewClient(ClientOptions{}).NewProjectWatcher(ProjectWatcherOptions{
+		API:      projectSequence(first, first, voted),
+		OnChange: func(change ProjectChange) { changes = append(changes, change) },
+		OnError:  func(err error) { t.Errorf("unexpected error: %v", err) },
+	})
+
+	for i := 0; i < 3; i++ {
+		pw.poll(context.Background())
+		if i < 2 && len(changes) != 0 {
+			t.Fatalf("poll %d reported %+v before anything changed", i+1, changes)
+		}
+	}
+
+	if len(changes) != 1 {
+		t.Fatalf("got %d changes, want 1", len(changes))
+	}
+
+	if change := changes[0]; strings.Join(change.Fields, ",") != "votes" || change.Previous.Votes != 10 || change.Current.Votes != 11 {
+		t.Fatalf("got change %+v, want votes going from 10 to 11", change)
+	}
+}
+
+func TestProjectWatcherErrors(t *testing.T) {
+	errAPI := errors.New("api is down")
+	first := Project{ID: 1, Votes: 10}
+	voted := first
+	voted.Votes = 11
+
+	var errs []error
+	pw := NewClient(ClientOptions{}).NewProjectWatcher(ProjectWatcherOptions{
+		API:      projectSequence(first, errAPI, voted),
+		OnChange: func(change ProjectChange) { panic("boom") },
+		OnError:  func(err error) { errs = append(errs, err) },
+	})
+
+	for i := 0; i < 3; i++ {
+		pw.poll(context.Background())
+	}
+
+	if len(errs) != 2 {
+		t.Fatalf("got errors %v, want failed poll and panic", errs)
+	}
+
+	if !errors.Is(errs[0], errAPI) {
+		t.Errorf("first error is %v, want %v", errs[0], errAPI)
+	}
+
+	var panicErr *PanicError
+	if !errors.As(errs[1], &panicErr) || panicErr.Callback != "OnChange" || panicErr.Value != "boom" {
+		t.Errorf("second error is %v, want OnChange panic", errs[1])
+	}
+
+	// Failed poll must not reset state, change is still reported against the last project seen.
+	if pw.last == nil || pw.last.Votes != 11 {
+		t.Errorf("watcher remembers %+v, want latest project", pw.last)
+	}
+}
+
+func TestProjectWatcherIgnoresCancelledPoll(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	pw := NewClient(ClientOptions{}).NewProjectWatcher(ProjectWatcherOptions{
+		API:     projectSequence(context.Canceled),
+		OnError: func(err error) { t.Errorf("error of cancelled poll was reported: %v", err) },
+	})
+	pw.poll(ctx)
+}
+
+func TestProjectWatcherRun(t *testing.T) {
+	scheduler := &instantScheduler{}
+	changed := make(chan ProjectChange, 1)
+	pw := NewClient(ClientOptions{Scheduler: scheduler}).NewProjectWatcher(ProjectWatcherOptions{
+		API:      projectSequence(Project{ID: 1, Votes: 1}, Project{ID: 1, Votes: 2}),
+		OnChange: func(change ProjectChange) { changed <- change },
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	if err := pw.Run(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Run = %v, want context.DeadlineExceeded", err)
+	}
+
+	select {
+	case change := <-changed:
+		if change.Current.Votes != 2 {
+			t.Fatalf("got change %+v, want 2 votes", change)
+		}
+	default:
+		t.Fatal("scheduled poll didn't report change")
+	}
+
+	if len(scheduler.intervals) != 1 || scheduler.intervals[0] != 5*time.Minute {
+		t.Fatalf("scheduled with intervals %v, want default 5m", scheduler.intervals)
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"io"
 	"net/http"
 	"net/url"
 	"time"
@@ -58,12 +59,119 @@ type PaginatedVotes struct {
 
 // https://docs.top.gg/api/v1/projects#discord-server
 // https://docs.top.gg/api/v1/projects#roblox-game
+//
+// Zero counts are omitted, so metrics that don't apply to the project (e.g. shard_count of unsharded bot
+// or server_count of Roblox game) aren't posted as 0. Counts that really are 0 are posted when listed in Zero.
 type MetricsPayload struct {
-	ServerCount int `json:"server_count"`
-	ShardCount  int `json:"shard_count"`
-	MemberCount int `json:"member_count"`
-	OnlineCount int `json:"online_count"`
-	PlayerCount int `json:"player_count"`
+	ServerCount int
+	ShardCount  int
+	MemberCount int
+	OnlineCount int
+	PlayerCount int
+	Zero        MetricCount // Counts posted even when 0, e.g. CountPlayers of Roblox game nobody plays right now.
+}
+
+// Set of MetricsPayload counts, see MetricsPayload.Zero.
+type MetricCount uint8
+
+const (
+	CountServers MetricCount = 1 << iota
+	CountShards
+	CountMembers
+	CountOnline
+	CountPlayers
+)
+
+// Wire form of MetricsPayload, nil counts are omitted.
+type metricsJSON struct {
+	ServerCount *int `json:"server_count,omitempty"`
+	ShardCount  *int `json:"shard_count,omitempty"`
+	MemberCount *int `json:"member_count,omitempty"`
+	OnlineCount *int `json:"online_count,omitempty"`
+	PlayerCount *int `json:"player_count,omitempty"`
+}
+
+func (p MetricsPayload) MarshalJSON() ([]byte, error) {
+	return json.Marshal(metricsJSON{
+		ServerCount: p.posted(p.ServerCount, CountServers),
+		ShardCount:  p.posted(p.ShardCount, CountShards),
+		MemberCount: p.posted(p.MemberCount, CountMembers),
+		OnlineCount: p.posted(p.OnlineCount, CountOnline),
+		PlayerCount: p.posted(p.PlayerCount, CountPlayers),
+	})
+}
+
+// Returns count as posted, nil when it's omitted.
+func (p MetricsPayload) posted(value int, count MetricCount) *int {
+	if value == 0 && p.Zero&count == 0 {
+		return nil
+	}
+
+	return &value
+}
+
+// Counts present as 0 are added to Zero, so payload survives round trip (e.g. through MetricsPoster outbox).
+func (p *MetricsPayload) UnmarshalJSON(b []byte) error {
+	var raw metricsJSON
+	if err := json.Unmarshal(b, &raw); err != nil {
+		return err
+	}
+
+	*p = MetricsPayload{}
+	p.ServerCount = p.received(raw.ServerCount, CountServers)
+	p.ShardCount = p.received(raw.ShardCount, CountShards)
+	p.MemberCount = p.received(raw.MemberCount, CountMembers)
+	p.OnlineCount = p.received(raw.OnlineCount, CountOnline)
+	p.PlayerCount = p.received(raw.PlayerCount, CountPlayers)
+	return nil
+}
+
+func (p *MetricsPayload) received(value *int, count MetricCount) int {
+	if value == nil {
+		return 0
+	}
+
+	if *value == 0 {
+		p.Zero |= count
+	}
+
+	return *value
+}
+
+// Checks payload client-side, so mistakes don't end up as opaque 400 responses.
+func (p MetricsPayload) Validate() error {
+	counts := []struct {
+		name  string
+		value int
+		count MetricCount
+	}{
+		{"server_count", p.ServerCount, CountServers},
+		{"shard_count", p.ShardCount, CountShards},
+		{"member_count", p.MemberCount, CountMembers},
+		{"online_count", p.OnlineCount, CountOnline},
+		{"player_count", p.PlayerCount, CountPlayers},
+	}
+
+	empty := true
+	for _, count := range counts {
+		if count.value < 0 {
+			return fmt.Errorf("%w: %s cannot be negative (got %d)", ErrInvalidPayload, count.name, count.value)
+		}
+
+		if p.posted(count.value, count.count) != nil {
+			empty = false
+		}
+	}
+
+	if empty {
+		return fmt.Errorf("%w: at least one count must be set, list counts that are 0 in Zero", ErrInvalidPayload)
+	}
+
+	if p.OnlineCount > 0 && p.MemberCount > 0 && p.OnlineCount > p.MemberCount {
+		return fmt.Errorf("%w: online_count (%d) cannot exceed member_count (%d)", ErrInvalidPayload, p.OnlineCount, p.MemberCount)
+	}
+
+	return nil
 }
 
 // https://docs.top.gg/api/v1/projects#param-platform
@@ -74,6 +182,16 @@ const (
 	PlatformRoblox  Platform = "roblox"
 )
 
+// Reports whether platform is one of constants above, e.g. to catch platforms added by Top.gg later.
+func (p Platform) Known() bool {
+	switch p {
+	case PlatformDiscord, PlatformRoblox:
+		return true
+	default:
+		return false
+	}
+}
+
 // https://docs.top.gg/api/v1/projects#param-type
 type ProjectType string
 
@@ -83,6 +201,16 @@ const (
 	ProjectTypeGame   ProjectType = "game"
 )
 
+// Reports whether project type is one of constants above.
+func (t ProjectType) Known() bool {
+	switch t {
+	case ProjectTypeBot, ProjectTypeServer, ProjectTypeGame:
+		return true
+	default:
+		return false
+	}
+}
+
 // https://docs.top.gg/api/v1/projects#supported-locales
 type Locale string
 
@@ -111,31 +239,31 @@ type BatchMetricsPayload struct {
 }
 
 // https://docs.top.gg/api/v1/projects#get-/projects/@me
-func (c *Client) GetProject(ctx context.Context) (*Project, error) {
-	b, err := c.request(ctx, http.MethodGet, "/v1/projects/@me", nil)
+func (c *Client) GetProject(ctx context.Context, opts ...RequestOption) (*Project, error) {
+	b, err := c.request(ctx, http.MethodGet, c.endpoint("/projects/@me"), nil, opts...)
 	if err != nil {
 		return nil, err
 	}
 
 	var project Project
-	err = json.Unmarshal(b, &project)
+	err = c.decode(b, &project, opts)
 	return &project, err
 }
 
 // https://docs.top.gg/api/v1/projects#patch-/projects/@me
-func (c *Client) EditProject(ctx context.Context, payload ProjectPayload) error {
-	_, err := c.request(ctx, http.MethodPatch, "/v1/projects/@me", payload)
+func (c *Client) EditProject(ctx context.Context, payload ProjectPayload, opts ...RequestOption) error {
+	_, err := c.request(ctx, http.MethodPatch, c.endpoint("/projects/@me"), payload, opts...)
 	return err
 }
 
 // https://docs.top.gg/api/v1/projects#put-/projects/@me/commands
-func (c *Client) PostApplicationCommands(ctx context.Context, commands []any) error {
-	_, err := c.request(ctx, http.MethodPut, "/v1/projects/@me/commands", commands)
+func (c *Client) PostApplicationCommands(ctx context.Context, commands []any, opts ...RequestOption) error {
+	_, err := c.request(ctx, http.MethodPut, c.endpoint("/projects/@me/commands"), commands, opts...)
 	return err
 }
 
 // https://docs.top.gg/api/v1/projects#post-/projects/@me/announcements
-func (c *Client) PostAnnouncement(ctx context.Context, title, content, category string) (*Announcement, error) {
+func (c *Client) PostAnnouncement(ctx context.Context, title, content, category string, opts ...RequestOption) (*Announcement, error) {
 	body := map[string]string{
 		"title":   title,
 		"content": content,
@@ -144,53 +272,127 @@ func (c *Client) PostAnnouncement(ctx context.Context, title, content, category
 		body["category"] = category
 	}
 
-	b, err := c.request(ctx, http.MethodPost, "/v1/projects/@me/announcements", body)
+	b, err := c.request(ctx, http.MethodPost, c.endpoint("/projects/@me/announcements"), body, opts...)
 	if err != nil {
 		return nil, err
 	}
 
 	var announcement Announcement
-	err = json.Unmarshal(b, &announcement)
+	err = c.decode(b, &announcement, opts)
 	return &announcement, err
 }
 
 // https://docs.top.gg/api/v1/projects#patch-/projects/@me/metrics
-func (c *Client) PostMetrics(ctx context.Context, payload MetricsPayload) error {
-	_, err := c.request(ctx, http.MethodPatch, "/v1/projects/@me/metrics", payload)
+func (c *Client) PostMetrics(ctx context.Context, payload MetricsPayload, opts ...RequestOption) error {
+	if err := c.checkMetrics(payload); err != nil {
+		return err
+	}
+
+	_, err := c.request(ctx, http.MethodPatch, c.endpoint("/projects/@me/metrics"), payload, opts...)
+	if err == nil {
+		c.rememberMetrics(payload)
+	}
+
 	return err
 }
 
 // https://docs.top.gg/api/v1/projects#post-/projects/@me/metrics/batch
-func (c *Client) PostMetricsInBatch(ctx context.Context, payload []BatchMetricsPayload) error {
+func (c *Client) PostMetricsInBatch(ctx context.Context, payload []BatchMetricsPayload, opts ...RequestOption) error {
+	for i, entry := range payload {
+		if err := entry.Metrics.Validate(); err != nil {
+			return fmt.Errorf("batch entry %d: %w", i, err)
+		}
+	}
+
 	body := map[string]any{"data": payload}
-	_, err := c.request(ctx, http.MethodPost, "/v1/projects/@me/metrics/batch", body)
+	_, err := c.request(ctx, http.MethodPost, c.endpoint("/projects/@me/metrics/batch"), body, opts...)
 	return err
 }
 
+// Validates payload and runs it through configured MetricsGuard against last posted metrics.
+func (c *Client) checkMetrics(payload MetricsPayload) error {
+	if err := payload.Validate(); err != nil {
+		return err
+	}
+
+	if c.metricsGuard == nil {
+		return nil
+	}
+
+	c.metricsMu.Lock()
+	previous := c.lastMetrics
+	c.metricsMu.Unlock()
+
+	if previous == nil {
+		return nil
+	}
+
+	var guardErr error
+	if err := safeCall("client", "MetricsGuard", func() { guardErr = c.metricsGuard(*previous, payload) }); err != nil {
+		// Payload can't be vouched for, so it's held back like a rejected one.
+		c.tracef("%v", err)
+		return err
+	}
+
+	if guardErr != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidPayload, guardErr)
+	}
+
+	return nil
+}
+
+func (c *Client) rememberMetrics(payload MetricsPayload) {
+	c.metricsMu.Lock()
+	c.lastMetrics = &payload
+	c.metricsMu.Unlock()
+}
+
 // https://docs.top.gg/api/v1/votes#get-/projects/@me/votes/user_id
-func (c *Client) GetVote(ctx context.Context, userID Snowflake, source Platform) (*PartialVote, error) {
+func (c *Client) GetVote(ctx context.Context, userID Snowflake, source Platform, opts ...RequestOption) (*PartialVote, error) {
 	q := url.Values{}
 	if source != "" {
 		q.Set("source", string(source))
 	}
 
-	urlStr := fmt.Sprintf("/v1/projects/@me/votes/%d", userID)
+	urlStr := c.endpoint(fmt.Sprintf("/projects/@me/votes/%d", userID))
 	if len(q) > 0 {
 		urlStr += "?" + q.Encode()
 	}
 
-	b, err := c.request(ctx, http.MethodGet, urlStr, nil)
+	b, err := c.request(ctx, http.MethodGet, urlStr, nil, opts...)
 	if err != nil {
 		return nil, err
 	}
 
 	var vote PartialVote
-	err = json.Unmarshal(b, &vote)
+	err = c.decode(b, &vote, opts)
 	return &vote, err
 }
 
 // https://docs.top.gg/api/v1/votes#get-/projects/@me/votes
-func (c *Client) GetVotes(ctx context.Context, cursor string, startDate *time.Time) (*PaginatedVotes, error) {
+func (c *Client) GetVotes(ctx context.Context, cursor string, startDate *time.Time, opts ...RequestOption) (*PaginatedVotes, error) {
+	b, err := c.request(ctx, http.MethodGet, c.votesRoute(cursor, startDate), nil, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	var res struct {
+		Cursor string `json:"cursor"`
+		Data   []Vote `json:"data"`
+	}
+
+	err = c.decode(b, &res, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	return &PaginatedVotes{
+		Votes:  res.Data,
+		Cursor: res.Cursor,
+	}, nil
+}
+
+func (c *Client) votesRoute(cursor string, startDate *time.Time) string {
 	q := url.Values{}
 	if startDate != nil {
 		q.Set("startDate", startDate.Format(time.RFC3339))
@@ -200,28 +402,98 @@ func (c *Client) GetVotes(ctx context.Context, cursor string, startDate *time.Ti
 		q.Set("cursor", cursor)
 	}
 
-	urlStr := "/v1/projects/@me/votes"
+	route := c.endpoint("/projects/@me/votes")
 	if len(q) > 0 {
-		urlStr += "?" + q.Encode()
+		route += "?" + q.Encode()
 	}
 
-	b, err := c.request(ctx, http.MethodGet, urlStr, nil)
-	if err != nil {
-		return nil, err
+	return route
+}
+
+// Same as GetVotes, but votes are passed to fn one by one as they're decoded, without holding the whole page
+// in memory. Returning false from fn stops reading. Returns cursor of next page, which is empty when fn stopped early.
+func (c *Client) StreamVotes(ctx context.Context, cursor string, startDate *time.Time, fn func(vote Vote) bool, opts ...RequestOption) (string, error) {
+	var next string
+	opts = append(opts, func(cfg *requestConfig) {
+		cfg.noCoalesce = true
+		cfg.stream = func(r io.Reader) error {
+			var err error
+			next, err = decodeVotes(r, fn)
+			return err
+		}
+	})
+
+	_, err := c.request(ctx, http.MethodGet, c.votesRoute(cursor, startDate), nil, opts...)
+	return next, err
+}
+
+// Decodes paginated votes object token by token.
+func decodeVotes(r io.Reader, fn func(vote Vote) bool) (string, error) {
+	dec := json.NewDecoder(r)
+	if err := expectDelim(dec, '{'); err != nil {
+		return "", err
 	}
 
-	var res struct {
-		Cursor string `json:"cursor"`
-		Data   []Vote `json:"data"`
+	var cursor string
+	for dec.More() {
+		key, err := dec.Token()
+		if err != nil {
+			return "", err
+		}
+
+		switch key {
+		case "cursor":
+			if err := dec.Decode(&cursor); err != nil {
+				return "", err
+			}
+		case "data":
+			token, err := dec.Token()
+			if err != nil {
+				return "", err
+			}
+
+			if token == nil {
+				continue
+			}
+
+			if token != json.Delim('[') {
+				return "", fmt.Errorf("unexpected JSON token %v, expected [", token)
+			}
+
+			for dec.More() {
+				var vote Vote
+				if err := dec.Decode(&vote); err != nil {
+					return "", err
+				}
+
+				if !fn(vote) {
+					return "", nil
+				}
+			}
+
+			if err := expectDelim(dec, ']'); err != nil {
+				return "", err
+			}
+		default:
+			var skipped json.RawMessage
+			if err := dec.Decode(&skipped); err != nil {
+				return "", err
+			}
+		}
 	}
 
-	err = json.Unmarshal(b, &res)
+	return cursor, expectDelim(dec, '}')
+}
+
+func expectDelim(dec *json.Decoder, delim json.Delim) error {
+	token, err := dec.Token()
 	if err != nil {
-		return nil, err
+		return err
 	}
 
-	return &PaginatedVotes{
-		Votes:  res.Data,
-		Cursor: res.Cursor,
-	}, nil
+	if token != delim {
+		return fmt.Errorf("unexpected JSON token %v, expected %v", token, delim)
+	}
+
+	return nil
 }
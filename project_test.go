@@ -0,0 +1,95 @@
+package topgg
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+func TestMetricsPayloadValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		payload MetricsPayload
+		wantErr bool
+	}{
+		{name: "server count", payload: MetricsPayload{ServerCount: 420}},
+		{name: "servers and shards", payload: MetricsPayload{ServerCount: 420, ShardCount: 2}},
+		{name: "more shards than servers", payload: MetricsPayload{ServerCount: 3, ShardCount: 16}},
+		{name: "members and online", payload: MetricsPayload{MemberCount: 100, OnlineCount: 40}},
+		{name: "players", payload: MetricsPayload{PlayerCount: 7}},
+		{name: "zero player count", payload: MetricsPayload{Zero: CountPlayers}},
+		{name: "empty payload", payload: MetricsPayload{}, wantErr: true},
+		{name: "negative server count", payload: MetricsPayload{ServerCount: -1}, wantErr: true},
+		{name: "negative shard count", payload: MetricsPayload{ServerCount: 1, ShardCount: -1}, wantErr: true},
+		{name: "negative member count", payload: MetricsPayload{MemberCount: -1}, wantErr: true},
+		{name: "negative online count", payload: MetricsPayload{MemberCount: 1, OnlineCount: -1}, wantErr: true},
+		{name: "negative player count", payload: MetricsPayload{PlayerCount: -1}, wantErr: true},
+		{name: "more online than members", payload: MetricsPayload{MemberCount: 10, OnlineCount: 11}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.payload.Validate()
+			if tt.wantErr {
+				if !errors.Is(err, ErrInvalidPayload) {
+					t.Fatalf("got %v, want ErrInvalidPayload", err)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("Validate: %v", err)
+			}
+		})
+	}
+}
+
+func TestMetricsPayloadJSON(t *testing.T) {
+	tests := []struct {
+		name    string
+		want    string
+		payload MetricsPayload
+	}{
+		{name: "unsharded bot", payload: MetricsPayload{ServerCount: 420}, want: `{"server_count":420}`},
+		{name: "sharded bot", payload: MetricsPayload{ServerCount: 420, ShardCount: 2}, want: `{"server_count":420,"shard_count":2}`},
+		{name: "discord server", payload: MetricsPayload{MemberCount: 100, OnlineCount: 40}, want: `{"member_count":100,"online_count":40}`},
+		{name: "roblox game", payload: MetricsPayload{PlayerCount: 7}, want: `{"player_count":7}`},
+		{name: "explicit zero", payload: MetricsPayload{Zero: CountPlayers}, want: `{"player_count":0}`},
+		{name: "zero ignored for set count", payload: MetricsPayload{ServerCount: 5, Zero: CountServers | CountShards}, want: `{"server_count":5,"shard_count":0}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b, err := json.Marshal(tt.payload)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if string(b) != tt.want {
+				t.Fatalf("got %s, want %s", b, tt.want)
+			}
+
+			// Batch entries embed the payload, so it must marshal the same way there.
+			batch, err := json.Marshal(BatchMetricsPayload{Metrics: tt.payload})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var decoded struct {
+				Metrics json.RawMessage `json:"metrics"`
+			}
+			if err := json.Unmarshal(batch, &decoded); err != nil || string(decoded.Metrics) != tt.want {
+				t.Fatalf("batch entry has metrics %s, want %s", decoded.Metrics, tt.want)
+			}
+
+			var back MetricsPayload
+			if err := json.Unmarshal(b, &back); err != nil {
+				t.Fatal(err)
+			}
+
+			if again, _ := json.Marshal(back); string(again) != tt.want {
+				t.Fatalf("round trip gave %s, want %s", again, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,126 @@
+package topgg
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+const (
+	atomicUsesBits = 22 // Leaves 42 bits for reset time in Unix milliseconds.
+	atomicUsesMask = 1<<atomicUsesBits - 1
+)
+
+// Lock-free alternative to fixed-window Bucket, see RateLimiterOptions.LockFree.
+// Reset time of current window (Unix milliseconds) and uses taken in it are packed in a single word,
+// so taking a use is one compare-and-swap.
+type atomicBucket struct {
+	state atomic.Uint64
+	limit atomic.Int64
+}
+
+func newAtomicBucket(limit int) *atomicBucket {
+	b := &atomicBucket{}
+	b.limit.Store(clampAtomicLimit(limit))
+	return b
+}
+
+// Keeps limit within what fits into uses bits.
+func clampAtomicLimit(limit int) int64 {
+	switch {
+	case limit > atomicUsesMask:
+		return atomicUsesMask
+	case limit < 0:
+		return 0
+	}
+
+	return int64(limit)
+}
+
+func packAtomicBucket(resetAt, used int64) uint64 {
+	return uint64(resetAt)<<atomicUsesBits | uint64(used)
+}
+
+func unpackAtomicBucket(state uint64) (resetAt, used int64) {
+	return int64(state >> atomicUsesBits), int64(state & atomicUsesMask)
+}
+
+func (b *atomicBucket) take(now time.Time) (bool, time.Duration) {
+	nowMs := now.UnixMilli()
+	limit := b.limit.Load()
+	for {
+		old := b.state.Load()
+		resetAt, used := unpackAtomicBucket(old)
+
+		var next uint64
+		switch {
+		case nowMs > resetAt:
+			next = packAtomicBucket(nowMs+time.Second.Milliseconds(), 1)
+		case used < limit:
+			next = old + 1
+		default:
+			return false, time.Duration(resetAt-nowMs) * time.Millisecond
+		}
+
+		if b.state.CompareAndSwap(old, next) {
+			return true, 0
+		}
+	}
+}
+
+// Returns reset time of current window, remaining uses and limit.
+func (b *atomicBucket) snapshot(now time.Time) (time.Time, int, int) {
+	resetAt, used := unpackAtomicBucket(b.state.Load())
+	limit := b.limit.Load()
+	if now.UnixMilli() > resetAt {
+		return time.UnixMilli(resetAt), int(limit), int(limit)
+	}
+
+	remaining := limit - used
+	if remaining < 0 {
+		remaining = 0
+	}
+
+	return time.UnixMilli(resetAt), int(remaining), int(limit)
+}
+
+// Sets remaining uses of window ending at resetAt, e.g. when restoring saved state.
+func (b *atomicBucket) restore(resetAt time.Time, remaining int) {
+	// Clamped to limit, so uses never spill over into reset time bits.
+	limit := b.limit.Load()
+	used := limit - int64(remaining)
+	switch {
+	case used < 0:
+		used = 0
+	case used > limit:
+		used = limit
+	}
+
+	b.state.Store(packAtomicBucket(resetAt.UnixMilli(), used))
+}
+
+// Atomic counterpart of RateLimiter.SetLimit for fixed windows.
+func (b *atomicBucket) setLimit(limit int, mode ReconfigureMode) {
+	newLimit := clampAtomicLimit(limit)
+	if mode == ReconfigureReset {
+		b.limit.Store(newLimit)
+		b.state.Store(packAtomicBucket(time.Now().Add(time.Second).UnixMilli(), 0))
+		return
+	}
+
+	oldLimit := b.limit.Swap(newLimit)
+	for {
+		old := b.state.Load()
+		resetAt, used := unpackAtomicBucket(old)
+		if oldLimit > 0 {
+			used = used * newLimit / oldLimit
+		}
+
+		if used > newLimit {
+			used = newLimit
+		}
+
+		if b.state.CompareAndSwap(old, packAtomicBucket(resetAt, used)) {
+			return
+		}
+	}
+}
@@ -0,0 +1,157 @@
+package topgg
+
+import (
+	"testing"
+	"time"
+)
+
+func TestAtomicBucketTake(t *testing.T) {
+	now := time.UnixMilli(1_700_000_000_000)
+	b := newAtomicBucket(3)
+
+	for i := 0; i < 3; i++ {
+		if ok, _ := b.take(now); !ok {
+			t.Fatalf("take %d was denied within limit", i+1)
+		}
+	}
+
+	ok, retryIn := b.take(now.Add(200 * time.Millisecond))
+	if ok || retryIn != 800*time.Millisecond {
+		t.Fatalf("take past limit = %v, %s; want denied for 800ms", ok, retryIn)
+	}
+
+	if ok, _ := b.take(now.Add(time.Second + time.Millisecond)); !ok {
+		t.Fatal("take in next window was denied")
+	}
+
+	if _, remaining, limit := b.snapshot(now.Add(time.Second + time.Millisecond)); remaining != 2 || limit != 3 {
+		t.Fatalf("snapshot in next window = %d of %d, want 2 of 3", remaining, limit)
+	}
+}
+
+func TestAtomicBucketSnapshot(t *testing.T) {
+	now := time.UnixMilli(1_700_000_000_000)
+	b := newAtomicBucket(5)
+
+	if _, remaining, limit := b.snapshot(now); remaining != 5 || limit != 5 {
+		t.Fatalf("fresh bucket has %d of %d uses, want 5 of 5", remaining, limit)
+	}
+
+	b.take(now)
+	b.take(now)
+	resetAt, remaining, _ := b.snapshot(now)
+	if remaining != 3 || !resetAt.Equal(now.Add(time.Second)) {
+		t.Fatalf("got %d uses until %s, want 3 until %s", remaining, resetAt, now.Add(time.Second))
+	}
+
+	if _, remaining, _ := b.snapshot(now.Add(2 * time.Second)); remaining != 5 {
+		t.Fatalf("expired window has %d uses, want full limit", remaining)
+	}
+}
+
+func TestAtomicBucketRestore(t *testing.T) {
+	now := time.UnixMilli(1_700_000_000_000)
+	resetAt := now.Add(500 * time.Millisecond)
+
+	tests := []struct {
+		name          string
+		limit         int
+		remaining     int
+		wantRemaining int
+	}{
+		{name: "partially used", limit: 10, remaining: 3, wantRemaining: 3},
+		{name: "more than limit", limit: 10, remaining: 50, wantRemaining: 10},
+		{name: "negative", limit: 10, remaining: -5, wantRemaining: 0},
+		// Uses can't spill over into reset time bits even with the largest limit.
+		{name: "negative at packing limit", limit: atomicUsesMask * 2, remaining: -5, wantRemaining: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := newAtomicBucket(tt.limit)
+			b.restore(resetAt, tt.remaining)
+
+			gotReset, remaining, _ := b.snapshot(now)
+			if remaining != tt.wantRemaining || !gotReset.Equal(resetAt) {
+				t.Fatalf("restored %d uses until %s, want %d until %s", remaining, gotReset, tt.wantRemaining, resetAt)
+			}
+		})
+	}
+}
+
+func TestAtomicBucketPackingLimits(t *testing.T) {
+	maxReset := int64(1<<(64-atomicUsesBits) - 1)
+	if resetAt, used := unpackAtomicBucket(packAtomicBucket(maxReset, atomicUsesMask)); resetAt != maxReset || used != atomicUsesMask {
+		t.Fatalf("round trip of largest values gave %d, %d", resetAt, used)
+	}
+
+	b := newAtomicBucket(atomicUsesMask + 1000)
+	if _, _, limit := b.snapshot(time.Now()); limit != atomicUsesMask {
+		t.Fatalf("limit %d wasn't clamped to %d", limit, atomicUsesMask)
+	}
+
+	// Last use of the largest window, counter must stop at the mask instead of carrying into reset time.
+	now := time.UnixMilli(1_700_000_000_000)
+	resetAt := now.Add(time.Second)
+	b.restore(resetAt, 1)
+	if ok, _ := b.take(now); !ok {
+		t.Fatal("last use was denied")
+	}
+
+	if ok, _ := b.take(now); ok {
+		t.Fatal("use past the largest limit was allowed")
+	}
+
+	if gotReset, remaining, _ := b.snapshot(now); remaining != 0 || !gotReset.Equal(resetAt) {
+		t.Fatalf("got %d uses until %s, want 0 until %s", remaining, gotReset, resetAt)
+	}
+}
+
+func TestAtomicBucketSetLimit(t *testing.T) {
+	tests := []struct {
+		name          string
+		mode          ReconfigureMode
+		newLimit      int
+		wantRemaining int
+	}{
+		{name: "rescale down", mode: ReconfigureRescale, newLimit: 5, wantRemaining: 3},
+		{name: "rescale up", mode: ReconfigureRescale, newLimit: 20, wantRemaining: 12},
+		{name: "reset", mode: ReconfigureReset, newLimit: 5, wantRemaining: 5},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			now := time.Now()
+			b := newAtomicBucket(10)
+			for i := 0; i < 4; i++ {
+				b.take(now)
+			}
+
+			b.setLimit(tt.newLimit, tt.mode)
+			if _, remaining, limit := b.snapshot(now); remaining != tt.wantRemaining || limit != tt.newLimit {
+				t.Fatalf("got %d of %d uses, want %d of %d", remaining, limit, tt.wantRemaining, tt.newLimit)
+			}
+		})
+	}
+}
+
+func BenchmarkBucketTakeParallel(b *testing.B) {
+	takers := []struct {
+		take func(now time.Time) (bool, time.Duration)
+		name string
+	}{
+		{name: "mutex", take: (&Bucket{Limit: atomicUsesMask}).take},
+		{name: "atomic", take: newAtomicBucket(atomicUsesMask).take},
+	}
+
+	for _, taker := range takers {
+		b.Run(taker.name, func(b *testing.B) {
+			b.ReportAllocs()
+			b.RunParallel(func(pb *testing.PB) {
+				for pb.Next() {
+					taker.take(time.Now())
+				}
+			})
+		})
+	}
+}
@@ -0,0 +1,125 @@
+package topgg
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// On-disk representation of RateLimiter, see RateLimiterOptions.StateFile.
+type rateLimiterState struct {
+	ResetAt         time.Time `json:"reset_at"`
+	GlobalWaitUntil time.Time `json:"global_wait_until"`
+	Remaining       int       `json:"remaining"`
+}
+
+// Restores limiter from state file, ignoring parts that already expired.
+func (rl *RateLimiter) loadState() error {
+	b, err := os.ReadFile(rl.stateFile)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		return err
+	}
+
+	var state rateLimiterState
+	if err := json.Unmarshal(b, &state); err != nil {
+		return fmt.Errorf("failed to decode rate limiter state: %w", err)
+	}
+
+	now := time.Now()
+	if now.Before(state.GlobalWaitUntil) {
+		rl.globalWaitUntil.Store(state.GlobalWaitUntil.UnixNano())
+		rl.tracef("Restored global wait, requests suspended until %s", state.GlobalWaitUntil.Format(time.RFC3339))
+	}
+
+	if rl.fast != nil {
+		if _, _, limit := rl.fast.snapshot(now); now.Before(state.ResetAt) && state.Remaining >= 0 && state.Remaining < limit {
+			rl.fast.restore(state.ResetAt, state.Remaining)
+		}
+	} else if rl.bucket.Burst > 0 {
+		// Burst bucket stores time of last refill, elapsed time is refilled on next take.
+		if !state.ResetAt.IsZero() && !state.ResetAt.After(now) && state.Remaining >= 0 && state.Remaining < rl.bucket.Burst {
+			rl.bucket.ResetAt = state.ResetAt
+			rl.bucket.Remaining = state.Remaining
+		}
+	} else if now.Before(state.ResetAt) && state.Remaining >= 0 && state.Remaining < rl.bucket.Limit {
+		rl.bucket.ResetAt = state.ResetAt
+		rl.bucket.Remaining = state.Remaining
+	}
+
+	return nil
+}
+
+// Schedules write of limiter state to the state file, at most once per checkpoint interval. Written by a timer
+// rather than the caller, so Wait and Allow never touch the disk. Changes made in between are saved by the next write.
+func (rl *RateLimiter) checkpoint() {
+	if rl.stateFile == "" || !rl.saveScheduled.CompareAndSwap(false, true) {
+		return
+	}
+
+	delay := time.Until(time.Unix(0, rl.lastCheckpoint.Load()).Add(rl.checkpointInterval))
+	if delay < 0 {
+		delay = 0
+	}
+
+	time.AfterFunc(delay, func() {
+		// Cleared first, so changes made during the write schedule another one.
+		rl.saveScheduled.Store(false)
+		rl.save()
+	})
+}
+
+// Writes limiter state to the state file right away, e.g. on 429 suspension.
+func (rl *RateLimiter) save() {
+	if rl.stateFile == "" {
+		return
+	}
+
+	rl.stateMu.Lock()
+	defer rl.stateMu.Unlock()
+
+	rl.lastCheckpoint.Store(time.Now().UnixNano())
+	if err := rl.saveState(); err != nil {
+		rl.tracef("Failed to save rate limiter state: %v", err)
+	}
+}
+
+func (rl *RateLimiter) saveState() error {
+	snapshot := rl.Snapshot()
+	b, err := json.Marshal(rateLimiterState{
+		ResetAt:         snapshot.ResetAt,
+		GlobalWaitUntil: snapshot.GlobalWaitUntil,
+		Remaining:       snapshot.Remaining,
+	})
+	if err != nil {
+		return err
+	}
+
+	return writeFileAtomic(rl.stateFile, b)
+}
+
+// Writes file via temporary file & rename, so crash in the middle never leaves truncated file behind.
+func writeFileAtomic(path string, b []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp*")
+	if err != nil {
+		return err
+	}
+
+	if _, err := tmp.Write(b); err != nil {
+		_ = tmp.Close()
+		_ = os.Remove(tmp.Name())
+		return err
+	}
+
+	if err := tmp.Close(); err != nil {
+		_ = os.Remove(tmp.Name())
+		return err
+	}
+
+	return os.Rename(tmp.Name(), path)
+}
@@ -0,0 +1,143 @@
+package topgg
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// Waits until state file holds given remaining uses, failing the test after a few seconds.
+func waitForState(t *testing.T, path string, remaining int) rateLimiterState {
+	t.Helper()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		var state rateLimiterState
+		if b, err := os.ReadFile(path); err == nil && json.Unmarshal(b, &state) == nil && state.Remaining == remaining {
+			return state
+		}
+
+		if time.Now().After(deadline) {
+			t.Fatalf("state file never recorded %d remaining uses", remaining)
+		}
+
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestRateLimiterStateRestart(t *testing.T) {
+	tests := []struct {
+		name string
+		opt  RateLimiterOptions
+		want int // Remaining uses after 3 taken.
+	}{
+		{name: "fixed window", opt: RateLimiterOptions{Limit: 10}, want: 7},
+		{name: "lock-free", opt: RateLimiterOptions{Limit: 10, LockFree: true}, want: 7},
+		{name: "burst", opt: RateLimiterOptions{Limit: 1, Burst: 10}, want: 7},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.opt.StateFile = filepath.Join(t.TempDir(), "limiter.json")
+			tt.opt.CheckpointInterval = 10 * time.Millisecond
+
+			before := NewRateLimiter(tt.opt)
+			for i := 0; i < 3; i++ {
+				if !before.Allow() {
+					t.Fatalf("use %d was denied", i+1)
+				}
+			}
+			waitForState(t, tt.opt.StateFile, tt.want)
+
+			after := NewRateLimiter(tt.opt)
+			if remaining := after.Snapshot().Remaining; remaining != tt.want {
+				t.Fatalf("restarted limiter has %d uses, want %d", remaining, tt.want)
+			}
+		})
+	}
+}
+
+func TestRateLimiterStateGlobalWait(t *testing.T) {
+	opt := RateLimiterOptions{StateFile: filepath.Join(t.TempDir(), "limiter.json"), CheckpointInterval: time.Hour}
+
+	// Saved right away, regardless of checkpoint interval.
+	NewRateLimiter(opt).SetGlobalWait(time.Minute)
+
+	restarted := NewRateLimiter(opt)
+	if restarted.Allow() {
+		t.Fatal("restarted limiter allowed request during 429 suspension")
+	}
+
+	if restarted.Snapshot().GlobalWaitUntil.IsZero() {
+		t.Fatal("global wait wasn't restored")
+	}
+}
+
+func TestRateLimiterStateIgnored(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+	}{
+		{name: "expired window", content: `{"reset_at":"2020-01-01T00:00:00Z","global_wait_until":"2020-01-01T00:00:00Z","remaining":0}`},
+		{name: "negative remaining", content: `{"reset_at":"2999-01-01T00:00:00Z","remaining":-1}`},
+		{name: "corrupted", content: `{"reset_at":`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "limiter.json")
+			if err := os.WriteFile(path, []byte(tt.content), 0o600); err != nil {
+				t.Fatal(err)
+			}
+
+			snapshot := NewRateLimiter(RateLimiterOptions{Limit: 10, StateFile: path}).Snapshot()
+			if snapshot.Remaining != 10 || !snapshot.GlobalWaitUntil.IsZero() {
+				t.Fatalf("got %+v, want fresh limiter", snapshot)
+			}
+		})
+	}
+}
+
+func TestRateLimiterCheckpointDebounced(t *testing.T) {
+	opt := RateLimiterOptions{Limit: 10, StateFile: filepath.Join(t.TempDir(), "limiter.json"), CheckpointInterval: time.Hour}
+	rl := NewRateLimiter(opt)
+
+	rl.Allow()
+	waitForState(t, opt.StateFile, 9)
+
+	// Next write is due in an hour, so these must not reach the file.
+	for i := 0; i < 5; i++ {
+		rl.Allow()
+	}
+	time.Sleep(50 * time.Millisecond)
+
+	var state rateLimiterState
+	if b, err := os.ReadFile(opt.StateFile); err != nil || json.Unmarshal(b, &state) != nil || state.Remaining != 9 {
+		t.Fatalf("state file holds %d remaining uses, want 9 until next checkpoint", state.Remaining)
+	}
+}
+
+func TestWriteFileAtomic(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "state.json")
+
+	for _, content := range []string{"first", "second"} {
+		if err := writeFileAtomic(path, []byte(content)); err != nil {
+			t.Fatalf("writeFileAtomic: %v", err)
+		}
+
+		if b, err := os.ReadFile(path); err != nil || string(b) != content {
+			t.Fatalf("file holds %q, %v; want %q", b, err, content)
+		}
+	}
+
+	if entries, _ := os.ReadDir(dir); len(entries) != 1 {
+		t.Fatalf("got %d files in directory, want no temporary ones left behind", len(entries))
+	}
+
+	if err := writeFileAtomic(filepath.Join(dir, "missing", "state.json"), []byte("x")); err == nil {
+		t.Fatal("write into missing directory succeeded")
+	}
+}
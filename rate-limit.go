@@ -1,7 +1,9 @@
 package topgg
 
 import (
+	"compress/gzip"
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"log"
@@ -12,28 +14,83 @@ import (
 	"time"
 )
 
+// Paces outgoing requests of the Client. RateLimiter is used by default, but it can be
+// swapped for e.g. a distributed limiter or a no-op one in tests.
+type Limiter interface {
+	// Blocks until next request is allowed to be sent or context is done.
+	Wait(ctx context.Context) error
+	// Suspends all requests for given duration, called when Top.gg responds with 429.
+	SetGlobalWait(d time.Duration)
+}
+
 type Bucket struct {
-	ResetAt   time.Time
+	ResetAt   time.Time // In burst mode, time when last use was refilled.
 	Remaining int
-	Limit     int
+	Limit     int // Uses per second.
+	Burst     int // When above 0, bucket holds up to Burst uses refilled one by one at Limit per second, instead of fixed one-second windows.
 	mu        sync.Mutex
 }
 
 type RateLimiterOptions struct {
-	TraceLogger *log.Logger
+	TraceLogger        *log.Logger
+	MetricsSink        MetricsSink
+	OnLongWait         func(remaining time.Duration) // Called once per Wait that's going to take longer than LongWaitThreshold, with expected remaining delay.
+	StateFile          string                        // When set, limiter state is checkpointed to this file and restored on start, so restarts don't burst past limits.
+	Limit              int                           // Sustained number of requests per second, defaults to 100.
+	Burst              int                           // Up to Burst requests go through immediately, then they're paced at Limit per second. 0 uses fixed one-second windows of Limit requests.
+	LongWaitThreshold  time.Duration                 // Defaults to 10 seconds.
+	CheckpointInterval time.Duration                 // Minimal delay between state file writes, defaults to 5 seconds. 429 suspensions are always saved immediately.
+	LockFree           bool                          // Uses lock-free bucket, for read-heavy workloads calling Allow from many goroutines. Ignored when Burst is set.
 }
 
 type RateLimiter struct {
-	globalWait  time.Time
 	traceLogger *log.Logger
+	onLongWait  func(remaining time.Duration)
+	sink        MetricsSink
+	stateFile   string
+	fast        *atomicBucket // Replaces bucket when set.
+
+	bucket             Bucket
+	checkpointInterval time.Duration
+	longWaitThreshold  time.Duration
+	lastCheckpoint     atomic.Int64
+	saveScheduled      atomic.Bool
+	globalWaitUntil    atomic.Int64 // Unix nanoseconds, 0 when not suspended. Atomic so Allow takes no extra lock.
+	stateMu            sync.Mutex
+}
+
+// Decides what happens with uses of current window when limit changes, see RateLimiter.SetLimit.
+type ReconfigureMode uint8
+
+const (
+	ReconfigureRescale ReconfigureMode = iota // Scales uses of current window proportionally to new limit.
+	ReconfigureReset                          // Starts fresh window with full new limit.
+)
 
-	bucket   Bucket
-	globalMu sync.RWMutex
+// Point-in-time view of RateLimiter state, e.g. for debug commands.
+type RateLimiterSnapshot struct {
+	ResetAt         time.Time // When global bucket refills.
+	GlobalWaitUntil time.Time // Zero when no 429 suspension is active.
+	Remaining       int
+	Limit           int
+	Burst           int
 }
 
 type rateLimitTransport struct {
-	limiter        *RateLimiter
+	limiter        Limiter
 	innerTransport http.RoundTripper
+	hooks          ClientHooks
+	traceLogger    *log.Logger
+	counters       *expvarCounters
+	latencies      *endpointLatencies
+	sink           MetricsSink
+	shouldRetry    func(resp *http.Response, err error, attempt int) bool
+
+	budgetWindowStart time.Time
+	retryBudgetWindow time.Duration
+	retryBudget       uint32
+	budgetUsed        uint32
+	budgetMu          sync.Mutex
 
 	retryCounter   atomic.Int64
 	retryThreshold int64
@@ -46,13 +103,51 @@ func NewRateLimiter(opt RateLimiterOptions) *RateLimiter {
 		opt.TraceLogger = log.New(io.Discard, "", 0)
 	}
 
-	return &RateLimiter{
-		traceLogger: opt.TraceLogger,
+	checkpointInterval := opt.CheckpointInterval
+	if checkpointInterval <= 0 {
+		checkpointInterval = 5 * time.Second
+	}
+
+	limit := opt.Limit
+	if limit <= 0 {
+		limit = 100
+	}
+
+	longWaitThreshold := opt.LongWaitThreshold
+	if longWaitThreshold <= 0 {
+		longWaitThreshold = 10 * time.Second
+	}
+
+	var sink MetricsSink = nopSink{}
+	if opt.MetricsSink != nil {
+		sink = opt.MetricsSink
+	}
+
+	rl := &RateLimiter{
+		traceLogger:        opt.TraceLogger,
+		onLongWait:         opt.OnLongWait,
+		sink:               sink,
+		longWaitThreshold:  longWaitThreshold,
+		stateFile:          opt.StateFile,
+		checkpointInterval: checkpointInterval,
 		bucket: Bucket{
-			Limit:     100,
-			Remaining: 100,
+			Limit:     limit,
+			Remaining: limit,
+			Burst:     opt.Burst,
 		},
 	}
+
+	if opt.LockFree && opt.Burst <= 0 {
+		rl.fast = newAtomicBucket(limit)
+	}
+
+	if rl.stateFile != "" {
+		if err := rl.loadState(); err != nil {
+			rl.tracef("Failed to restore rate limiter state from %s: %v", rl.stateFile, err)
+		}
+	}
+
+	return rl
 }
 
 func (rl *RateLimiter) tracef(format string, v ...any) {
@@ -60,11 +155,23 @@ func (rl *RateLimiter) tracef(format string, v ...any) {
 }
 
 func (rl *RateLimiter) Wait(ctx context.Context) error {
-	rl.globalMu.RLock()
-	if !rl.globalWait.IsZero() && time.Now().Before(rl.globalWait) {
-		wait := time.Until(rl.globalWait)
-		rl.globalMu.RUnlock()
+	start := time.Now()
+	reported := false
+	reportLongWait := func(wait time.Duration) {
+		if rl.onLongWait == nil || reported || time.Since(start)+wait < rl.longWaitThreshold {
+			return
+		}
+
+		reported = true
+		if err := safeCall("rate limiter", "OnLongWait", func() { rl.onLongWait(wait) }); err != nil {
+			rl.tracef("%v", err)
+		}
+	}
+
+	if globalWait := rl.globalWait(); !globalWait.IsZero() && time.Now().Before(globalWait) {
+		wait := time.Until(globalWait)
 		rl.tracef("Global rate limit or 429 hit! Waiting %s...", wait.Round(time.Millisecond))
+		reportLongWait(wait)
 
 		timer := time.NewTimer(wait)
 		select {
@@ -73,29 +180,18 @@ func (rl *RateLimiter) Wait(ctx context.Context) error {
 			return ctx.Err()
 		case <-timer.C:
 		}
-	} else {
-		rl.globalMu.RUnlock()
 	}
 
 	for {
-		rl.bucket.mu.Lock()
-		now := time.Now()
-
-		if now.After(rl.bucket.ResetAt) {
-			rl.bucket.Remaining = rl.bucket.Limit
-			rl.bucket.ResetAt = now.Add(time.Second)
-		}
-
-		if rl.bucket.Remaining > 0 {
-			rl.bucket.Remaining--
-			rl.bucket.mu.Unlock()
+		ok, waitDuration := rl.take(time.Now())
+		if ok {
+			rl.sink.Timing(MetricRateLimitWait, time.Since(start))
+			rl.checkpoint()
 			return nil
 		}
 
-		waitDuration := rl.bucket.ResetAt.Sub(now)
-		rl.bucket.mu.Unlock()
-
 		rl.tracef("Rate limit hit on global bucket! Waiting %s...", waitDuration.Round(time.Millisecond))
+		reportLongWait(waitDuration)
 
 		timer := time.NewTimer(waitDuration)
 		select {
@@ -107,18 +203,322 @@ func (rl *RateLimiter) Wait(ctx context.Context) error {
 	}
 }
 
+func (rl *RateLimiter) take(now time.Time) (bool, time.Duration) {
+	if rl.fast != nil {
+		return rl.fast.take(now)
+	}
+
+	return rl.bucket.take(now)
+}
+
+// Returns time until which all requests are suspended, zero when they never were.
+func (rl *RateLimiter) globalWait() time.Time {
+	if until := rl.globalWaitUntil.Load(); until != 0 {
+		return time.Unix(0, until)
+	}
+
+	return time.Time{}
+}
+
+// Non-blocking variant of Wait. Takes one use from the bucket if it's available right away.
+func (rl *RateLimiter) Allow() bool {
+	ok, _ := rl.AllowWithDelay()
+	return ok
+}
+
+// Like Allow, but when request isn't allowed it also reports how long caller has to wait,
+// so it can be relayed to users (e.g. "try again in 42s").
+func (rl *RateLimiter) AllowWithDelay() (bool, time.Duration) {
+	now := time.Now()
+	if globalWait := rl.globalWait(); now.Before(globalWait) {
+		return false, globalWait.Sub(now)
+	}
+
+	ok, retryIn := rl.take(now)
+	if ok {
+		rl.checkpoint()
+	}
+
+	return ok, retryIn
+}
+
+// Refills bucket up to given time, caller must hold the lock.
+func (b *Bucket) refill(now time.Time) {
+	if b.Burst <= 0 {
+		if now.After(b.ResetAt) {
+			b.Remaining = b.Limit
+			b.ResetAt = now.Add(time.Second)
+		}
+		return
+	}
+
+	if b.ResetAt.IsZero() {
+		b.Remaining = b.Burst
+		b.ResetAt = now
+		return
+	}
+
+	interval := b.refillInterval()
+	if elapsed := now.Sub(b.ResetAt); elapsed >= interval {
+		refilled := int(elapsed / interval)
+		b.Remaining += refilled
+		b.ResetAt = b.ResetAt.Add(time.Duration(refilled) * interval)
+		if b.Remaining >= b.Burst {
+			b.Remaining = b.Burst
+			b.ResetAt = now
+		}
+	}
+}
+
+func (b *Bucket) refillInterval() time.Duration {
+	if b.Limit <= 0 {
+		return time.Second
+	}
+
+	return time.Second / time.Duration(b.Limit)
+}
+
+// Takes single use from the bucket. When bucket is empty, returns how long it takes until next refill.
+func (b *Bucket) take(now time.Time) (bool, time.Duration) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.refill(now)
+	if b.Remaining > 0 {
+		b.Remaining--
+		return true, 0
+	}
+
+	if b.Burst > 0 {
+		return false, b.ResetAt.Add(b.refillInterval()).Sub(now)
+	}
+
+	return false, b.ResetAt.Sub(now)
+}
+
 func (rl *RateLimiter) SetGlobalWait(d time.Duration) {
-	rl.globalMu.Lock()
-	defer rl.globalMu.Unlock()
+	rl.globalWaitUntil.Store(time.Now().Add(d).UnixNano())
 
-	rl.globalWait = time.Now().Add(d)
 	rl.tracef("Received 429! All requests suspended for %s", d.Round(time.Millisecond))
+	rl.sink.Count(MetricRateLimitSuspended, 1)
+	rl.save()
+}
+
+// Changes number of requests allowed per second, e.g. when Top.gg changes documented limits.
+// Remaining uses never exceed new limit, so callers blocked in Wait are never stuck until window expiry.
+func (rl *RateLimiter) SetLimit(limit int, mode ReconfigureMode) {
+	if limit <= 0 {
+		return
+	}
+
+	if rl.fast != nil {
+		_, _, previous := rl.fast.snapshot(time.Now())
+		rl.fast.setLimit(limit, mode)
+		rl.tracef("Limit changed from %d to %d requests per second", previous, limit)
+		return
+	}
+
+	rl.bucket.mu.Lock()
+	defer rl.bucket.mu.Unlock()
+
+	switch {
+	case rl.bucket.Burst > 0:
+		// Burst allowance doesn't depend on the rate, only the refill speed changes.
+		rl.bucket.refill(time.Now())
+		if mode == ReconfigureReset {
+			rl.bucket.Remaining = rl.bucket.Burst
+		}
+	case mode == ReconfigureReset:
+		rl.bucket.Remaining = limit
+		rl.bucket.ResetAt = time.Now().Add(time.Second)
+	default:
+		rescaled := limit
+		if rl.bucket.Limit > 0 && time.Now().Before(rl.bucket.ResetAt) {
+			used := rl.bucket.Limit - rl.bucket.Remaining
+			rescaled = limit - used*limit/rl.bucket.Limit
+		}
+
+		if rescaled < 0 {
+			rescaled = 0
+		}
+
+		rl.bucket.Remaining = rescaled
+	}
+
+	rl.tracef("Limit changed from %d to %d requests per second", rl.bucket.Limit, limit)
+	rl.bucket.Limit = limit
+}
+
+// Returns current state of the limiter. Top.gg rate limits are tracked in a single, global bucket.
+func (rl *RateLimiter) Snapshot() RateLimiterSnapshot {
+	var snapshot RateLimiterSnapshot
+
+	if globalWait := rl.globalWait(); time.Now().Before(globalWait) {
+		snapshot.GlobalWaitUntil = globalWait
+	}
+
+	if rl.fast != nil {
+		snapshot.ResetAt, snapshot.Remaining, snapshot.Limit = rl.fast.snapshot(time.Now())
+		return snapshot
+	}
+
+	rl.bucket.mu.Lock()
+	if rl.bucket.Burst > 0 {
+		rl.bucket.refill(time.Now())
+	}
+
+	snapshot.Limit = rl.bucket.Limit
+	snapshot.Burst = rl.bucket.Burst
+	snapshot.Remaining = rl.bucket.Remaining
+	snapshot.ResetAt = rl.bucket.ResetAt
+	if rl.bucket.Burst <= 0 && time.Now().After(rl.bucket.ResetAt) {
+		snapshot.Remaining = rl.bucket.Limit
+	}
+	rl.bucket.mu.Unlock()
+
+	return snapshot
 }
 
 func (t *rateLimitTransport) tripped() bool {
 	return t.trippedUntil.Load() > time.Now().UnixNano()
 }
 
+// Reserves one retry from the shared budget. Returns false when budget of current window is spent.
+func (t *rateLimitTransport) takeRetryBudget() bool {
+	if t.retryBudget == 0 {
+		return true
+	}
+
+	t.budgetMu.Lock()
+	defer t.budgetMu.Unlock()
+
+	now := time.Now()
+	if now.Sub(t.budgetWindowStart) >= t.retryBudgetWindow {
+		t.budgetWindowStart = now
+		t.budgetUsed = 0
+	}
+
+	if t.budgetUsed >= t.retryBudget {
+		return false
+	}
+
+	t.budgetUsed++
+	return true
+}
+
+// Suspends limiter for duration requested by 429 response, or a minute when Top.gg didn't say.
+// Returns the applied duration, zero when Retry-After couldn't be parsed.
+func (t *rateLimitTransport) applyRetryAfter(resp *http.Response) time.Duration {
+	retryAfter := time.Minute
+	if retryAfterStr := resp.Header.Get("Retry-After"); retryAfterStr != "" {
+		retryAfterSec, err := strconv.Atoi(retryAfterStr)
+		if err != nil {
+			return 0
+		}
+
+		retryAfter = time.Duration(retryAfterSec) * time.Second
+	}
+
+	t.limiter.SetGlobalWait(retryAfter)
+	return retryAfter
+}
+
+// Waits for the limiter, within what's left of WithMaxTotalWait of the request. Time waited is added to waited.
+func (t *rateLimitTransport) waitLimiter(req *http.Request, limits waitLimits, waited *time.Duration) error {
+	ctx := req.Context()
+	if limits.maxTotalWait <= 0 {
+		return t.limiter.Wait(ctx)
+	}
+
+	remaining := limits.maxTotalWait - *waited
+	if remaining <= 0 {
+		return &WaitLimitError{Wait: *waited, Limit: limits.maxTotalWait}
+	}
+
+	// Default limiter knows about suspension after 429 upfront, so there's no point in waiting it out partially.
+	if rl, ok := t.limiter.(*RateLimiter); ok {
+		if suspended := time.Until(rl.globalWait()); suspended > remaining {
+			return &WaitLimitError{Wait: *waited + suspended, Limit: limits.maxTotalWait}
+		}
+	}
+
+	waitCtx, cancel := context.WithTimeout(ctx, remaining)
+	defer cancel()
+
+	start := time.Now()
+	err := t.limiter.Wait(waitCtx)
+	*waited += time.Since(start)
+	if err != nil && ctx.Err() == nil && errors.Is(err, context.DeadlineExceeded) {
+		return &WaitLimitError{Wait: *waited, Limit: limits.maxTotalWait}
+	}
+
+	return err
+}
+
+// Checks wait requested by Retry-After of 429 response against limits of the request.
+func checkRetryAfter(retryAfter time.Duration, limits waitLimits, waited time.Duration) error {
+	if limits.maxRetryAfter > 0 && retryAfter > limits.maxRetryAfter {
+		return &WaitLimitError{Wait: retryAfter, Limit: limits.maxRetryAfter, RetryAfter: true}
+	}
+
+	if limits.maxTotalWait > 0 && waited+retryAfter > limits.maxTotalWait {
+		return &WaitLimitError{Wait: waited + retryAfter, Limit: limits.maxTotalWait, RetryAfter: true}
+	}
+
+	return nil
+}
+
+// Bounds error response body kept in APIError of failed retries.
+const maxErrorBodySize = 64 * 1024
+
+// Reads body of error response that's retried, so its message survives when retries run out.
+func readErrorBody(resp *http.Response) ([]byte, error) {
+	var reader io.Reader = resp.Body
+	if resp.Header.Get("Content-Encoding") == "gzip" {
+		gz, err := gzip.NewReader(resp.Body)
+		if err != nil {
+			return nil, err
+		}
+
+		defer gz.Close()
+		reader = gz
+	}
+
+	body, err := io.ReadAll(io.LimitReader(reader, maxErrorBodySize))
+	if err != nil {
+		return nil, err
+	}
+
+	// Drained, so connection can be reused.
+	_, err = io.Copy(io.Discard, resp.Body)
+	return body, err
+}
+
+// Waits before next attempt of failed request. Time waited is added to waited.
+func (t *rateLimitTransport) backoff(req *http.Request, attempt uint8, lastErr error, waited *time.Duration) error {
+	delay := time.Millisecond * time.Duration(250*int64(attempt+1))
+	if limits := waitLimitsFrom(req.Context()); limits.maxTotalWait > 0 && *waited+delay > limits.maxTotalWait {
+		return &WaitLimitError{Wait: *waited + delay, Limit: limits.maxTotalWait}
+	}
+
+	if !t.takeRetryBudget() {
+		return &retryBudgetError{last: lastErr, window: t.retryBudgetWindow, budget: t.retryBudget}
+	}
+
+	t.hooks.retry(t.traceLogger, req, int(attempt)+2, lastErr)
+	t.sink.Count(MetricAPIRetries, 1, "method:"+req.Method, "path:"+endpointTemplate(req.URL.Path))
+	timer := time.NewTimer(delay)
+	select {
+	case <-req.Context().Done():
+		timer.Stop()
+		return req.Context().Err()
+	case <-timer.C:
+	}
+
+	*waited += delay
+	return nil
+}
+
 func (t *rateLimitTransport) RoundTrip(req *http.Request) (*http.Response, error) {
 	var (
 		lastResp *http.Response
@@ -132,12 +532,20 @@ func (t *rateLimitTransport) RoundTrip(req *http.Request) (*http.Response, error
 		}
 	}()
 
-	for i := uint8(0); i < t.maxRetries; i++ {
+	maxRetries := t.maxRetries
+	if noRetry(req.Context()) {
+		maxRetries = 1
+	}
+
+	limits := waitLimitsFrom(req.Context())
+	var waited time.Duration // Spent on limiter and backoff, counted against WithMaxTotalWait.
+
+	for i := uint8(0); i < maxRetries; i++ {
 		if t.tripped() {
 			return nil, ErrLocalRatelimit
 		}
 
-		if err := t.limiter.Wait(req.Context()); err != nil {
+		if err := t.waitLimiter(req, limits, &waited); err != nil {
 			return nil, err
 		}
 
@@ -156,7 +564,38 @@ func (t *rateLimitTransport) RoundTrip(req *http.Request) (*http.Response, error
 			req.Body = body
 		}
 
+		t.hooks.request(t.traceLogger, req, int(i)+1)
+		t.counters.add(ExpvarAPIRequests)
+		start := time.Now()
 		resp, err := t.innerTransport.RoundTrip(req)
+		if err != nil {
+			markRoundTripTimeout(req, err)
+		} else {
+			duration := time.Since(start)
+			t.hooks.response(t.traceLogger, req, resp.StatusCode, int(i)+1, duration)
+			// Voter IDs are templated out of the path, so tag cardinality stays bounded.
+			path := "path:" + endpointTemplate(req.URL.Path)
+			t.sink.Count(MetricAPIRequests, 1, "method:"+req.Method, path, "status:"+strconv.Itoa(resp.StatusCode))
+			t.sink.Timing(MetricAPILatency, duration, "method:"+req.Method, path)
+			t.latencies.observe(req.Method, req.URL.Path, duration)
+			if resp.StatusCode == http.StatusTooManyRequests {
+				t.counters.add(ExpvarAPI429s)
+			}
+		}
+
+		retry := err != nil || resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= http.StatusInternalServerError
+		if t.shouldRetry != nil {
+			retry = t.shouldRetry(resp, err, int(i)+1)
+		}
+
+		if !retry {
+			if err == nil && resp.StatusCode == http.StatusTooManyRequests {
+				t.applyRetryAfter(resp)
+			}
+
+			return resp, err
+		}
+
 		if err != nil {
 			lastErr = err
 
@@ -168,13 +607,9 @@ func (t *rateLimitTransport) RoundTrip(req *http.Request) (*http.Response, error
 				}
 			}
 
-			if i < t.maxRetries-1 {
-				timer := time.NewTimer(time.Millisecond * time.Duration(250*int64(i+1)))
-				select {
-				case <-req.Context().Done():
-					timer.Stop()
-					return nil, req.Context().Err()
-				case <-timer.C:
+			if i < maxRetries-1 {
+				if err := t.backoff(req, i, lastErr, &waited); err != nil {
+					return nil, err
 				}
 			}
 
@@ -195,27 +630,22 @@ func (t *rateLimitTransport) RoundTrip(req *http.Request) (*http.Response, error
 				}
 			}
 
-			if retryAfterStr := resp.Header.Get("Retry-After"); retryAfterStr != "" {
-				if retryAfterSec, err := strconv.Atoi(retryAfterStr); err == nil {
-					t.limiter.SetGlobalWait(time.Duration(retryAfterSec) * time.Second)
-				}
-			} else {
-				t.limiter.SetGlobalWait(time.Minute)
-			}
-
+			retryAfter := t.applyRetryAfter(resp)
 			lastResp = resp
 			lastErr = ErrRemoteRatelimit
 			if cErr := resp.Body.Close(); cErr != nil {
 				lastErr = fmt.Errorf("%w, and failed to close response body: %v", lastErr, cErr)
 			}
 
-			if i < t.maxRetries-1 {
-				timer := time.NewTimer(time.Millisecond * time.Duration(250*int64(i+1)))
-				select {
-				case <-req.Context().Done():
-					timer.Stop()
-					return nil, req.Context().Err()
-				case <-timer.C:
+			if i < maxRetries-1 {
+				if err := checkRetryAfter(retryAfter, limits, waited); err != nil {
+					return nil, err
+				}
+			}
+
+			if i < maxRetries-1 {
+				if err := t.backoff(req, i, lastErr, &waited); err != nil {
+					return nil, err
 				}
 			}
 
@@ -236,31 +666,56 @@ func (t *rateLimitTransport) RoundTrip(req *http.Request) (*http.Response, error
 				}
 			}
 
-			if _, err := io.Copy(io.Discard, resp.Body); err != nil {
+			if body, err := readErrorBody(resp); err != nil {
 				lastErr = fmt.Errorf("%w: failed to read top.gg API internal server error body: %v", ErrRequestFailed, err)
 			} else {
-				lastErr = fmt.Errorf("%w: top.gg API internal server error: %s", ErrRequestFailed, resp.Status)
+				lastErr = newAPIError(resp.StatusCode, body)
 			}
+			lastErr = &unavailableError{err: lastErr}
 
 			lastResp = resp
 			if cErr := resp.Body.Close(); cErr != nil {
 				lastErr = fmt.Errorf("%w, and failed to close response body: %v", lastErr, cErr)
 			}
 
-			if i < t.maxRetries-1 {
-				timer := time.NewTimer(time.Millisecond * time.Duration(250*int64(i+1)))
-				select {
-				case <-req.Context().Done():
-					timer.Stop()
-					return nil, req.Context().Err()
-				case <-timer.C:
+			if i < maxRetries-1 {
+				if err := t.backoff(req, i, lastErr, &waited); err != nil {
+					return nil, err
 				}
 			}
 
 			continue
 		}
 
-		return resp, nil
+		// Status that's not retried by default, but custom ShouldRetry asked for it.
+		if !retrying {
+			retrying = true
+			if t.retryCounter.Add(1) > t.retryThreshold {
+				t.trippedUntil.Store(time.Now().Add(5 * time.Second).UnixNano())
+				errRet := fmt.Errorf("%w: global retry threshold (%d) exceeded", ErrLocalRatelimit, t.retryThreshold)
+				if cErr := resp.Body.Close(); cErr != nil {
+					errRet = fmt.Errorf("%w, and failed to close response body: %v", errRet, cErr)
+				}
+
+				return nil, errRet
+			}
+		}
+
+		lastResp = resp
+		lastErr = fmt.Errorf("%w: %s", ErrRequestFailed, resp.Status)
+		if _, err := io.Copy(io.Discard, resp.Body); err != nil {
+			lastErr = fmt.Errorf("%w, and failed to read response body: %v", lastErr, err)
+		}
+
+		if cErr := resp.Body.Close(); cErr != nil {
+			lastErr = fmt.Errorf("%w, and failed to close response body: %v", lastErr, cErr)
+		}
+
+		if i < maxRetries-1 {
+			if err := t.backoff(req, i, lastErr, &waited); err != nil {
+				return nil, err
+			}
+		}
 	}
 
 	if lastErr != nil {
@@ -268,7 +723,7 @@ func (t *rateLimitTransport) RoundTrip(req *http.Request) (*http.Response, error
 			return nil, lastErr
 		}
 
-		return nil, fmt.Errorf("request failed after %d retries: %w", t.maxRetries, lastErr)
+		return nil, fmt.Errorf("request failed after %d retries: %w", maxRetries, lastErr)
 	}
 
 	return lastResp, nil
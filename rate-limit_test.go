@@ -0,0 +1,397 @@
+package topgg
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/url"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// Limiter that never waits, recording suspensions requested after 429 responses.
+type recordingLimiter struct {
+	noopLimiter
+	globalWaits []time.Duration
+}
+
+func (l *recordingLimiter) SetGlobalWait(d time.Duration) {
+	l.globalWaits = append(l.globalWaits, d)
+}
+
+func TestRetries(t *testing.T) {
+	const vote = `{"created_at":"2026-01-01T00:00:00Z","expires_at":"2026-01-01T12:00:00Z","weight":1}`
+
+	type response struct {
+		retryAfter string
+		status     int
+	}
+
+	tests := []struct {
+		wantErr      error
+		name         string
+		responses    []response // Last one repeats.
+		opts         []RequestOption
+		globalWaits  []time.Duration
+		wantAttempts int64
+		wantLimitErr bool
+	}{
+		{
+			name:         "success isn't retried",
+			responses:    []response{{status: 200}},
+			wantAttempts: 1,
+		},
+		{
+			name:         "client error isn't retried",
+			responses:    []response{{status: 400}},
+			wantAttempts: 1,
+			wantErr:      ErrRequestFailed,
+		},
+		{
+			name:         "server error is retried",
+			responses:    []response{{status: 502}, {status: 200}},
+			wantAttempts: 2,
+		},
+		{
+			name:         "server errors exhaust retries",
+			responses:    []response{{status: 500}},
+			wantAttempts: 3,
+			wantErr:      ErrRequestFailed,
+		},
+		{
+			name:         "NoRetry makes single attempt",
+			responses:    []response{{status: 500}},
+			opts:         []RequestOption{NoRetry()},
+			wantAttempts: 1,
+			wantErr:      ErrRequestFailed,
+		},
+		{
+			name:         "429 suspends limiter for Retry-After",
+			responses:    []response{{status: 429, retryAfter: "2"}, {status: 200}},
+			globalWaits:  []time.Duration{2 * time.Second},
+			wantAttempts: 2,
+		},
+		{
+			name:         "429 without Retry-After suspends limiter for a minute",
+			responses:    []response{{status: 429}, {status: 200}},
+			globalWaits:  []time.Duration{time.Minute},
+			wantAttempts: 2,
+		},
+		{
+			name:         "Retry-After above WithMaxRetryAfter fails fast",
+			responses:    []response{{status: 429, retryAfter: "120"}},
+			opts:         []RequestOption{WithMaxRetryAfter(time.Minute)},
+			globalWaits:  []time.Duration{2 * time.Minute},
+			wantAttempts: 1,
+			wantLimitErr: true,
+		},
+		{
+			name:         "Retry-After above WithMaxTotalWait fails fast",
+			responses:    []response{{status: 429, retryAfter: "10"}},
+			opts:         []RequestOption{WithMaxTotalWait(5 * time.Second)},
+			globalWaits:  []time.Duration{10 * time.Second},
+			wantAttempts: 1,
+			wantLimitErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var attempts atomic.Int64
+			limiter := &recordingLimiter{}
+			client := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				i := int(attempts.Add(1)) - 1
+				if i >= len(tt.responses) {
+					i = len(tt.responses) - 1
+				}
+
+				resp := tt.responses[i]
+				if resp.retryAfter != "" {
+					w.Header().Set("Retry-After", resp.retryAfter)
+				}
+
+				w.WriteHeader(resp.status)
+				if resp.status == http.StatusOK {
+					_, _ = w.Write([]byte(vote))
+				}
+			}), ClientOptions{Limiter: limiter})
+
+			_, err := client.GetVote(context.Background(), 1, PlatformDiscord, tt.opts...)
+
+			var limitErr *WaitLimitError
+			switch {
+			case tt.wantLimitErr:
+				if !errors.As(err, &limitErr) {
+					t.Fatalf("got error %v, want *WaitLimitError", err)
+				}
+			case tt.wantErr != nil:
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("got error %v, want %v", err, tt.wantErr)
+				}
+			case err != nil:
+				t.Fatalf("GetVote: %v", err)
+			}
+
+			if got := attempts.Load(); got != tt.wantAttempts {
+				t.Errorf("made %d attempts, want %d", got, tt.wantAttempts)
+			}
+
+			if len(limiter.globalWaits) != len(tt.globalWaits) {
+				t.Fatalf("limiter suspended for %v, want %v", limiter.globalWaits, tt.globalWaits)
+			}
+
+			for i, wait := range tt.globalWaits {
+				if limiter.globalWaits[i] != wait {
+					t.Errorf("limiter suspended for %v, want %v", limiter.globalWaits, tt.globalWaits)
+				}
+			}
+		})
+	}
+}
+
+func TestRetryBudgetKeepsUnavailable(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	refused := &url.URL{Scheme: "http", Host: listener.Addr().String()}
+	listener.Close()
+
+	tests := []struct {
+		transport http.RoundTripper // Nil uses test server answering 500.
+		name      string
+	}{
+		{name: "server errors"},
+		{name: "connection failures", transport: redirectTransport{target: refused}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusInternalServerError)
+			}), ClientOptions{RetryBudget: 1})
+			if tt.transport != nil {
+				client = NewClient(ClientOptions{Token: "test-token", Transport: tt.transport, Limiter: noopLimiter{}, RetryBudget: 1})
+			}
+
+			_, err := client.GetVote(context.Background(), 1, PlatformDiscord)
+			if !errors.Is(err, ErrLocalRatelimit) {
+				t.Fatalf("got %v, want retry budget error", err)
+			}
+
+			if !IsUnavailable(err) {
+				t.Fatalf("IsUnavailable(%v) = false, want outage to stay recognizable", err)
+			}
+		})
+	}
+}
+
+func TestRateLimiterAllow(t *testing.T) {
+	for _, lockFree := range []bool{false, true} {
+		rl := NewRateLimiter(RateLimiterOptions{Limit: 3, LockFree: lockFree})
+		for i := 0; i < 3; i++ {
+			if ok, delay := rl.AllowWithDelay(); !ok || delay != 0 {
+				t.Fatalf("lock-free %v: use %d got %v, %s; want allowed right away", lockFree, i+1, ok, delay)
+			}
+		}
+
+		if ok, delay := rl.AllowWithDelay(); ok || delay <= 0 || delay > time.Second {
+			t.Fatalf("lock-free %v: use past limit got %v, %s; want denied until window ends", lockFree, ok, delay)
+		}
+
+		if rl.Allow() {
+			t.Fatalf("lock-free %v: Allow took use past limit", lockFree)
+		}
+
+		// 429 suspension applies even when bucket has uses left.
+		suspended := NewRateLimiter(RateLimiterOptions{Limit: 3, LockFree: lockFree})
+		suspended.SetGlobalWait(time.Minute)
+		if ok, delay := suspended.AllowWithDelay(); ok || delay < 59*time.Second {
+			t.Fatalf("lock-free %v: suspended limiter got %v, %s; want denied for a minute", lockFree, ok, delay)
+		}
+
+		if remaining := suspended.Snapshot().Remaining; remaining != 3 {
+			t.Fatalf("lock-free %v: denied call took a use, %d left", lockFree, remaining)
+		}
+	}
+}
+
+func TestRateLimiterSetLimit(t *testing.T) {
+	tests := []struct {
+		name          string
+		opt           RateLimiterOptions
+		newLimit      int
+		wantRemaining int
+		wantLimit     int
+		mode          ReconfigureMode
+	}{
+		{name: "rescale down", opt: RateLimiterOptions{Limit: 10}, newLimit: 5, mode: ReconfigureRescale, wantRemaining: 3, wantLimit: 5},
+		{name: "rescale up", opt: RateLimiterOptions{Limit: 10}, newLimit: 20, mode: ReconfigureRescale, wantRemaining: 12, wantLimit: 20},
+		{name: "reset", opt: RateLimiterOptions{Limit: 10}, newLimit: 5, mode: ReconfigureReset, wantRemaining: 5, wantLimit: 5},
+		{name: "non-positive limit is ignored", opt: RateLimiterOptions{Limit: 10}, newLimit: 0, wantRemaining: 6, wantLimit: 10},
+		{name: "lock-free rescale", opt: RateLimiterOptions{Limit: 10, LockFree: true}, newLimit: 5, mode: ReconfigureRescale, wantRemaining: 3, wantLimit: 5},
+		{name: "lock-free reset", opt: RateLimiterOptions{Limit: 10, LockFree: true}, newLimit: 5, mode: ReconfigureReset, wantRemaining: 5, wantLimit: 5},
+		// Burst allowance stays, only refill speed follows the limit.
+		{name: "burst rescale", opt: RateLimiterOptions{Limit: 1, Burst: 10}, newLimit: 5, mode: ReconfigureRescale, wantRemaining: 6, wantLimit: 5},
+		{name: "burst reset", opt: RateLimiterOptions{Limit: 1, Burst: 10}, newLimit: 5, mode: ReconfigureReset, wantRemaining: 10, wantLimit: 5},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rl := NewRateLimiter(tt.opt)
+			for i := 0; i < 4; i++ {
+				rl.Allow()
+			}
+
+			rl.SetLimit(tt.newLimit, tt.mode)
+			if snapshot := rl.Snapshot(); snapshot.Remaining != tt.wantRemaining || snapshot.Limit != tt.wantLimit {
+				t.Fatalf("got %d uses of limit %d, want %d of %d", snapshot.Remaining, snapshot.Limit, tt.wantRemaining, tt.wantLimit)
+			}
+		})
+	}
+}
+
+func TestRateLimiterSetLimitSpeedsUpRefill(t *testing.T) {
+	rl := NewRateLimiter(RateLimiterOptions{Limit: 1, Burst: 1})
+	if !rl.Allow() {
+		t.Fatal("first use was denied")
+	}
+
+	// At 1 per second, next use would be a second away.
+	rl.SetLimit(1000, ReconfigureRescale)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+	defer cancel()
+	if err := rl.Wait(ctx); err != nil {
+		t.Fatalf("Wait after raising limit: %v", err)
+	}
+}
+
+func TestBucketBurst(t *testing.T) {
+	start := time.UnixMilli(1_700_000_000_000)
+	b := &Bucket{Limit: 10, Burst: 3} // Refills one use every 100ms.
+
+	steps := []struct {
+		at       time.Duration // Since start.
+		wantOK   bool
+		wantWait time.Duration
+	}{
+		{at: 0, wantOK: true},
+		{at: 0, wantOK: true},
+		{at: 0, wantOK: true},
+		{at: 0, wantWait: 100 * time.Millisecond},
+		{at: 40 * time.Millisecond, wantWait: 60 * time.Millisecond},
+		{at: 100 * time.Millisecond, wantOK: true},
+		{at: 150 * time.Millisecond, wantWait: 50 * time.Millisecond},
+		// Two uses refilled meanwhile, partial interval carries over to the next one.
+		{at: 320 * time.Millisecond, wantOK: true},
+		{at: 320 * time.Millisecond, wantOK: true},
+		{at: 320 * time.Millisecond, wantWait: 80 * time.Millisecond},
+		// Long idle time refills up to Burst only.
+		{at: time.Minute, wantOK: true},
+		{at: time.Minute, wantOK: true},
+		{at: time.Minute, wantOK: true},
+		{at: time.Minute, wantWait: 100 * time.Millisecond},
+	}
+
+	for i, step := range steps {
+		ok, wait := b.take(start.Add(step.at))
+		if ok != step.wantOK || wait != step.wantWait {
+			t.Fatalf("take %d at +%s = %v, %s; want %v, %s", i+1, step.at, ok, wait, step.wantOK, step.wantWait)
+		}
+	}
+}
+
+func TestRateLimiterBurstIgnoresLockFree(t *testing.T) {
+	rl := NewRateLimiter(RateLimiterOptions{Limit: 1, Burst: 5, LockFree: true})
+	if rl.fast != nil {
+		t.Fatal("lock-free bucket was used in burst mode")
+	}
+
+	for i := 0; i < 5; i++ {
+		if !rl.Allow() {
+			t.Fatalf("use %d of burst was denied", i+1)
+		}
+	}
+
+	if snapshot := rl.Snapshot(); snapshot.Remaining != 0 || snapshot.Burst != 5 {
+		t.Fatalf("got %+v after spending burst", snapshot)
+	}
+}
+
+func TestRateLimiterSnapshot(t *testing.T) {
+	rl := NewRateLimiter(RateLimiterOptions{Limit: 5})
+	if snapshot := rl.Snapshot(); snapshot.Remaining != 5 || snapshot.Limit != 5 || snapshot.Burst != 0 || !snapshot.GlobalWaitUntil.IsZero() {
+		t.Fatalf("fresh limiter snapshot is %+v", snapshot)
+	}
+
+	before := time.Now()
+	rl.Allow()
+	rl.Allow()
+	snapshot := rl.Snapshot()
+	if snapshot.Remaining != 3 || snapshot.ResetAt.Before(before) || snapshot.ResetAt.After(time.Now().Add(time.Second)) {
+		t.Fatalf("got %+v after 2 uses, want 3 left until end of current window", snapshot)
+	}
+
+	// Window ran out, so full limit is reported although bucket refills only on next take.
+	rl.bucket.mu.Lock()
+	rl.bucket.ResetAt = time.Now().Add(-time.Millisecond)
+	rl.bucket.mu.Unlock()
+	if snapshot := rl.Snapshot(); snapshot.Remaining != 5 {
+		t.Fatalf("expired window reports %d uses, want 5", snapshot.Remaining)
+	}
+
+	rl.SetGlobalWait(time.Minute)
+	if until := rl.Snapshot().GlobalWaitUntil; until.Before(time.Now().Add(59 * time.Second)) {
+		t.Fatalf("suspension reported until %s, want a minute from now", until)
+	}
+
+	rl.SetGlobalWait(-time.Second)
+	if until := rl.Snapshot().GlobalWaitUntil; !until.IsZero() {
+		t.Fatalf("past suspension reported until %s", until)
+	}
+
+	burst := NewRateLimiter(RateLimiterOptions{Limit: 1, Burst: 4})
+	burst.Allow()
+	if snapshot := burst.Snapshot(); snapshot.Remaining != 3 || snapshot.Burst != 4 || snapshot.Limit != 1 {
+		t.Fatalf("burst limiter snapshot is %+v", snapshot)
+	}
+}
+
+func TestAllowDoesNotAllocate(t *testing.T) {
+	for _, lockFree := range []bool{false, true} {
+		rl := NewRateLimiter(RateLimiterOptions{Limit: 1_000_000, LockFree: lockFree})
+		if allocs := testing.AllocsPerRun(1000, func() { rl.AllowWithDelay() }); allocs != 0 {
+			t.Errorf("AllowWithDelay (lock-free: %v) made %.1f allocations per call, want 0", lockFree, allocs)
+		}
+	}
+}
+
+func BenchmarkAllow(b *testing.B) {
+	tests := []struct {
+		name      string
+		stateFile bool
+	}{
+		{name: "without state file"},
+		{name: "with state file", stateFile: true},
+	}
+
+	for _, tt := range tests {
+		b.Run(tt.name, func(b *testing.B) {
+			opt := RateLimiterOptions{Limit: 1_000_000}
+			if tt.stateFile {
+				opt.StateFile = filepath.Join(b.TempDir(), "limiter.json")
+			}
+
+			rl := NewRateLimiter(opt)
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				rl.AllowWithDelay()
+			}
+		})
+	}
+}
@@ -0,0 +1,64 @@
+package topgg
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/url"
+)
+
+// Raw API request for endpoints the SDK doesn't wrap yet, see Client.Do.
+type Request struct {
+	Body   any        // Encoded as JSON when not nil.
+	Query  url.Values // Optional query string.
+	Method string
+	Path   string // Relative to BaseURL, e.g. "/v1/projects/@me".
+}
+
+// Raw 2xx API response, see Client.Do.
+type Response struct {
+	codec      Codec
+	Header     http.Header
+	Body       []byte
+	StatusCode int
+}
+
+// Decodes JSON body of the response into v, with client's Codec.
+func (r *Response) Decode(v any) error {
+	if r.codec == nil {
+		return json.Unmarshal(r.Body, v)
+	}
+
+	return r.codec.Unmarshal(r.Body, v)
+}
+
+// Sends request with authorization, rate limiting and retries applied, but without response typing,
+// so newly launched Top.gg endpoints can be used before SDK wraps them. Non-2xx responses are returned as *APIError.
+func (c *Client) Do(ctx context.Context, req *Request, opts ...RequestOption) (*Response, error) {
+	route := req.Path
+	if len(req.Query) > 0 {
+		route += "?" + req.Query.Encode()
+	}
+
+	var meta *ResponseMeta
+	opts = append(opts, func(cfg *requestConfig) {
+		if cfg.meta == nil {
+			cfg.meta = &ResponseMeta{}
+		}
+
+		cfg.noCoalesce = true
+		meta = cfg.meta
+	})
+
+	body, err := c.request(ctx, req.Method, route, req.Body, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Response{
+		codec:      c.codec,
+		Header:     meta.Header,
+		Body:       body,
+		StatusCode: meta.StatusCode,
+	}, nil
+}
@@ -0,0 +1,116 @@
+package topgg
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Overrides client defaults for a single API call, e.g. interactive commands wanting a short
+// deadline and no retries while background jobs sharing the same client keep the defaults.
+type RequestOption func(cfg *requestConfig)
+
+type requestConfig struct {
+	meta       *ResponseMeta
+	stream     func(r io.Reader) error
+	timeout    time.Duration
+	waitLimits waitLimits
+	noRetry    bool
+	noCoalesce bool
+	strict     bool
+}
+
+// Bounds the whole call, including rate limiter waits and retries. Implies NoCoalesce, so the deadline
+// covers a request of its own and calls sharing it aren't cut short.
+func WithTimeout(d time.Duration) RequestOption {
+	return func(cfg *requestConfig) {
+		cfg.timeout = d
+		cfg.noCoalesce = true
+	}
+}
+
+// Makes a single attempt, failed requests (including 429) are returned right away.
+func NoRetry() RequestOption {
+	return func(cfg *requestConfig) {
+		cfg.noRetry = true
+	}
+}
+
+// Always sends own request instead of sharing result of identical GET that's already in flight.
+func NoCoalesce() RequestOption {
+	return func(cfg *requestConfig) {
+		cfg.noCoalesce = true
+	}
+}
+
+// Fails the call with *WaitLimitError when Top.gg asks to retry after more than d, instead of waiting.
+// Implies NoCoalesce, so the limit isn't bypassed by sharing result of a call without it.
+func WithMaxRetryAfter(d time.Duration) RequestOption {
+	return func(cfg *requestConfig) {
+		cfg.waitLimits.maxRetryAfter = d
+		cfg.noCoalesce = true
+	}
+}
+
+// Fails the call with *WaitLimitError once rate limiter waits and retry backoffs would exceed d in total.
+// Time spent on requests themselves doesn't count. Implies NoCoalesce.
+func WithMaxTotalWait(d time.Duration) RequestOption {
+	return func(cfg *requestConfig) {
+		cfg.waitLimits.maxTotalWait = d
+		cfg.noCoalesce = true
+	}
+}
+
+// Fills meta with details of the final response, for users doing their own adaptive scheduling.
+// Implies NoCoalesce, since call sharing result of another one has no response of its own.
+func WithMeta(meta *ResponseMeta) RequestOption {
+	return func(cfg *requestConfig) {
+		cfg.meta = meta
+		cfg.noCoalesce = true
+	}
+}
+
+// Details of API response, see WithMeta. Rate limit fields are zero when Top.gg didn't send them.
+type ResponseMeta struct {
+	Header             http.Header
+	RetryAfter         time.Duration // From Retry-After header.
+	Duration           time.Duration // Time since sending the request until reading the response, including retries.
+	StatusCode         int
+	RateLimitLimit     int // From X-RateLimit-Limit header.
+	RateLimitRemaining int // From X-RateLimit-Remaining header.
+}
+
+func (m *ResponseMeta) fill(res *http.Response, duration time.Duration) {
+	m.Header = res.Header
+	m.StatusCode = res.StatusCode
+	m.Duration = duration
+	m.RateLimitLimit, _ = strconv.Atoi(res.Header.Get("X-RateLimit-Limit"))
+	m.RateLimitRemaining, _ = strconv.Atoi(res.Header.Get("X-RateLimit-Remaining"))
+	if seconds, err := strconv.Atoi(res.Header.Get("Retry-After")); err == nil {
+		m.RetryAfter = time.Duration(seconds) * time.Second
+	}
+}
+
+type noRetryKey struct{}
+
+// Reports whether request context was marked with NoRetry, read by rateLimitTransport.
+func noRetry(ctx context.Context) bool {
+	v, _ := ctx.Value(noRetryKey{}).(bool)
+	return v
+}
+
+// Bounds of waiting within a single call, see WithMaxRetryAfter and WithMaxTotalWait.
+type waitLimits struct {
+	maxRetryAfter time.Duration
+	maxTotalWait  time.Duration
+}
+
+type waitLimitsKey struct{}
+
+// Returns wait limits request context was marked with, read by rateLimitTransport.
+func waitLimitsFrom(ctx context.Context) waitLimits {
+	limits, _ := ctx.Value(waitLimitsKey{}).(waitLimits)
+	return limits
+}
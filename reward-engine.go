@@ -0,0 +1,359 @@
+package topgg
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+)
+
+// Perk granted to user for a vote, lasting until ExpiresAt.
+type Reward struct {
+	GrantedAt time.Time
+	ExpiresAt time.Time
+	UserID    Snowflake
+}
+
+// Persists active rewards, so their expiry callbacks fire even when process restarts in between.
+// Every user has at most one reward, PutReward replaces the previous one. GetReward returns nil
+// when user has none. DeleteReward must only delete stored reward if it's the given one (same
+// ExpiresAt), so reward extended by a new vote in the meantime survives.
+type RewardStore interface {
+	GetReward(ctx context.Context, userID Snowflake) (*Reward, error)
+	PutReward(ctx context.Context, reward Reward) error
+	DeleteReward(ctx context.Context, reward Reward) error
+	ListRewards(ctx context.Context) ([]Reward, error)
+}
+
+// Default, in-memory RewardStore. Rewards are lost on restart, use persistent store to keep them.
+type MemoryRewardStore struct {
+	rewards map[Snowflake]Reward
+	mu      sync.Mutex
+}
+
+func NewMemoryRewardStore() *MemoryRewardStore {
+	return &MemoryRewardStore{
+		rewards: make(map[Snowflake]Reward),
+	}
+}
+
+func (s *MemoryRewardStore) GetReward(ctx context.Context, userID Snowflake) (*Reward, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	reward, ok := s.rewards[userID]
+	if !ok {
+		return nil, nil
+	}
+
+	return &reward, nil
+}
+
+func (s *MemoryRewardStore) PutReward(ctx context.Context, reward Reward) error {
+	s.mu.Lock()
+	s.rewards[reward.UserID] = reward
+	s.mu.Unlock()
+	return nil
+}
+
+func (s *MemoryRewardStore) DeleteReward(ctx context.Context, reward Reward) error {
+	s.mu.Lock()
+	if current, ok := s.rewards[reward.UserID]; ok && current.ExpiresAt.Equal(reward.ExpiresAt) {
+		delete(s.rewards, reward.UserID)
+	}
+	s.mu.Unlock()
+	return nil
+}
+
+func (s *MemoryRewardStore) ListRewards(ctx context.Context) ([]Reward, error) {
+	s.mu.Lock()
+	rewards := make([]Reward, 0, len(s.rewards))
+	for _, reward := range s.rewards {
+		rewards = append(rewards, reward)
+	}
+	s.mu.Unlock()
+
+	return rewards, nil
+}
+
+type RewardEngineOptions struct {
+	Store    RewardStore         // Defaults to MemoryRewardStore.
+	API      TopGGClient         // Used by Reconcile instead of the client creating RewardEngine, e.g. MockClient in tests.
+	OnGrant  func(reward Reward) // Called for every vote granting or extending a reward. Votes whose reward would end before the active one (e.g. redeliveries) are ignored.
+	OnExpire func(reward Reward) // Called once reward expires. Reward is deleted only after it returns, so it may run again after crash.
+	OnError  func(err error)     // Called when store fails or OnGrant/OnExpire panics (with *PanicError).
+	TTL      time.Duration       // How long rewards last, defaults to ClientOptions.VoteValidity.
+}
+
+// Grants time-limited rewards for votes and fires expiry callbacks, independent of any perk system.
+// Pass RecordVote as WebhookOptions.OnVote and keep Run going in background.
+type RewardEngine struct {
+	client      TopGGClient
+	store       RewardStore
+	scheduler   Scheduler
+	onGrant     func(reward Reward)
+	onExpire    func(reward Reward)
+	onError     func(err error)
+	traceLogger *log.Logger
+	wake        chan struct{}
+	next        time.Time // Expiry Run waits for, zero when there's none. Grant only wakes Run for earlier ones.
+	ttl         time.Duration
+	grantMu     sync.Mutex // Makes lookup and put of Grant atomic within the process.
+	nextMu      sync.Mutex
+}
+
+func (c *Client) NewRewardEngine(opt RewardEngineOptions) *RewardEngine {
+	store := opt.Store
+	if store == nil {
+		store = NewMemoryRewardStore()
+	}
+
+	ttl := opt.TTL
+	if ttl <= 0 {
+		ttl = c.voteValidity
+	}
+
+	var api TopGGClient = c
+	if opt.API != nil {
+		api = opt.API
+	}
+
+	return &RewardEngine{
+		client:      api,
+		store:       store,
+		scheduler:   c.scheduler,
+		onGrant:     opt.OnGrant,
+		onExpire:    opt.OnExpire,
+		onError:     opt.OnError,
+		traceLogger: c.traceLogger,
+		wake:        make(chan struct{}, 1),
+		ttl:         ttl,
+	}
+}
+
+func (e *RewardEngine) tracef(format string, v ...any) {
+	e.traceLogger.Printf("[REWARD ENGINE] "+format, v...)
+}
+
+func (e *RewardEngine) fail(err error) {
+	e.tracef("%v", err)
+	if e.onError != nil {
+		if err := safeCall("reward engine", "OnError", func() { e.onError(err) }); err != nil {
+			e.tracef("%v", err)
+		}
+	}
+}
+
+// Grants reward for vote delivered by webhook.
+func (e *RewardEngine) RecordVote(vote VoteCreatePayload) {
+	if err := e.Grant(context.Background(), vote.User.PlatformID, vote.VotedAt); err != nil {
+		e.fail(err)
+	}
+}
+
+// Grants (or extends) reward of given user, lasting TTL since grantedAt. Zero grantedAt means now.
+// Active reward lasting at least as long is kept as is, so older votes (e.g. redeliveries) can't shorten it.
+// Lookup and put aren't atomic across processes sharing the store.
+func (e *RewardEngine) Grant(ctx context.Context, userID Snowflake, grantedAt time.Time) error {
+	if grantedAt.IsZero() {
+		grantedAt = time.Now()
+	}
+
+	reward := Reward{
+		GrantedAt: grantedAt,
+		ExpiresAt: grantedAt.Add(e.ttl),
+		UserID:    userID,
+	}
+
+	extended, err := e.put(ctx, reward)
+	if err != nil || !extended {
+		return err
+	}
+
+	if e.onGrant != nil {
+		if err := safeCall("reward engine", "OnGrant", func() { e.onGrant(reward) }); err != nil {
+			e.fail(err)
+		}
+	}
+
+	e.schedule(reward.ExpiresAt)
+	return nil
+}
+
+// Stores reward unless active one lasts at least as long. Reports whether it was stored.
+func (e *RewardEngine) put(ctx context.Context, reward Reward) (bool, error) {
+	e.grantMu.Lock()
+	defer e.grantMu.Unlock()
+
+	current, err := e.store.GetReward(ctx, reward.UserID)
+	if err != nil {
+		return false, err
+	}
+
+	if current != nil && !current.ExpiresAt.Before(reward.ExpiresAt) {
+		e.tracef("Ignored vote of user %s cast at %s, active reward lasts longer", reward.UserID, reward.GrantedAt.Format(time.RFC3339))
+		return false, nil
+	}
+
+	return true, e.store.PutReward(ctx, reward)
+}
+
+// Wakes Run when reward expiring at given time is due before the one it waits for,
+// so granting doesn't make it list the whole store on every vote.
+func (e *RewardEngine) schedule(expiresAt time.Time) {
+	e.nextMu.Lock()
+	earlier := e.next.IsZero() || expiresAt.Before(e.next)
+	if earlier {
+		e.next = expiresAt
+	}
+	e.nextMu.Unlock()
+
+	if earlier {
+		select {
+		case e.wake <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// Fires expiry callbacks until ctx is done. Rewards that expired while engine wasn't running
+// (e.g. during restart) are expired right away.
+func (e *RewardEngine) Run(ctx context.Context) error {
+	for {
+		// Rewards granted while store is being listed wake Run again, as listing may have missed them.
+		e.nextMu.Lock()
+		e.next = time.Time{}
+		e.nextMu.Unlock()
+
+		next, err := e.expire(ctx)
+		if err != nil {
+			e.fail(err)
+			// Retries failed store a minute later.
+			next = time.Now().Add(time.Minute)
+		}
+
+		e.nextMu.Lock()
+		e.next = next
+		e.nextMu.Unlock()
+
+		var timer *time.Timer
+		var timerC <-chan time.Time
+		if !next.IsZero() {
+			timer = time.NewTimer(time.Until(next))
+			timerC = timer.C
+		}
+
+		select {
+		case <-ctx.Done():
+		case <-e.wake:
+		case <-timerC:
+		}
+
+		if timer != nil {
+			timer.Stop()
+		}
+
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+	}
+}
+
+// Expires due rewards and returns when the next one is due, zero when there's none.
+func (e *RewardEngine) expire(ctx context.Context) (time.Time, error) {
+	rewards, err := e.store.ListRewards(ctx)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	var next time.Time
+	now := time.Now()
+	for _, reward := range rewards {
+		if reward.ExpiresAt.After(now) {
+			if next.IsZero() || reward.ExpiresAt.Before(next) {
+				next = reward.ExpiresAt
+			}
+			continue
+		}
+
+		// Reward is deleted even when callback panics, otherwise it would be expired again on every wake up.
+		if e.onExpire != nil {
+			if err := safeCall("reward engine", "OnExpire", func() { e.onExpire(reward) }); err != nil {
+				e.fail(err)
+			}
+		}
+
+		if err := e.store.DeleteReward(ctx, reward); err != nil {
+			return time.Time{}, err
+		}
+	}
+
+	return next, nil
+}
+
+// Summary of a single reward reconciliation run.
+type RewardReconcileReport struct {
+	Fetched int // Votes returned by Top.gg API that still fall within TTL.
+	Granted int // Votes that had no reward (e.g. process crashed before handling them) and got one.
+}
+
+// Grants rewards for votes cast within TTL that never got one, e.g. because webhook was down
+// or process crashed mid-handling. Rewards that should have expired in the meantime are expired by Run.
+func (e *RewardEngine) Reconcile(ctx context.Context) (RewardReconcileReport, error) {
+	var report RewardReconcileReport
+
+	rewards, err := e.store.ListRewards(ctx)
+	if err != nil {
+		return report, err
+	}
+
+	granted := make(map[Snowflake]time.Time, len(rewards))
+	for _, reward := range rewards {
+		granted[reward.UserID] = reward.GrantedAt
+	}
+
+	now := time.Now()
+	since := now.Add(-e.ttl)
+	err = EachItem(ctx, VotePages(e.client, &since), func(vote Vote) error {
+		if !now.Before(vote.VotedAt.Add(e.ttl)) {
+			return nil
+		}
+
+		report.Fetched++
+		if grantedAt, ok := granted[vote.PlatformID]; ok && !grantedAt.Before(vote.VotedAt) {
+			return nil
+		}
+
+		if err := e.Grant(ctx, vote.PlatformID, vote.VotedAt); err != nil {
+			return err
+		}
+
+		granted[vote.PlatformID] = vote.VotedAt
+		report.Granted++
+		return nil
+	})
+
+	return report, err
+}
+
+// Reconciles rewards with Top.gg API every interval until ctx is done.
+// Optional onReport callback receives result of every run.
+func (e *RewardEngine) RunReconciler(ctx context.Context, interval time.Duration, onReport func(report RewardReconcileReport, err error)) {
+	reconcile := func(ctx context.Context) {
+		report, err := e.Reconcile(ctx)
+		if err != nil {
+			e.fail(err)
+		} else if report.Granted > 0 {
+			e.tracef("Reconciliation granted %d missed rewards", report.Granted)
+		}
+
+		if onReport != nil {
+			if err := safeCall("reward engine", "onReport", func() { onReport(report, err) }); err != nil {
+				e.fail(err)
+			}
+		}
+	}
+
+	reconcile(ctx)
+	_ = e.scheduler.Every(ctx, interval, reconcile)
+}
@@ -0,0 +1,184 @@
+package topgg
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// MemoryRewardStore counting full listings.
+type countingRewardStore struct {
+	*MemoryRewardStore
+	lists atomic.Int64
+}
+
+func (s *countingRewardStore) ListRewards(ctx context.Context) ([]Reward, error) {
+	s.lists.Add(1)
+	return s.MemoryRewardStore.ListRewards(ctx)
+}
+
+func TestRewardEngineGrant(t *testing.T) {
+	base := time.Now()
+	ttl := 12 * time.Hour
+
+	tests := []struct {
+		name        string
+		votes       []time.Duration // Vote times, relative to base.
+		wantExpires time.Duration   // Expiry of stored reward, relative to base.
+		wantGrants  int
+	}{
+		{name: "first vote", votes: []time.Duration{0}, wantExpires: ttl, wantGrants: 1},
+		{name: "newer vote extends reward", votes: []time.Duration{0, time.Hour}, wantExpires: time.Hour + ttl, wantGrants: 2},
+		{name: "older vote keeps later expiry", votes: []time.Duration{time.Hour, 0}, wantExpires: time.Hour + ttl, wantGrants: 1},
+		{name: "redelivery is ignored", votes: []time.Duration{0, 0}, wantExpires: ttl, wantGrants: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := NewMemoryRewardStore()
+			var grants int
+			engine := NewClient(ClientOptions{}).NewRewardEngine(RewardEngineOptions{
+				Store:   store,
+				TTL:     ttl,
+				OnGrant: func(reward Reward) { grants++ },
+			})
+
+			for _, offset := range tt.votes {
+				if err := engine.Grant(context.Background(), 1, base.Add(offset)); err != nil {
+					t.Fatalf("Grant: %v", err)
+				}
+			}
+
+			reward, err := store.GetReward(context.Background(), 1)
+			if err != nil || reward == nil {
+				t.Fatalf("GetReward = %v, %v; want stored reward", reward, err)
+			}
+
+			if want := base.Add(tt.wantExpires); !reward.ExpiresAt.Equal(want) {
+				t.Errorf("reward expires at %s, want %s", reward.ExpiresAt, want)
+			}
+
+			if grants != tt.wantGrants {
+				t.Errorf("OnGrant called %d times, want %d", grants, tt.wantGrants)
+			}
+		})
+	}
+}
+
+func TestRewardEngineExpires(t *testing.T) {
+	store := NewMemoryRewardStore()
+	expired := make(chan Reward, 10)
+	engine := NewClient(ClientOptions{}).NewRewardEngine(RewardEngineOptions{
+		Store:    store,
+		TTL:      50 * time.Millisecond,
+		OnExpire: func(reward Reward) { expired <- reward },
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go engine.Run(ctx)
+
+	if err := engine.Grant(ctx, 1, time.Time{}); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case reward := <-expired:
+		if reward.UserID != 1 {
+			t.Fatalf("expired reward of user %s, want 1", reward.UserID)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("reward didn't expire")
+	}
+
+	if reward, _ := store.GetReward(ctx, 1); reward != nil {
+		t.Fatalf("expired reward %+v is still stored", reward)
+	}
+}
+
+func TestRewardEngineRestart(t *testing.T) {
+	now := time.Now()
+	store := NewMemoryRewardStore()
+	// Left behind by previous process: one ran out while it was down, one is still active.
+	_ = store.PutReward(context.Background(), Reward{UserID: 1, GrantedAt: now.Add(-13 * time.Hour), ExpiresAt: now.Add(-time.Hour)})
+	_ = store.PutReward(context.Background(), Reward{UserID: 2, GrantedAt: now, ExpiresAt: now.Add(12 * time.Hour)})
+
+	var mu sync.Mutex
+	var expired []Snowflake
+	engine := NewClient(ClientOptions{}).NewRewardEngine(RewardEngineOptions{
+		Store: store,
+		OnExpire: func(reward Reward) {
+			mu.Lock()
+			expired = append(expired, reward.UserID)
+			mu.Unlock()
+		},
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	_ = engine.Run(ctx)
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(expired) != 1 || expired[0] != 1 {
+		t.Fatalf("expired rewards of users %v, want [1]", expired)
+	}
+
+	if reward, _ := store.GetReward(context.Background(), 2); reward == nil {
+		t.Fatal("active reward was dropped")
+	}
+}
+
+func TestRewardEngineGrantDoesNotScanStore(t *testing.T) {
+	store := &countingRewardStore{MemoryRewardStore: NewMemoryRewardStore()}
+	engine := NewClient(ClientOptions{}).NewRewardEngine(RewardEngineOptions{Store: store, TTL: time.Hour})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go engine.Run(ctx)
+
+	// Every later vote expires after the first one, which Run is already waiting for.
+	start := time.Now()
+	for i := 0; i < 100; i++ {
+		if err := engine.Grant(ctx, Snowflake(i+1), start.Add(time.Duration(i)*time.Millisecond)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	time.Sleep(50 * time.Millisecond)
+	if n := store.lists.Load(); n > 3 {
+		t.Fatalf("store was listed %d times for 100 grants", n)
+	}
+}
+
+func TestRewardEngineReconcile(t *testing.T) {
+	now := time.Now()
+	store := NewMemoryRewardStore()
+	api := &MockClient{GetVotesFunc: func(ctx context.Context, cursor string, startDate *time.Time) (*PaginatedVotes, error) {
+		return &PaginatedVotes{Votes: []Vote{
+			{PlatformID: 1, PartialVote: PartialVote{VotedAt: now.Add(-time.Hour)}},      // Already rewarded.
+			{PlatformID: 2, PartialVote: PartialVote{VotedAt: now.Add(-2 * time.Hour)}},  // Missed.
+			{PlatformID: 3, PartialVote: PartialVote{VotedAt: now.Add(-20 * time.Hour)}}, // Outside TTL.
+		}}, nil
+	}}
+
+	engine := NewClient(ClientOptions{}).NewRewardEngine(RewardEngineOptions{Store: store, API: api, TTL: 12 * time.Hour})
+	if err := engine.Grant(context.Background(), 1, now.Add(-time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+
+	report, err := engine.Reconcile(context.Background())
+	if err != nil {
+		t.Fatalf("Reconcile: %v", err)
+	}
+
+	if report.Fetched != 2 || report.Granted != 1 {
+		t.Fatalf("got report %+v, want 2 fetched and 1 granted", report)
+	}
+
+	if reward, _ := store.GetReward(context.Background(), 2); reward == nil {
+		t.Fatal("missed vote didn't get reward")
+	}
+}
@@ -0,0 +1,138 @@
+package topgg
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+)
+
+// Owns SDK components of an application (webhook handlers, metrics posters, background loops)
+// and tears them down in one place. Components are stopped in reverse order of registration,
+// so register them in dependency order, e.g. reward engine before the webhook feeding it.
+type Runner struct {
+	traceLogger *log.Logger
+	stages      []runnerStage
+	mu          sync.Mutex
+	stopped     bool
+}
+
+type runnerStage struct {
+	stop func(ctx context.Context) error
+	name string
+}
+
+// Returned by Runner.Shutdown when some components failed to stop, e.g. because ctx expired first.
+// Unwraps to error of the first failed component.
+type ShutdownError struct {
+	Components []string // Names of failed components, in order they were stopped.
+	Errors     []error
+}
+
+func (e *ShutdownError) Error() string {
+	failures := make([]string, len(e.Errors))
+	for i, err := range e.Errors {
+		failures[i] = e.Components[i] + ": " + err.Error()
+	}
+
+	return fmt.Sprintf("failed to stop %d components: %s", len(e.Errors), strings.Join(failures, "; "))
+}
+
+func (e *ShutdownError) Unwrap() error {
+	return e.Errors[0]
+}
+
+func (c *Client) NewRunner() *Runner {
+	return &Runner{traceLogger: c.traceLogger}
+}
+
+func (r *Runner) tracef(format string, v ...any) {
+	r.traceLogger.Printf("[RUNNER] "+format, v...)
+}
+
+// Registers component stopped by given func, e.g. Webhook.Shutdown or MetricsPoster.Close.
+func (r *Runner) OnShutdown(name string, stop func(ctx context.Context) error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.stopped {
+		r.tracef("Runner is shut down, %s is not registered", name)
+		return
+	}
+
+	r.stages = append(r.stages, runnerStage{stop: stop, name: name})
+}
+
+// Starts background loop, e.g. RewardEngine.Run. On shutdown its context is cancelled and Runner waits for it to return.
+// Loop returning early is reported by trace logger and its error is returned by Shutdown.
+func (r *Runner) Go(name string, run func(ctx context.Context) error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.stopped {
+		r.tracef("Runner is shut down, %s is not started", name)
+		return
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		err := run(ctx)
+		if err != nil && ctx.Err() == nil {
+			r.tracef("%s stopped early: %v", name, err)
+		}
+		done <- err
+	}()
+
+	r.stages = append(r.stages, runnerStage{
+		stop: func(stopCtx context.Context) error {
+			cancel()
+			select {
+			case err := <-done:
+				if errors.Is(err, context.Canceled) {
+					return nil
+				}
+				return err
+			case <-stopCtx.Done():
+				return stopCtx.Err()
+			}
+		},
+		name: name,
+	})
+}
+
+// Stops all components in reverse order of registration. Every component is stopped even when
+// previous ones fail, but all share ctx deadline. Subsequent calls do nothing.
+func (r *Runner) Shutdown(ctx context.Context) error {
+	r.mu.Lock()
+	if r.stopped {
+		r.mu.Unlock()
+		return nil
+	}
+	r.stopped = true
+	stages := r.stages
+	r.stages = nil
+	r.mu.Unlock()
+
+	var shutdownErr *ShutdownError
+	for i := len(stages) - 1; i >= 0; i-- {
+		stage := stages[i]
+		r.tracef("Stopping %s", stage.name)
+		if err := stage.stop(ctx); err != nil {
+			r.tracef("Failed to stop %s: %v", stage.name, err)
+			if shutdownErr == nil {
+				shutdownErr = &ShutdownError{}
+			}
+			shutdownErr.Components = append(shutdownErr.Components, stage.name)
+			shutdownErr.Errors = append(shutdownErr.Errors, err)
+		}
+	}
+
+	if shutdownErr != nil {
+		return shutdownErr
+	}
+
+	return nil
+}
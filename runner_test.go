@@ -0,0 +1,137 @@
+package topgg
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestRunnerShutdownOrder(t *testing.T) {
+	runner := NewClient(ClientOptions{}).NewRunner()
+
+	var mu sync.Mutex
+	var order []string
+	stopped := func(name string) {
+		mu.Lock()
+		order = append(order, name)
+		mu.Unlock()
+	}
+
+	runner.OnShutdown("engine", func(ctx context.Context) error { stopped("engine"); return nil })
+	runner.Go("loop", func(ctx context.Context) error {
+		<-ctx.Done()
+		stopped("loop")
+		return ctx.Err()
+	})
+	runner.OnShutdown("webhook", func(ctx context.Context) error { stopped("webhook"); return nil })
+
+	if err := runner.Shutdown(context.Background()); err != nil {
+		t.Fatalf("Shutdown: %v", err)
+	}
+
+	if got := strings.Join(order, ","); got != "webhook,loop,engine" {
+		t.Fatalf("components stopped in order %s, want webhook,loop,engine", got)
+	}
+
+	// Later calls and registrations do nothing.
+	runner.OnShutdown("late", func(ctx context.Context) error { stopped("late"); return nil })
+	if err := runner.Shutdown(context.Background()); err != nil || len(order) != 3 {
+		t.Fatalf("second Shutdown = %v, stopped %v", err, order)
+	}
+}
+
+func TestRunnerShutdownErrors(t *testing.T) {
+	errFirst := errors.New("first failure")
+	errSecond := errors.New("second failure")
+	runner := NewClient(ClientOptions{}).NewRunner()
+
+	ran := false
+	runner.OnShutdown("a", func(ctx context.Context) error { return errSecond })
+	runner.OnShutdown("b", func(ctx context.Context) error { ran = true; return nil })
+	runner.OnShutdown("c", func(ctx context.Context) error { return errFirst })
+
+	err := runner.Shutdown(context.Background())
+	var shutdownErr *ShutdownError
+	if !errors.As(err, &shutdownErr) {
+		t.Fatalf("got %v, want ShutdownError", err)
+	}
+
+	if !ran {
+		t.Error("component after failed one wasn't stopped")
+	}
+
+	if got := strings.Join(shutdownErr.Components, ","); got != "c,a" || len(shutdownErr.Errors) != 2 {
+		t.Fatalf("failed components %s with %d errors, want c,a", got, len(shutdownErr.Errors))
+	}
+
+	if !errors.Is(err, errFirst) {
+		t.Errorf("error doesn't unwrap to first failure: %v", err)
+	}
+
+	if msg := err.Error(); !strings.Contains(msg, "c: first failure") || !strings.Contains(msg, "a: second failure") {
+		t.Errorf("error %q doesn't name both failures", msg)
+	}
+}
+
+func TestRunnerGo(t *testing.T) {
+	errLoop := errors.New("loop failed")
+
+	tests := []struct {
+		run     func(ctx context.Context) error
+		wantErr error
+		name    string
+	}{
+		{
+			name: "cancelled loop",
+			run: func(ctx context.Context) error {
+				<-ctx.Done()
+				return ctx.Err()
+			},
+		},
+		{
+			name: "loop returning nil",
+			run: func(ctx context.Context) error {
+				<-ctx.Done()
+				return nil
+			},
+		},
+		{
+			name:    "loop that stopped early",
+			run:     func(ctx context.Context) error { return errLoop },
+			wantErr: errLoop,
+		},
+		{
+			name: "loop ignoring cancellation",
+			run: func(ctx context.Context) error {
+				time.Sleep(time.Second)
+				return nil
+			},
+			wantErr: context.DeadlineExceeded,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			runner := NewClient(ClientOptions{}).NewRunner()
+			runner.Go("loop", tt.run)
+
+			ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+			defer cancel()
+
+			err := runner.Shutdown(ctx)
+			if tt.wantErr == nil {
+				if err != nil {
+					t.Fatalf("Shutdown: %v", err)
+				}
+				return
+			}
+
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("got %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,31 @@
+package topgg
+
+import (
+	"fmt"
+	"runtime/debug"
+)
+
+// Reported to error handlers when user callback panics. SDK recovers such panics,
+// so one buggy callback can't take down unrelated subsystems.
+type PanicError struct {
+	Value     any    // Value passed to panic.
+	Subsystem string // Subsystem that ran the callback, e.g. "reward engine".
+	Callback  string // Callback that panicked, e.g. "OnExpire".
+	Stack     []byte // Stack trace of the panicking goroutine.
+}
+
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("%s: %s callback panicked: %v", e.Subsystem, e.Callback, e.Value)
+}
+
+// Runs user callback, returning *PanicError if it panics.
+func safeCall(subsystem, callback string, fn func()) (err error) {
+	defer func() {
+		if rec := recover(); rec != nil {
+			err = &PanicError{Value: rec, Subsystem: subsystem, Callback: callback, Stack: debug.Stack()}
+		}
+	}()
+
+	fn()
+	return nil
+}
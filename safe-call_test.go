@@ -0,0 +1,155 @@
+package topgg
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestSafeCall(t *testing.T) {
+	err := safeCall("test", "OnTest", func() { panic("boom") })
+
+	var panicErr *PanicError
+	if !errors.As(err, &panicErr) {
+		t.Fatalf("got %v, want *PanicError", err)
+	}
+
+	if panicErr.Subsystem != "test" || panicErr.Callback != "OnTest" || panicErr.Value != "boom" || len(panicErr.Stack) == 0 {
+		t.Fatalf("got %+v", panicErr)
+	}
+
+	if err := safeCall("test", "OnTest", func() {}); err != nil {
+		t.Fatalf("got %v for callback that returned", err)
+	}
+}
+
+// Every user callback panics; the SDK must recover and keep serving.
+func TestCallbackPanicsAreRecovered(t *testing.T) {
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"created_at":"2026-01-01T00:00:00Z","expires_at":"2026-01-01T12:00:00Z","weight":1}`))
+	})
+	unavailable := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	})
+
+	tests := []struct {
+		run  func(t *testing.T)
+		name string
+	}{
+		{
+			name: "ClientHooks",
+			run: func(t *testing.T) {
+				hooks := ClientHooks{
+					OnRequest:  func(method, path string, attempt int) { panic("boom") },
+					OnResponse: func(method, path string, status, attempt int, duration time.Duration) { panic("boom") },
+					OnRetry:    func(method, path string, attempt int, err error) { panic("boom") },
+				}
+
+				client := newTestClient(t, ok, ClientOptions{Hooks: hooks})
+				if _, err := client.GetVote(context.Background(), 1, PlatformDiscord); err != nil {
+					t.Fatalf("GetVote: %v", err)
+				}
+			},
+		},
+		{
+			name: "OnUnavailable",
+			run: func(t *testing.T) {
+				client := newTestClient(t, unavailable, ClientOptions{
+					OnUnavailable: func(err error) { panic("boom") },
+				})
+
+				if _, err := client.GetVote(context.Background(), 1, PlatformDiscord, NoRetry()); !IsUnavailable(err) {
+					t.Fatalf("got %v, want unavailable error", err)
+				}
+			},
+		},
+		{
+			name: "MetricsGuard",
+			run: func(t *testing.T) {
+				client := newTestClient(t, ok, ClientOptions{
+					MetricsGuard: func(previous, next MetricsPayload) error { panic("boom") },
+				})
+
+				if err := client.PostMetrics(context.Background(), MetricsPayload{ServerCount: 1}); err != nil {
+					t.Fatalf("first PostMetrics: %v", err)
+				}
+
+				var panicErr *PanicError
+				if err := client.PostMetrics(context.Background(), MetricsPayload{ServerCount: 2}); !errors.As(err, &panicErr) {
+					t.Fatalf("got %v, want *PanicError", err)
+				}
+			},
+		},
+		{
+			name: "OnLongWait",
+			run: func(t *testing.T) {
+				limiter := NewRateLimiter(RateLimiterOptions{
+					Limit:             20,
+					LongWaitThreshold: time.Nanosecond,
+					OnLongWait:        func(remaining time.Duration) { panic("boom") },
+				})
+
+				for i := 0; i < 21; i++ {
+					if err := limiter.Wait(context.Background()); err != nil {
+						t.Fatalf("Wait: %v", err)
+					}
+				}
+			},
+		},
+		{
+			name: "OnRejected",
+			run: func(t *testing.T) {
+				var reported error
+				w := NewClient(ClientOptions{}).NewWebhookHandler(WebhookOptions{
+					Secret:     testSecret,
+					OnRejected: func(reason RejectionReason, r *http.Request) { panic("boom") },
+					OnError:    func(err error) { reported = err },
+				})
+
+				if got := deliver(w, signedRequest(t, "wrong-secret", voteBody(1), time.Now())); got != http.StatusUnauthorized {
+					t.Fatalf("got %d, want 401", got)
+				}
+
+				var panicErr *PanicError
+				if !errors.As(reported, &panicErr) || panicErr.Callback != "OnRejected" {
+					t.Fatalf("OnError got %v, want OnRejected *PanicError", reported)
+				}
+			},
+		},
+		{
+			name: "VoteChecker onReport",
+			run: func(t *testing.T) {
+				ctx, cancel := context.WithCancel(context.Background())
+				cancel()
+
+				checker := NewClient(ClientOptions{}).NewVoteChecker(VoteCheckerOptions{API: &MockClient{}})
+				checker.RunReconciler(ctx, time.Hour, func(report ReconcileReport, err error) { panic("boom") })
+			},
+		},
+		{
+			name: "RewardEngine onReport",
+			run: func(t *testing.T) {
+				ctx, cancel := context.WithCancel(context.Background())
+				cancel()
+
+				var reported error
+				engine := NewClient(ClientOptions{}).NewRewardEngine(RewardEngineOptions{
+					API:     &MockClient{},
+					OnError: func(err error) { reported = err },
+				})
+				engine.RunReconciler(ctx, time.Hour, func(report RewardReconcileReport, err error) { panic("boom") })
+
+				var panicErr *PanicError
+				if !errors.As(reported, &panicErr) {
+					t.Fatalf("OnError got %v, want *PanicError", reported)
+				}
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, tt.run)
+	}
+}
@@ -0,0 +1,49 @@
+package topgg
+
+import (
+	"context"
+	"time"
+)
+
+// Runs periodic background work of SDK components (reconcilers, MetricsPoster, ProjectWatcher, VoteRateMonitor).
+// Implement it to hand that work over to an existing job framework, see ClientOptions.Scheduler.
+type Scheduler interface {
+	// Calls job every interval until ctx is done, then returns ctx error. First call happens
+	// one interval after start and calls must not overlap.
+	Every(ctx context.Context, interval time.Duration, job func(ctx context.Context)) error
+
+	// Calls job once after delay and returns when it did. When ctx is done first, job isn't
+	// called and ctx error is returned. Used for delays that change between runs, e.g. adaptive
+	// interval of MetricsPoster.
+	After(ctx context.Context, delay time.Duration, job func(ctx context.Context)) error
+}
+
+// Default Scheduler, backed by time.Ticker and time.Timer.
+type TickerScheduler struct{}
+
+func (TickerScheduler) Every(ctx context.Context, interval time.Duration, job func(ctx context.Context)) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+			job(ctx)
+		}
+	}
+}
+
+func (TickerScheduler) After(ctx context.Context, delay time.Duration, job func(ctx context.Context)) error {
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		job(ctx)
+		return nil
+	}
+}
@@ -0,0 +1,86 @@
+package topgg
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+type flightCall struct {
+	err     error
+	done    chan struct{}
+	cancel  context.CancelFunc
+	res     []byte
+	waiters int
+}
+
+// Collapses identical concurrent calls into one, sharing its result.
+type flightGroup struct {
+	calls map[string]*flightCall
+	mu    sync.Mutex
+}
+
+// Runs fn once per key at a time. Call runs on context detached from its callers, so one of them
+// giving up doesn't fail the others. Callers stop waiting when their ctx is done and call is cancelled
+// once all of them did.
+func (g *flightGroup) do(ctx context.Context, key string, fn func(ctx context.Context) ([]byte, error)) ([]byte, error) {
+	g.mu.Lock()
+	if g.calls == nil {
+		g.calls = make(map[string]*flightCall)
+	}
+
+	call, ok := g.calls[key]
+	if !ok {
+		callCtx, cancel := context.WithCancel(detachedContext{parent: ctx})
+		call = &flightCall{done: make(chan struct{}), cancel: cancel}
+		g.calls[key] = call
+		go g.run(callCtx, key, call, fn)
+	}
+	call.waiters++
+	g.mu.Unlock()
+
+	select {
+	case <-call.done:
+		return call.res, call.err
+	case <-ctx.Done():
+		g.mu.Lock()
+		call.waiters--
+		if call.waiters == 0 {
+			call.cancel()
+			// Callers coming later start a new call instead of joining cancelled one.
+			g.forget(key, call)
+		}
+		g.mu.Unlock()
+
+		return nil, ctx.Err()
+	}
+}
+
+func (g *flightGroup) run(ctx context.Context, key string, call *flightCall, fn func(ctx context.Context) ([]byte, error)) {
+	res, err := fn(ctx)
+
+	g.mu.Lock()
+	call.res, call.err = res, err
+	g.forget(key, call)
+	g.mu.Unlock()
+
+	call.cancel()
+	close(call.done)
+}
+
+// Must be called with g.mu held.
+func (g *flightGroup) forget(key string, call *flightCall) {
+	if g.calls[key] == call {
+		delete(g.calls, key)
+	}
+}
+
+// Keeps values of parent, but not its deadline and cancellation.
+type detachedContext struct {
+	parent context.Context
+}
+
+func (detachedContext) Deadline() (time.Time, bool) { return time.Time{}, false }
+func (detachedContext) Done() <-chan struct{}       { return nil }
+func (detachedContext) Err() error                  { return nil }
+func (c detachedContext) Value(key any) any         { return c.parent.Value(key) }
@@ -0,0 +1,77 @@
+package topgg
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+const voteResponse = `{"created_at":"2026-01-01T00:00:00Z","expires_at":"2026-01-01T12:00:00Z","weight":1}`
+
+func TestCoalescedCallerCancels(t *testing.T) {
+	started := make(chan struct{}, 1)
+	release := make(chan struct{})
+	var requests atomic.Int32
+
+	client := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		started <- struct{}{}
+		<-release
+		_, _ = w.Write([]byte(voteResponse))
+	}), ClientOptions{})
+
+	first, cancelFirst := context.WithCancel(context.Background())
+	firstErr := make(chan error, 1)
+	go func() {
+		_, err := client.GetVote(first, 1, PlatformDiscord)
+		firstErr <- err
+	}()
+	<-started
+
+	secondErr := make(chan error, 1)
+	go func() {
+		_, err := client.GetVote(context.Background(), 1, PlatformDiscord)
+		secondErr <- err
+	}()
+
+	// Give second caller time to join the call in flight.
+	time.Sleep(50 * time.Millisecond)
+	cancelFirst()
+	if err := <-firstErr; !errors.Is(err, context.Canceled) {
+		t.Fatalf("got %v from cancelled caller, want context.Canceled", err)
+	}
+
+	close(release)
+	if err := <-secondErr; err != nil {
+		t.Fatalf("caller with valid ctx failed: %v", err)
+	}
+
+	if n := requests.Load(); n != 1 {
+		t.Fatalf("got %d requests, want 1 shared", n)
+	}
+}
+
+func TestCoalescedCallCancelledWithoutWaiters(t *testing.T) {
+	aborted := make(chan struct{})
+	client := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+		close(aborted)
+	}), ClientOptions{})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	_, err := client.GetVote(ctx, 1, PlatformDiscord)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("got %v, want context.DeadlineExceeded", err)
+	}
+
+	select {
+	case <-aborted:
+	case <-time.After(5 * time.Second):
+		t.Fatal("shared request kept running after its only caller left")
+	}
+}
@@ -0,0 +1,217 @@
+package topgg
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// SQL flavour used by SQLVoteStore. Each statement contains %s for the table name,
+// parameters follow the documented order.
+type SQLDialect struct {
+	CreateTable string // No parameters.
+	Select      string // Parameters: user_id.
+	Upsert      string // Parameters: user_id, voted_at, expires_at, weight.
+	Prune       string // Parameters: expires_at.
+}
+
+// Reports first statement that's missing or lacks placeholder for the table name.
+func (d SQLDialect) validate() error {
+	statements := []struct {
+		name      string
+		statement string
+	}{
+		{"CreateTable", d.CreateTable},
+		{"Select", d.Select},
+		{"Upsert", d.Upsert},
+		{"Prune", d.Prune},
+	}
+
+	for _, s := range statements {
+		if !strings.Contains(s.statement, "%s") {
+			return fmt.Errorf("topgg: SQLDialect.%s must be set and contain %%s for the table name, use one of predefined dialects (e.g. SQLDialectPostgres)", s.name)
+		}
+	}
+
+	return nil
+}
+
+var (
+	SQLDialectPostgres = SQLDialect{
+		CreateTable: "CREATE TABLE IF NOT EXISTS %s (user_id BIGINT PRIMARY KEY, voted_at BIGINT NOT NULL, expires_at BIGINT NOT NULL, weight INTEGER NOT NULL)",
+		Select:      "SELECT voted_at, expires_at, weight FROM %s WHERE user_id = $1",
+		Upsert:      "INSERT INTO %s (user_id, voted_at, expires_at, weight) VALUES ($1, $2, $3, $4) ON CONFLICT (user_id) DO UPDATE SET voted_at = EXCLUDED.voted_at, expires_at = EXCLUDED.expires_at, weight = EXCLUDED.weight",
+		Prune:       "DELETE FROM %s WHERE expires_at < $1",
+	}
+
+	// Also used for MariaDB.
+	SQLDialectMySQL = SQLDialect{
+		CreateTable: "CREATE TABLE IF NOT EXISTS %s (user_id BIGINT UNSIGNED PRIMARY KEY, voted_at BIGINT NOT NULL, expires_at BIGINT NOT NULL, weight INT NOT NULL)",
+		Select:      "SELECT voted_at, expires_at, weight FROM %s WHERE user_id = ?",
+		Upsert:      "INSERT INTO %s (user_id, voted_at, expires_at, weight) VALUES (?, ?, ?, ?) ON DUPLICATE KEY UPDATE voted_at = VALUES(voted_at), expires_at = VALUES(expires_at), weight = VALUES(weight)",
+		Prune:       "DELETE FROM %s WHERE expires_at < ?",
+	}
+
+	// Requires SQLite 3.24 or newer.
+	SQLDialectSQLite = SQLDialect{
+		CreateTable: "CREATE TABLE IF NOT EXISTS %s (user_id INTEGER PRIMARY KEY, voted_at INTEGER NOT NULL, expires_at INTEGER NOT NULL, weight INTEGER NOT NULL)",
+		Select:      "SELECT voted_at, expires_at, weight FROM %s WHERE user_id = ?",
+		Upsert:      "INSERT INTO %s (user_id, voted_at, expires_at, weight) VALUES (?, ?, ?, ?) ON CONFLICT (user_id) DO UPDATE SET voted_at = excluded.voted_at, expires_at = excluded.expires_at, weight = excluded.weight",
+		Prune:       "DELETE FROM %s WHERE expires_at < ?",
+	}
+)
+
+type SQLVoteStoreOptions struct {
+	DB            *sql.DB       // Connection pool with driver of user's choice, SDK doesn't import any.
+	TraceLogger   *log.Logger   // Receives failures of automatic pruning.
+	Dialect       SQLDialect    // Required, e.g. SQLDialectPostgres.
+	Table         string        // Defaults to "topgg_votes". Inserted into statements as is, so it must be trusted.
+	Retention     time.Duration // How long expired votes are kept, e.g. for own statistics. Defaults to 0, pruning them once they expire.
+	PruneInterval time.Duration // Minimal delay between prunes run in the background by PutVote, defaults to 1 hour. Negative disables them.
+}
+
+// Default SQLVoteStoreOptions.PruneInterval.
+const defaultSQLPruneInterval = time.Hour
+
+// VoteStore on top of database/sql. Times are stored as Unix milliseconds, so they don't depend on driver time handling.
+type SQLVoteStore struct {
+	db            *sql.DB
+	traceLogger   *log.Logger
+	get           string
+	put           string
+	pruneBy       string
+	retention     time.Duration
+	pruneInterval time.Duration
+	lastPrune     atomic.Int64 // Unix nanoseconds of last automatic prune.
+}
+
+var (
+	_ VoteStore      = (*SQLVoteStore)(nil)
+	_ StaleVoteStore = (*SQLVoteStore)(nil)
+)
+
+// Creates store, creating its table when it doesn't exist yet.
+func NewSQLVoteStore(ctx context.Context, opt SQLVoteStoreOptions) (*SQLVoteStore, error) {
+	if opt.DB == nil {
+		return nil, errors.New("topgg: SQLVoteStoreOptions.DB is required")
+	}
+
+	if err := opt.Dialect.validate(); err != nil {
+		return nil, err
+	}
+
+	table := opt.Table
+	if table == "" {
+		table = "topgg_votes"
+	}
+
+	if _, err := opt.DB.ExecContext(ctx, fmt.Sprintf(opt.Dialect.CreateTable, table)); err != nil {
+		return nil, fmt.Errorf("failed to create votes table: %w", err)
+	}
+
+	if opt.TraceLogger == nil {
+		opt.TraceLogger = log.New(io.Discard, "", 0)
+	}
+
+	pruneInterval := opt.PruneInterval
+	if pruneInterval == 0 {
+		pruneInterval = defaultSQLPruneInterval
+	}
+
+	retention := opt.Retention
+	if retention < 0 {
+		retention = 0
+	}
+
+	return &SQLVoteStore{
+		db:            opt.DB,
+		traceLogger:   opt.TraceLogger,
+		get:           fmt.Sprintf(opt.Dialect.Select, table),
+		put:           fmt.Sprintf(opt.Dialect.Upsert, table),
+		pruneBy:       fmt.Sprintf(opt.Dialect.Prune, table),
+		retention:     retention,
+		pruneInterval: pruneInterval,
+	}, nil
+}
+
+func (s *SQLVoteStore) GetVote(ctx context.Context, userID Snowflake) (*PartialVote, error) {
+	vote, err := s.GetLastVote(ctx, userID)
+	if err != nil || vote == nil || time.Now().After(vote.ExpiresAt) {
+		return nil, err
+	}
+
+	return vote, nil
+}
+
+// Like GetVote, but also returns expired votes that weren't pruned yet, see SQLVoteStoreOptions.Retention.
+func (s *SQLVoteStore) GetLastVote(ctx context.Context, userID Snowflake) (*PartialVote, error) {
+	var votedAt, expiresAt int64
+	var weight int
+	err := s.db.QueryRowContext(ctx, s.get, int64(userID)).Scan(&votedAt, &expiresAt, &weight)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &PartialVote{
+		VotedAt:   time.UnixMilli(votedAt),
+		ExpiresAt: time.UnixMilli(expiresAt),
+		Weight:    weight,
+	}, nil
+}
+
+func (s *SQLVoteStore) tracef(format string, v ...any) {
+	s.traceLogger.Printf("[SQL VOTE STORE] "+format, v...)
+}
+
+func (s *SQLVoteStore) PutVote(ctx context.Context, userID Snowflake, vote PartialVote) error {
+	if _, err := s.db.ExecContext(ctx, s.put, int64(userID), vote.VotedAt.UnixMilli(), vote.ExpiresAt.UnixMilli(), vote.Weight); err != nil {
+		return err
+	}
+
+	s.autoPrune()
+	return nil
+}
+
+// Prunes votes past retention in the background, at most once per prune interval.
+func (s *SQLVoteStore) autoPrune() {
+	if s.pruneInterval < 0 {
+		return
+	}
+
+	now := time.Now().UnixNano()
+	last := s.lastPrune.Load()
+	if now-last < int64(s.pruneInterval) || !s.lastPrune.CompareAndSwap(last, now) {
+		return
+	}
+
+	go func() {
+		// Detached from PutVote, so its caller isn't slowed down or cancelling the prune.
+		ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+		defer cancel()
+
+		if _, err := s.Prune(ctx, time.Now().Add(-s.retention)); err != nil {
+			s.tracef("Failed to prune expired votes: %v", err)
+		}
+	}()
+}
+
+// Deletes votes that expired before given time and returns how many were deleted.
+// PutVote already runs it in the background once per SQLVoteStoreOptions.PruneInterval, keeping votes within Retention.
+func (s *SQLVoteStore) Prune(ctx context.Context, before time.Time) (int64, error) {
+	res, err := s.db.ExecContext(ctx, s.pruneBy, before.UnixMilli())
+	if err != nil {
+		return 0, err
+	}
+
+	return res.RowsAffected()
+}
@@ -0,0 +1,151 @@
+package topgg
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+var errNoDatabase = errors.New("no database in tests")
+
+// Connector failing every connection, so store construction stops at its first statement.
+type failingConnector struct{}
+
+func (failingConnector) Connect(ctx context.Context) (driver.Conn, error) { return nil, errNoDatabase }
+func (failingConnector) Driver() driver.Driver                            { return nil }
+
+func TestNewSQLVoteStoreDialect(t *testing.T) {
+	partial := SQLDialectPostgres
+	partial.Prune = ""
+
+	tests := []struct {
+		wantErr string // Empty when dialect should be accepted.
+		name    string
+		dialect SQLDialect
+	}{
+		{name: "zero value", dialect: SQLDialect{}, wantErr: "SQLDialect.CreateTable"},
+		{name: "missing statement", dialect: partial, wantErr: "SQLDialect.Prune"},
+		{name: "no table placeholder", dialect: SQLDialect{CreateTable: "CREATE TABLE votes ()"}, wantErr: "SQLDialect.CreateTable"},
+		{name: "postgres", dialect: SQLDialectPostgres},
+		{name: "mysql", dialect: SQLDialectMySQL},
+		{name: "sqlite", dialect: SQLDialectSQLite},
+	}
+
+	db := sql.OpenDB(failingConnector{})
+	defer db.Close()
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewSQLVoteStore(context.Background(), SQLVoteStoreOptions{DB: db, Dialect: tt.dialect})
+			if tt.wantErr == "" {
+				if !errors.Is(err, errNoDatabase) {
+					t.Fatalf("got %v, want dialect to be accepted", err)
+				}
+				return
+			}
+
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("got %v, want error about %s", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+// Statement executed through recordingConnector.
+type execCall struct {
+	query string
+	args  []driver.NamedValue
+}
+
+// Connector accepting every statement, sending each one to execs.
+type recordingConnector struct {
+	execs chan execCall
+}
+
+func (c recordingConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	return recordingConn(c), nil
+}
+func (c recordingConnector) Driver() driver.Driver { return nil }
+
+type recordingConn struct {
+	execs chan execCall
+}
+
+func (c recordingConn) Prepare(query string) (driver.Stmt, error) { return nil, errNoDatabase }
+func (c recordingConn) Close() error                              { return nil }
+func (c recordingConn) Begin() (driver.Tx, error)                 { return nil, errNoDatabase }
+
+func (c recordingConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	c.execs <- execCall{query: query, args: args}
+	return driver.RowsAffected(1), nil
+}
+
+func TestSQLVoteStoreAutoPrune(t *testing.T) {
+	tests := []struct {
+		name          string
+		retention     time.Duration
+		pruneInterval time.Duration
+		wantPrunes    int // Over two puts in quick succession.
+	}{
+		{name: "prunes once per interval", retention: 24 * time.Hour, wantPrunes: 1},
+		{name: "prunes expired votes by default", wantPrunes: 1},
+		{name: "short interval", pruneInterval: time.Nanosecond, wantPrunes: 2},
+		{name: "disabled", pruneInterval: -1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			execs := make(chan execCall, 10)
+			db := sql.OpenDB(recordingConnector{execs: execs})
+			defer db.Close()
+
+			store, err := NewSQLVoteStore(context.Background(), SQLVoteStoreOptions{
+				DB:            db,
+				Dialect:       SQLDialectPostgres,
+				Retention:     tt.retention,
+				PruneInterval: tt.pruneInterval,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			<-execs // CREATE TABLE.
+
+			for i := 0; i < 2; i++ {
+				if tt.pruneInterval == time.Nanosecond {
+					time.Sleep(time.Millisecond)
+				}
+
+				if err := store.PutVote(context.Background(), 1, PartialVote{ExpiresAt: time.Now().Add(time.Hour)}); err != nil {
+					t.Fatalf("PutVote: %v", err)
+				}
+			}
+
+			prunes := 0
+			timeout := time.After(200 * time.Millisecond)
+			for done := false; !done; {
+				select {
+				case call := <-execs:
+					if !strings.HasPrefix(call.query, "DELETE") {
+						continue
+					}
+
+					prunes++
+					cutoff := time.UnixMilli(call.args[0].Value.(int64))
+					if want := time.Now().Add(-tt.retention); cutoff.After(want) || cutoff.Before(want.Add(-time.Minute)) {
+						t.Errorf("pruned votes expired before %s, want about %s", cutoff, want)
+					}
+				case <-timeout:
+					done = true
+				}
+			}
+
+			if prunes != tt.wantPrunes {
+				t.Fatalf("got %d prunes, want %d", prunes, tt.wantPrunes)
+			}
+		})
+	}
+}
@@ -0,0 +1,124 @@
+package topgg
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+var jsonUnmarshalerType = reflect.TypeOf((*json.Unmarshaler)(nil)).Elem()
+
+// Makes decoding of the response fail with ErrSchemaMismatch when it has fields SDK doesn't know about,
+// or lacks ones it expects. Meant for CI of libraries built on top of the SDK, to notice Top.gg schema changes early.
+func WithStrictDecoding() RequestOption {
+	return func(cfg *requestConfig) {
+		cfg.strict = true
+	}
+}
+
+// Decodes JSON into v with given codec. Unknown fields are ignored unless strict is set, in which case
+// encoding/json is used and both unknown fields and missing ones (all without omitempty, except pointers)
+// are reported as ErrSchemaMismatch.
+func decodeJSON(codec Codec, data []byte, v any, strict bool) error {
+	if !strict {
+		return codec.Unmarshal(data, v)
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(v); err != nil {
+		var typeErr *json.UnmarshalTypeError
+		if strings.HasPrefix(err.Error(), "json: unknown field") || errors.As(err, &typeErr) {
+			return fmt.Errorf("%w: %v", ErrSchemaMismatch, err)
+		}
+
+		return err
+	}
+
+	if missing := missingFields(reflect.TypeOf(v), data, ""); len(missing) > 0 {
+		return fmt.Errorf("%w: missing fields %s", ErrSchemaMismatch, strings.Join(missing, ", "))
+	}
+
+	return nil
+}
+
+// Returns paths of required fields of t absent in data.
+func missingFields(t reflect.Type, data []byte, path string) []string {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	switch t.Kind() {
+	case reflect.Struct:
+		if reflect.PointerTo(t).Implements(jsonUnmarshalerType) {
+			return nil
+		}
+
+		var object map[string]json.RawMessage
+		if err := json.Unmarshal(data, &object); err != nil || object == nil {
+			return nil
+		}
+
+		return missingStructFields(t, object, path)
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return nil
+		}
+
+		var items []json.RawMessage
+		if err := json.Unmarshal(data, &items); err != nil {
+			return nil
+		}
+
+		var missing []string
+		for i, item := range items {
+			missing = append(missing, missingFields(t.Elem(), item, fmt.Sprintf("%s[%d]", path, i))...)
+		}
+
+		return missing
+	default:
+		return nil
+	}
+}
+
+func missingStructFields(t reflect.Type, object map[string]json.RawMessage, path string) []string {
+	var missing []string
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag := field.Tag.Get("json")
+		if !field.IsExported() || tag == "-" {
+			continue
+		}
+
+		name, flags, _ := strings.Cut(tag, ",")
+		if field.Anonymous && name == "" && field.Type.Kind() == reflect.Struct {
+			missing = append(missing, missingStructFields(field.Type, object, path)...)
+			continue
+		}
+
+		if name == "" {
+			name = field.Name
+		}
+
+		fieldPath := name
+		if path != "" {
+			fieldPath = path + "." + name
+		}
+
+		raw, ok := object[name]
+		if !ok {
+			if !strings.Contains(flags, "omitempty") && field.Type.Kind() != reflect.Pointer {
+				missing = append(missing, fieldPath)
+			}
+
+			continue
+		}
+
+		missing = append(missing, missingFields(field.Type, raw, fieldPath)...)
+	}
+
+	return missing
+}
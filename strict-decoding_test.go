@@ -0,0 +1,127 @@
+package topgg
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+type strictItem struct {
+	Name string `json:"name"`
+}
+
+type strictSample struct {
+	Nested *strictItem  `json:"nested"`
+	Name   string       `json:"name"`
+	Note   string       `json:"note,omitempty"`
+	Items  []strictItem `json:"items"`
+	Count  int          `json:"count"`
+}
+
+func TestDecodeJSON(t *testing.T) {
+	tests := []struct {
+		name         string
+		data         string
+		wantMissing  string // Path reported as missing, if any.
+		strict       bool
+		wantErr      bool
+		wantMismatch bool
+	}{
+		{name: "complete", strict: true, data: `{"nested":{"name":"a"},"items":[{"name":"b"}],"name":"c","note":"d","count":1}`},
+		{name: "omitempty and pointer may be absent", strict: true, data: `{"items":[],"name":"c","count":1}`},
+		{name: "unknown field", strict: true, data: `{"items":[],"name":"c","count":1,"extra":true}`, wantErr: true, wantMismatch: true},
+		{name: "missing field", strict: true, data: `{"items":[],"name":"c"}`, wantErr: true, wantMismatch: true, wantMissing: "count"},
+		{name: "missing field of slice item", strict: true, data: `{"items":[{"name":"a"},{}],"name":"c","count":1}`, wantErr: true, wantMismatch: true, wantMissing: "items[1].name"},
+		{name: "missing field of nested struct", strict: true, data: `{"nested":{},"items":[],"name":"c","count":1}`, wantErr: true, wantMismatch: true, wantMissing: "nested.name"},
+		{name: "wrong type", strict: true, data: `{"items":[],"name":"c","count":"1"}`, wantErr: true, wantMismatch: true},
+		{name: "syntax error", strict: true, data: `{"name":`, wantErr: true},
+		{name: "lenient ignores unknown and missing", data: `{"name":"c","extra":true}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var v strictSample
+			err := decodeJSON(stdCodec{}, []byte(tt.data), &v, tt.strict)
+			if !tt.wantErr {
+				if err != nil {
+					t.Fatalf("decodeJSON: %v", err)
+				}
+				return
+			}
+
+			if err == nil {
+				t.Fatal("decodeJSON succeeded")
+			}
+
+			if errors.Is(err, ErrSchemaMismatch) != tt.wantMismatch {
+				t.Fatalf("got %v, want ErrSchemaMismatch %v", err, tt.wantMismatch)
+			}
+
+			if tt.wantMissing != "" && !strings.Contains(err.Error(), tt.wantMissing) {
+				t.Fatalf("got %v, want it to name %s", err, tt.wantMissing)
+			}
+		})
+	}
+}
+
+func TestWebhookStrictDecoding(t *testing.T) {
+	data, err := json.Marshal(VoteCreatePayload{ID: 1, Weight: 1, VotedAt: time.Now(), ExpiresAt: time.Now().Add(DefaultVoteValidity)})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	complete := `{"type":"vote.create","data":` + string(data) + `}`
+	tests := []struct {
+		name   string
+		body   string
+		strict int // Expected status in strict mode.
+		loose  int // And without it.
+	}{
+		{name: "complete vote", body: complete, strict: http.StatusOK, loose: http.StatusOK},
+		{name: "unknown field", body: strings.Replace(complete, `"weight":1`, `"weight":1,"bonus":2`, 1), strict: http.StatusBadRequest, loose: http.StatusOK},
+		{name: "missing field", body: strings.Replace(complete, `"query":null,`, ``, 1), strict: http.StatusBadRequest, loose: http.StatusOK},
+		{name: "unknown base field", body: `{"type":"vote.create","version":2,"data":` + string(data) + `}`, strict: http.StatusBadRequest, loose: http.StatusOK},
+		{name: "unknown type", body: `{"type":"vote.delete","data":{}}`, strict: http.StatusBadRequest, loose: http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, strict := range []bool{true, false} {
+				w := NewClient(ClientOptions{}).NewWebhookHandler(WebhookOptions{
+					Secret:         testSecret,
+					StrictDecoding: strict,
+					OnVote:         func(vote VoteCreatePayload) {},
+				})
+
+				want := tt.loose
+				if strict {
+					want = tt.strict
+				}
+
+				if got := deliver(w, signedRequest(t, testSecret, []byte(tt.body), time.Now())); got != want {
+					t.Errorf("strict %v: got %d, want %d", strict, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestWebhookDecodesDataOnce(t *testing.T) {
+	codec := &countingCodec{}
+	w := NewClient(ClientOptions{Codec: codec}).NewWebhookHandler(WebhookOptions{
+		Secret: testSecret,
+		OnVote: func(vote VoteCreatePayload) {},
+	})
+
+	if got := deliver(w, signedRequest(t, testSecret, voteBody(1), time.Now())); got != http.StatusOK {
+		t.Fatalf("got %d, want 200", got)
+	}
+
+	// Once for the envelope, once for the vote.
+	if n := codec.unmarshals.Load(); n != 2 {
+		t.Fatalf("delivery was unmarshalled %d times, want 2", n)
+	}
+}
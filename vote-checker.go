@@ -0,0 +1,392 @@
+package topgg
+
+import (
+	"container/list"
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Persists known votes, keyed by the voter's platform ID (e.g. Discord user ID).
+// GetVote should return nil vote (and nil error) when there's no record for given user.
+type VoteStore interface {
+	GetVote(ctx context.Context, userID Snowflake) (*PartialVote, error)
+	PutVote(ctx context.Context, userID Snowflake, vote PartialVote) error
+}
+
+// Optionally implemented by VoteStore keeping expired votes for a while (see MemoryVoteStore.SetRetention),
+// so VoteChecker can fall back to them when Top.gg is unavailable, see VoteCheckerOptions.StaleFor.
+type StaleVoteStore interface {
+	// Like GetVote, but also returns last vote of user when it already expired and store still keeps it.
+	GetLastVote(ctx context.Context, userID Snowflake) (*PartialVote, error)
+}
+
+// Default, in-memory VoteStore. Expired votes are dropped on read and pruned periodically,
+// so votes of users that never come back don't pile up.
+type MemoryVoteStore struct {
+	votes      map[Snowflake]PartialVote
+	lru        *list.List // User IDs from most to least recently used, nil when store is unbounded.
+	elements   map[Snowflake]*list.Element
+	evictions  uint64
+	sincePrune int // Puts since last prune.
+	maxEntries int
+	retention  time.Duration
+	mu         sync.RWMutex
+}
+
+// Number of puts after which MemoryVoteStore prunes expired votes.
+const memoryVoteStorePruneEvery = 1024
+
+func NewMemoryVoteStore() *MemoryVoteStore {
+	return &MemoryVoteStore{
+		votes: make(map[Snowflake]PartialVote),
+	}
+}
+
+// Like NewMemoryVoteStore, but keeps at most maxEntries votes, evicting least recently used ones,
+// so memory use stays predictable on small hosts. Evicted users are looked up through API again.
+func NewBoundedMemoryVoteStore(maxEntries int) *MemoryVoteStore {
+	s := NewMemoryVoteStore()
+	if maxEntries > 0 {
+		s.lru = list.New()
+		s.elements = make(map[Snowflake]*list.Element)
+		s.maxEntries = maxEntries
+	}
+
+	return s
+}
+
+func (s *MemoryVoteStore) GetVote(ctx context.Context, userID Snowflake) (*PartialVote, error) {
+	if s.lru != nil {
+		return s.getTouching(userID), nil
+	}
+
+	s.mu.RLock()
+	vote, ok := s.votes[userID]
+	s.mu.RUnlock()
+
+	if !ok {
+		return nil, nil
+	}
+
+	if now := time.Now(); now.After(vote.ExpiresAt) {
+		s.mu.Lock()
+		if current, ok := s.votes[userID]; ok && current.ExpiresAt.Equal(vote.ExpiresAt) && s.pastRetention(now, current) {
+			delete(s.votes, userID)
+		}
+		s.mu.Unlock()
+		return nil, nil
+	}
+
+	return &vote, nil
+}
+
+func (s *MemoryVoteStore) GetLastVote(ctx context.Context, userID Snowflake) (*PartialVote, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	vote, ok := s.votes[userID]
+	if !ok || s.pastRetention(time.Now(), vote) {
+		return nil, nil
+	}
+
+	return &vote, nil
+}
+
+// Bounded variant of GetVote, marking vote as recently used.
+func (s *MemoryVoteStore) getTouching(userID Snowflake) *PartialVote {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	vote, ok := s.votes[userID]
+	if !ok {
+		return nil
+	}
+
+	if now := time.Now(); now.After(vote.ExpiresAt) {
+		if s.pastRetention(now, vote) {
+			s.remove(userID)
+		}
+		return nil
+	}
+
+	s.lru.MoveToFront(s.elements[userID])
+	return &vote
+}
+
+func (s *MemoryVoteStore) PutVote(ctx context.Context, userID Snowflake, vote PartialVote) error {
+	s.mu.Lock()
+	s.votes[userID] = vote
+	if s.lru != nil {
+		if element, ok := s.elements[userID]; ok {
+			s.lru.MoveToFront(element)
+		} else {
+			s.elements[userID] = s.lru.PushFront(userID)
+		}
+
+		for len(s.votes) > s.maxEntries {
+			s.remove(s.lru.Back().Value.(Snowflake))
+			s.evictions++
+		}
+	}
+
+	s.sincePrune++
+	if s.sincePrune >= memoryVoteStorePruneEvery {
+		s.prune(time.Now())
+	}
+	s.mu.Unlock()
+	return nil
+}
+
+// Keeps expired votes for given duration before pruning them, they're still not returned by GetVote.
+// Defaults to 0, dropping votes once they expire.
+func (s *MemoryVoteStore) SetRetention(retention time.Duration) {
+	if retention < 0 {
+		retention = 0
+	}
+
+	s.mu.Lock()
+	s.retention = retention
+	s.mu.Unlock()
+}
+
+// Removes votes expired for longer than retention and returns how many were removed.
+func (s *MemoryVoteStore) Prune() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.prune(time.Now())
+}
+
+func (s *MemoryVoteStore) prune(now time.Time) int {
+	pruned := 0
+	for userID, vote := range s.votes {
+		if s.pastRetention(now, vote) {
+			s.remove(userID)
+			pruned++
+		}
+	}
+
+	s.sincePrune = 0
+	return pruned
+}
+
+// Reports whether vote expired for longer than retention, caller must hold the lock.
+func (s *MemoryVoteStore) pastRetention(now time.Time, vote PartialVote) bool {
+	return now.After(vote.ExpiresAt.Add(s.retention))
+}
+
+// Caller must hold the write lock.
+func (s *MemoryVoteStore) remove(userID Snowflake) {
+	delete(s.votes, userID)
+	if s.lru != nil {
+		s.lru.Remove(s.elements[userID])
+		delete(s.elements, userID)
+	}
+}
+
+func (s *MemoryVoteStore) MemoryStats() MemoryStats {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return MemoryStats{Entries: len(s.votes), MaxEntries: s.maxEntries, Evictions: s.evictions}
+}
+
+type VoteCheckerOptions struct {
+	Store    VoteStore     // Defaults to MemoryVoteStore.
+	API      TopGGClient   // Used for API lookups instead of the client creating VoteChecker, e.g. MockClient in tests.
+	Source   Platform      // Platform of user IDs passed to HasVoted, defaults to PlatformDiscord.
+	StaleFor time.Duration // When Top.gg is unavailable, HasVoted accepts stored vote that expired less than StaleFor ago. Requires Store implementing StaleVoteStore that retains votes at least as long.
+}
+
+// Combines votes received through webhooks with API lookups.
+// Pass RecordVote as WebhookOptions.OnVote (or call it from your own callback) to feed the store.
+type VoteChecker struct {
+	client      TopGGClient
+	store       VoteStore
+	scheduler   Scheduler
+	traceLogger *log.Logger
+	source      Platform
+	validity    time.Duration
+	staleFor    time.Duration
+}
+
+func (c *Client) NewVoteChecker(opt VoteCheckerOptions) *VoteChecker {
+	store := opt.Store
+	if store == nil {
+		store = NewMemoryVoteStore()
+	}
+
+	var api TopGGClient = c
+	if opt.API != nil {
+		api = opt.API
+	}
+
+	source := opt.Source
+	if source == "" {
+		source = PlatformDiscord
+	}
+
+	return &VoteChecker{
+		client:      api,
+		store:       store,
+		scheduler:   c.scheduler,
+		traceLogger: c.traceLogger,
+		source:      source,
+		validity:    c.voteValidity,
+		staleFor:    opt.StaleFor,
+	}
+}
+
+func (vc *VoteChecker) tracef(format string, v ...any) {
+	vc.traceLogger.Printf("[VOTE CHECKER] "+format, v...)
+}
+
+// Saves vote delivered by webhook in the store. Votes without expiry time last for ClientOptions.VoteValidity.
+func (vc *VoteChecker) RecordVote(vote VoteCreatePayload) {
+	expiresAt := vote.ExpiresAt
+	if expiresAt.IsZero() {
+		expiresAt = vote.VotedAt.Add(vc.validity)
+	}
+
+	err := vc.store.PutVote(context.Background(), vote.User.PlatformID, PartialVote{
+		VotedAt:   vote.VotedAt,
+		ExpiresAt: expiresAt,
+		Weight:    vote.Weight,
+	})
+
+	if err != nil {
+		vc.tracef("Failed to store vote of user %s: %v", vote.User.PlatformID, err)
+	}
+}
+
+// Reports whether user has an active vote. The local store is consulted first and
+// Top.gg API is only called when store has no active record of given user.
+// When Top.gg is unavailable, returned error matches ErrUnavailable, so command handlers can tell users to try later,
+// unless VoteCheckerOptions.StaleFor lets recently expired stored vote count instead.
+func (vc *VoteChecker) HasVoted(ctx context.Context, userID Snowflake) (bool, error) {
+	stored, err := vc.store.GetVote(ctx, userID)
+	if err != nil {
+		vc.tracef("Failed to read vote of user %s from store, falling back to API: %v", userID, err)
+	} else if stored != nil && time.Now().Before(stored.ExpiresAt) {
+		return true, nil
+	}
+
+	vote, err := vc.client.GetVote(ctx, userID, vc.source)
+	if err != nil {
+		var apiErr *APIError
+		if errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusNotFound {
+			return false, nil
+		}
+
+		if !IsUnavailable(err) {
+			return false, err
+		}
+
+		if vc.hasStaleVote(ctx, userID) {
+			vc.tracef("Top.gg is unavailable, accepting recently expired vote of user %s: %v", userID, err)
+			return true, nil
+		}
+
+		if !errors.Is(err, ErrUnavailable) {
+			err = &unavailableError{err: err}
+		}
+
+		return false, err
+	}
+
+	if !time.Now().Before(vote.ExpiresAt) {
+		return false, nil
+	}
+
+	if err := vc.store.PutVote(ctx, userID, *vote); err != nil {
+		vc.tracef("Failed to store vote of user %s: %v", userID, err)
+	}
+
+	return true, nil
+}
+
+// Reports whether store keeps vote of user that expired less than VoteCheckerOptions.StaleFor ago.
+func (vc *VoteChecker) hasStaleVote(ctx context.Context, userID Snowflake) bool {
+	store, ok := vc.store.(StaleVoteStore)
+	if vc.staleFor <= 0 || !ok {
+		return false
+	}
+
+	vote, err := store.GetLastVote(ctx, userID)
+	if err != nil {
+		vc.tracef("Failed to read last vote of user %s from store: %v", userID, err)
+		return false
+	}
+
+	return vote != nil && time.Now().Before(vote.ExpiresAt.Add(vc.staleFor))
+}
+
+// Summary of a single reconciliation run.
+type ReconcileReport struct {
+	Fetched int // Active votes returned by Top.gg API.
+	Missing int // Votes that were absent from the store (e.g. missed while webhook was down) and got added.
+	Updated int // Votes that replaced older record of the same user.
+}
+
+// Pulls votes cast since given time and merges ones missing from the store.
+// Stored record is only replaced when fetched vote expires later than it.
+func (vc *VoteChecker) Reconcile(ctx context.Context, since time.Time) (ReconcileReport, error) {
+	var report ReconcileReport
+
+	now := time.Now()
+	err := EachItem(ctx, VotePages(vc.client, &since), func(vote Vote) error {
+		if !now.Before(vote.ExpiresAt) {
+			return nil
+		}
+
+		report.Fetched++
+		stored, err := vc.store.GetVote(ctx, vote.PlatformID)
+		if err != nil {
+			return err
+		}
+
+		if stored != nil && !vote.ExpiresAt.After(stored.ExpiresAt) {
+			return nil
+		}
+
+		if err := vc.store.PutVote(ctx, vote.PlatformID, vote.PartialVote); err != nil {
+			return err
+		}
+
+		if stored == nil {
+			report.Missing++
+		} else {
+			report.Updated++
+		}
+
+		return nil
+	})
+
+	return report, err
+}
+
+// Reconciles store with Top.gg API every interval until ctx is done, looking back by ClientOptions.VoteValidity.
+// Optional onReport callback receives result of every run.
+func (vc *VoteChecker) RunReconciler(ctx context.Context, interval time.Duration, onReport func(report ReconcileReport, err error)) {
+	reconcile := func(ctx context.Context) {
+		report, err := vc.Reconcile(ctx, time.Now().Add(-vc.validity))
+		if err != nil {
+			vc.tracef("Reconciliation failed: %v", err)
+		} else if report.Missing > 0 {
+			vc.tracef("Reconciliation found %d missing votes", report.Missing)
+		}
+
+		if onReport != nil {
+			if err := safeCall("vote checker", "onReport", func() { onReport(report, err) }); err != nil {
+				vc.tracef("%v", err)
+			}
+		}
+	}
+
+	reconcile(ctx)
+	_ = vc.scheduler.Every(ctx, interval, reconcile)
+}
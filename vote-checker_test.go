@@ -0,0 +1,325 @@
+package topgg
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestBoundedMemoryVoteStore(t *testing.T) {
+	type op struct {
+		user    Snowflake
+		get     bool // Reads vote instead of storing one.
+		expired bool // Stored vote has already expired.
+	}
+
+	tests := []struct {
+		name       string
+		ops        []op
+		present    []Snowflake
+		missing    []Snowflake
+		maxEntries int
+		evictions  uint64
+	}{
+		{
+			name:       "keeps votes within limit",
+			maxEntries: 3,
+			ops:        []op{{user: 1}, {user: 2}, {user: 3}},
+			present:    []Snowflake{1, 2, 3},
+		},
+		{
+			name:       "evicts least recently stored vote",
+			maxEntries: 2,
+			ops:        []op{{user: 1}, {user: 2}, {user: 3}},
+			present:    []Snowflake{2, 3},
+			missing:    []Snowflake{1},
+			evictions:  1,
+		},
+		{
+			name:       "read marks vote as recently used",
+			maxEntries: 2,
+			ops:        []op{{user: 1}, {user: 2}, {user: 1, get: true}, {user: 3}},
+			present:    []Snowflake{1, 3},
+			missing:    []Snowflake{2},
+			evictions:  1,
+		},
+		{
+			name:       "storing vote again marks it as recently used",
+			maxEntries: 2,
+			ops:        []op{{user: 1}, {user: 2}, {user: 1}, {user: 3}},
+			present:    []Snowflake{1, 3},
+			missing:    []Snowflake{2},
+			evictions:  1,
+		},
+		{
+			name:       "expired vote is dropped on read",
+			maxEntries: 2,
+			ops:        []op{{user: 1, expired: true}, {user: 2}, {user: 1, get: true}, {user: 3}},
+			present:    []Snowflake{2, 3},
+			missing:    []Snowflake{1},
+		},
+		{
+			name:    "unbounded store never evicts",
+			ops:     []op{{user: 1}, {user: 2}, {user: 3}},
+			present: []Snowflake{1, 2, 3},
+		},
+	}
+
+	ctx := context.Background()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := NewBoundedMemoryVoteStore(tt.maxEntries)
+			for _, op := range tt.ops {
+				if op.get {
+					_, _ = s.GetVote(ctx, op.user)
+					continue
+				}
+
+				expiresAt := time.Now().Add(time.Hour)
+				if op.expired {
+					expiresAt = time.Now().Add(-time.Hour)
+				}
+
+				if err := s.PutVote(ctx, op.user, PartialVote{ExpiresAt: expiresAt}); err != nil {
+					t.Fatalf("PutVote: %v", err)
+				}
+			}
+
+			for _, user := range tt.present {
+				if vote, _ := s.GetVote(ctx, user); vote == nil {
+					t.Errorf("vote of user %s is missing", user)
+				}
+			}
+
+			for _, user := range tt.missing {
+				if vote, _ := s.GetVote(ctx, user); vote != nil {
+					t.Errorf("vote of user %s should be gone", user)
+				}
+			}
+
+			stats := s.MemoryStats()
+			if stats.Evictions != tt.evictions || stats.MaxEntries != tt.maxEntries {
+				t.Errorf("got %+v, want %d evictions with limit %d", stats, tt.evictions, tt.maxEntries)
+			}
+
+			if tt.maxEntries > 0 && stats.Entries > tt.maxEntries {
+				t.Errorf("store holds %d votes, limit is %d", stats.Entries, tt.maxEntries)
+			}
+		})
+	}
+}
+
+func TestMemoryVoteStorePrune(t *testing.T) {
+	for _, maxEntries := range []int{0, 2 * memoryVoteStorePruneEvery} {
+		s := NewBoundedMemoryVoteStore(maxEntries)
+		ctx := context.Background()
+
+		for i := 0; i < memoryVoteStorePruneEvery-1; i++ {
+			_ = s.PutVote(ctx, Snowflake(i), PartialVote{ExpiresAt: time.Now().Add(-time.Hour)})
+		}
+
+		if n := s.MemoryStats().Entries; n != memoryVoteStorePruneEvery-1 {
+			t.Fatalf("limit %d: store holds %d votes before prune", maxEntries, n)
+		}
+
+		// Put reaching the threshold prunes expired votes, keeping the fresh one.
+		_ = s.PutVote(ctx, 0, PartialVote{ExpiresAt: time.Now().Add(time.Hour)})
+		if n := s.MemoryStats().Entries; n != 1 {
+			t.Fatalf("limit %d: store holds %d votes after prune, want 1", maxEntries, n)
+		}
+	}
+}
+
+func TestMemoryVoteStoreRetention(t *testing.T) {
+	for _, maxEntries := range []int{0, 10} {
+		s := NewBoundedMemoryVoteStore(maxEntries)
+		s.SetRetention(time.Hour)
+		ctx := context.Background()
+
+		_ = s.PutVote(ctx, 1, PartialVote{ExpiresAt: time.Now().Add(-30 * time.Minute)})
+		_ = s.PutVote(ctx, 2, PartialVote{ExpiresAt: time.Now().Add(-2 * time.Hour)})
+
+		// Retained votes still aren't valid ones.
+		if vote, _ := s.GetVote(ctx, 1); vote != nil {
+			t.Fatalf("limit %d: expired vote within retention was returned", maxEntries)
+		}
+
+		if n := s.Prune(); n != 1 {
+			t.Fatalf("limit %d: pruned %d votes, want only the one past retention", maxEntries, n)
+		}
+
+		if n := s.MemoryStats().Entries; n != 1 {
+			t.Fatalf("limit %d: store holds %d votes, want the retained one", maxEntries, n)
+		}
+	}
+}
+
+// VoteStore hiding GetLastVote of wrapped store.
+type plainVoteStore struct {
+	VoteStore
+}
+
+func TestVoteCheckerStaleFallback(t *testing.T) {
+	tests := []struct {
+		apiErr      error
+		name        string
+		expiredAgo  time.Duration // Age of stored vote past its expiry.
+		staleFor    time.Duration
+		plainStore  bool
+		want        bool
+		wantErr     bool
+		wantUnavail bool
+	}{
+		{name: "recently expired vote", apiErr: newAPIError(http.StatusServiceUnavailable, nil), expiredAgo: 10 * time.Minute, staleFor: time.Hour, want: true},
+		{name: "network error", apiErr: &unavailableError{err: errors.New("connection refused")}, expiredAgo: 10 * time.Minute, staleFor: time.Hour, want: true},
+		{name: "vote too old", apiErr: newAPIError(http.StatusServiceUnavailable, nil), expiredAgo: 2 * time.Hour, staleFor: time.Hour, wantErr: true, wantUnavail: true},
+		{name: "fallback disabled", apiErr: newAPIError(http.StatusBadGateway, nil), expiredAgo: 10 * time.Minute, wantErr: true, wantUnavail: true},
+		{name: "store without last votes", apiErr: newAPIError(http.StatusServiceUnavailable, nil), expiredAgo: 10 * time.Minute, staleFor: time.Hour, plainStore: true, wantErr: true, wantUnavail: true},
+		{name: "user didn't vote again", apiErr: newAPIError(http.StatusNotFound, nil), expiredAgo: 10 * time.Minute, staleFor: time.Hour},
+		{name: "other API error", apiErr: newAPIError(http.StatusBadRequest, nil), expiredAgo: 10 * time.Minute, staleFor: time.Hour, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			memory := NewMemoryVoteStore()
+			memory.SetRetention(3 * time.Hour)
+			expiresAt := time.Now().Add(-tt.expiredAgo)
+			_ = memory.PutVote(context.Background(), 1, PartialVote{VotedAt: expiresAt.Add(-12 * time.Hour), ExpiresAt: expiresAt})
+
+			var store VoteStore = memory
+			if tt.plainStore {
+				store = plainVoteStore{memory}
+			}
+
+			api := &MockClient{GetVoteFunc: func(ctx context.Context, userID Snowflake, source Platform) (*PartialVote, error) {
+				return nil, tt.apiErr
+			}}
+
+			checker := NewClient(ClientOptions{}).NewVoteChecker(VoteCheckerOptions{Store: store, API: api, StaleFor: tt.staleFor})
+			voted, err := checker.HasVoted(context.Background(), 1)
+			if (err != nil) != tt.wantErr || voted != tt.want {
+				t.Fatalf("HasVoted = %v, %v; want %v with error %v", voted, err, tt.want, tt.wantErr)
+			}
+
+			if tt.wantUnavail && !errors.Is(err, ErrUnavailable) {
+				t.Fatalf("got %v, want ErrUnavailable", err)
+			}
+		})
+	}
+}
+
+var errStoreDown = errors.New("store is down")
+
+// VoteStore failing every call.
+type failingVoteStore struct{}
+
+func (failingVoteStore) GetVote(ctx context.Context, userID Snowflake) (*PartialVote, error) {
+	return nil, errStoreDown
+}
+
+func (failingVoteStore) PutVote(ctx context.Context, userID Snowflake, vote PartialVote) error {
+	return errStoreDown
+}
+
+func TestVoteCheckerHasVoted(t *testing.T) {
+	active := &PartialVote{VotedAt: time.Now(), ExpiresAt: time.Now().Add(time.Hour), Weight: 1}
+
+	tests := []struct {
+		apiErr    error
+		stored    *PartialVote // Vote in store before the lookup.
+		apiVote   *PartialVote
+		name      string
+		wantCalls int // API lookups.
+		failStore bool
+		want      bool
+		wantErr   bool
+		wantSaved bool // Vote is in store afterwards.
+	}{
+		{name: "store hit", stored: active, want: true, wantSaved: true},
+		{name: "store miss, API hit", apiVote: active, want: true, wantCalls: 1, wantSaved: true},
+		{name: "expired in store, API hit", stored: &PartialVote{ExpiresAt: time.Now().Add(-time.Minute)}, apiVote: active, want: true, wantCalls: 1, wantSaved: true},
+		{name: "expired API vote", apiVote: &PartialVote{ExpiresAt: time.Now().Add(-time.Minute)}, wantCalls: 1},
+		{name: "API 404", apiErr: newAPIError(http.StatusNotFound, nil), wantCalls: 1},
+		{name: "API error", apiErr: newAPIError(http.StatusUnauthorized, nil), wantErr: true, wantCalls: 1},
+		{name: "store error falls back to API", failStore: true, apiVote: active, want: true, wantCalls: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			memory := NewMemoryVoteStore()
+			if tt.stored != nil {
+				_ = memory.PutVote(context.Background(), 1, *tt.stored)
+			}
+
+			var store VoteStore = memory
+			if tt.failStore {
+				store = failingVoteStore{}
+			}
+
+			calls := 0
+			api := &MockClient{GetVoteFunc: func(ctx context.Context, userID Snowflake, source Platform) (*PartialVote, error) {
+				calls++
+				if userID != 1 || source != PlatformDiscord {
+					t.Errorf("looked up user %s on %s, want 1 on discord", userID, source)
+				}
+
+				return tt.apiVote, tt.apiErr
+			}}
+
+			checker := NewClient(ClientOptions{}).NewVoteChecker(VoteCheckerOptions{Store: store, API: api})
+			voted, err := checker.HasVoted(context.Background(), 1)
+			if (err != nil) != tt.wantErr || voted != tt.want {
+				t.Fatalf("HasVoted = %v, %v; want %v with error %v", voted, err, tt.want, tt.wantErr)
+			}
+
+			if calls != tt.wantCalls {
+				t.Errorf("API was called %d times, want %d", calls, tt.wantCalls)
+			}
+
+			if saved, _ := memory.GetVote(context.Background(), 1); (saved != nil) != tt.wantSaved {
+				t.Errorf("store holds %+v, want vote saved %v", saved, tt.wantSaved)
+			}
+		})
+	}
+}
+
+func TestVoteCheckerRecordVote(t *testing.T) {
+	votedAt := time.Now()
+	tests := []struct {
+		wantExpires time.Time
+		name        string
+		vote        VoteCreatePayload
+	}{
+		{name: "expiry from payload", vote: VoteCreatePayload{VotedAt: votedAt, ExpiresAt: votedAt.Add(time.Hour), Weight: 2}, wantExpires: votedAt.Add(time.Hour)},
+		{name: "missing expiry", vote: VoteCreatePayload{VotedAt: votedAt, Weight: 2}, wantExpires: votedAt.Add(DefaultVoteValidity)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := NewMemoryVoteStore()
+			api := &MockClient{GetVoteFunc: func(ctx context.Context, userID Snowflake, source Platform) (*PartialVote, error) {
+				t.Error("API was called for recorded vote")
+				return nil, nil
+			}}
+
+			checker := NewClient(ClientOptions{}).NewVoteChecker(VoteCheckerOptions{Store: store, API: api})
+			tt.vote.User.PlatformID = 1
+			checker.RecordVote(tt.vote)
+
+			stored, _ := store.GetVote(context.Background(), 1)
+			if stored == nil || !stored.ExpiresAt.Equal(tt.wantExpires) || stored.Weight != 2 {
+				t.Fatalf("stored %+v, want vote expiring at %s", stored, tt.wantExpires)
+			}
+
+			if voted, err := checker.HasVoted(context.Background(), 1); !voted || err != nil {
+				t.Fatalf("HasVoted = %v, %v after RecordVote", voted, err)
+			}
+		})
+	}
+
+	// Store failures are only traced.
+	checker := NewClient(ClientOptions{}).NewVoteChecker(VoteCheckerOptions{Store: failingVoteStore{}, API: &MockClient{}})
+	checker.RecordVote(VoteCreatePayload{VotedAt: votedAt})
+}
@@ -0,0 +1,152 @@
+package topgg
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+)
+
+type VoteRateAnomalyKind uint8
+
+const (
+	VoteRateCollapse VoteRateAnomalyKind = iota + 1 // Votes (nearly) stopped arriving, webhook is likely broken.
+	VoteRateSpike                                   // Votes arrive much faster than usual, possibly abuse.
+)
+
+// Interval with vote count far off the rolling baseline.
+type VoteRateAnomaly struct {
+	At       time.Time // End of the interval.
+	Baseline float64   // Average votes per interval over the baseline window.
+	Votes    int       // Votes in the interval.
+	Kind     VoteRateAnomalyKind
+}
+
+type VoteRateMonitorOptions struct {
+	OnAnomaly      func(anomaly VoteRateAnomaly) // Called when vote rate becomes anomalous, not again until it gets back to normal.
+	Interval       time.Duration                 // Length of compared intervals, defaults to 10 minutes.
+	BaselineWindow int                           // Number of past intervals averaged into baseline, defaults to 36 (6 hours with default interval).
+	CollapseRatio  float64                       // Interval with less than this fraction of baseline is a collapse, defaults to 0.2.
+	SpikeRatio     float64                       // Interval with more than this multiple of baseline is a spike, defaults to 5.
+	MinVotes       int                           // Collapses need baseline of at least this many votes per interval and spikes this many votes, defaults to 5. Keeps quiet projects from alerting on noise.
+}
+
+// Watches rate of incoming votes and reports collapses and spikes against rolling baseline.
+// Pass RecordVote as WebhookOptions.OnVote (or call it from your own callback) and keep Run going in background.
+type VoteRateMonitor struct {
+	onAnomaly     func(anomaly VoteRateAnomaly)
+	scheduler     Scheduler
+	traceLogger   *log.Logger
+	history       []int // Vote counts of past intervals, ring buffer.
+	interval      time.Duration
+	collapseRatio float64
+	spikeRatio    float64
+	next          int
+	filled        int
+	current       int
+	minVotes      int
+	mu            sync.Mutex
+	anomalous     bool
+}
+
+func (c *Client) NewVoteRateMonitor(opt VoteRateMonitorOptions) *VoteRateMonitor {
+	interval := opt.Interval
+	if interval <= 0 {
+		interval = 10 * time.Minute
+	}
+
+	window := opt.BaselineWindow
+	if window <= 0 {
+		window = 36
+	}
+
+	collapseRatio := opt.CollapseRatio
+	if collapseRatio <= 0 {
+		collapseRatio = 0.2
+	}
+
+	spikeRatio := opt.SpikeRatio
+	if spikeRatio <= 0 {
+		spikeRatio = 5
+	}
+
+	minVotes := opt.MinVotes
+	if minVotes <= 0 {
+		minVotes = 5
+	}
+
+	return &VoteRateMonitor{
+		onAnomaly:     opt.OnAnomaly,
+		scheduler:     c.scheduler,
+		traceLogger:   c.traceLogger,
+		history:       make([]int, window),
+		interval:      interval,
+		collapseRatio: collapseRatio,
+		spikeRatio:    spikeRatio,
+		minVotes:      minVotes,
+	}
+}
+
+func (m *VoteRateMonitor) tracef(format string, v ...any) {
+	m.traceLogger.Printf("[VOTE RATE MONITOR] "+format, v...)
+}
+
+// Counts vote delivered by webhook.
+func (m *VoteRateMonitor) RecordVote(vote VoteCreatePayload) {
+	m.mu.Lock()
+	m.current++
+	m.mu.Unlock()
+}
+
+// Closes an interval every Interval and checks it against baseline, until ctx is done.
+// Nothing is reported until BaselineWindow intervals were observed.
+func (m *VoteRateMonitor) Run(ctx context.Context) error {
+	return m.scheduler.Every(ctx, m.interval, func(ctx context.Context) {
+		m.closeInterval(time.Now())
+	})
+}
+
+func (m *VoteRateMonitor) closeInterval(now time.Time) {
+	m.mu.Lock()
+	votes := m.current
+	m.current = 0
+
+	var anomaly *VoteRateAnomaly
+	if m.filled == len(m.history) {
+		total := 0
+		for _, count := range m.history {
+			total += count
+		}
+		baseline := float64(total) / float64(len(m.history))
+
+		kind := VoteRateAnomalyKind(0)
+		switch {
+		case baseline >= float64(m.minVotes) && float64(votes) < baseline*m.collapseRatio:
+			kind = VoteRateCollapse
+		case votes >= m.minVotes && float64(votes) > baseline*m.spikeRatio:
+			kind = VoteRateSpike
+		}
+
+		if kind != 0 && !m.anomalous {
+			anomaly = &VoteRateAnomaly{At: now, Baseline: baseline, Votes: votes, Kind: kind}
+		}
+		m.anomalous = kind != 0
+	} else {
+		m.filled++
+	}
+
+	m.history[m.next] = votes
+	m.next = (m.next + 1) % len(m.history)
+	m.mu.Unlock()
+
+	if anomaly == nil {
+		return
+	}
+
+	m.tracef("Vote rate anomaly: %d votes in last %s against baseline of %.1f", anomaly.Votes, m.interval, anomaly.Baseline)
+	if m.onAnomaly != nil {
+		if err := safeCall("vote rate monitor", "OnAnomaly", func() { m.onAnomaly(*anomaly) }); err != nil {
+			m.tracef("%v", err)
+		}
+	}
+}
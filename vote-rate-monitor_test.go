@@ -0,0 +1,106 @@
+package topgg
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestVoteRateMonitor(t *testing.T) {
+	tests := []struct {
+		name      string
+		intervals []int // Votes per closed interval.
+		want      []VoteRateAnomalyKind
+	}{
+		{name: "steady rate", intervals: []int{10, 10, 10, 9, 12}},
+		{name: "collapse", intervals: []int{10, 10, 10, 1}, want: []VoteRateAnomalyKind{VoteRateCollapse}},
+		{name: "spike", intervals: []int{10, 10, 10, 60}, want: []VoteRateAnomalyKind{VoteRateSpike}},
+		{name: "nothing reported during warmup", intervals: []int{10, 0, 60}},
+		{name: "reported once while anomalous", intervals: []int{10, 10, 10, 1, 1}, want: []VoteRateAnomalyKind{VoteRateCollapse}},
+		{name: "reported again after recovery", intervals: []int{10, 10, 10, 60, 10, 300}, want: []VoteRateAnomalyKind{VoteRateSpike, VoteRateSpike}},
+		{name: "quiet project doesn't collapse", intervals: []int{2, 2, 2, 0}},
+		{name: "spike needs minimum votes", intervals: []int{0, 0, 0, 4}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []VoteRateAnomaly
+			m := NewClient(ClientOptions{}).NewVoteRateMonitor(VoteRateMonitorOptions{
+				OnAnomaly:      func(anomaly VoteRateAnomaly) { got = append(got, anomaly) },
+				BaselineWindow: 3,
+			})
+
+			now := time.Now()
+			for i, votes := range tt.intervals {
+				for j := 0; j < votes; j++ {
+					m.RecordVote(VoteCreatePayload{})
+				}
+				m.closeInterval(now.Add(time.Duration(i) * time.Minute))
+			}
+
+			if len(got) != len(tt.want) {
+				t.Fatalf("got anomalies %+v, want kinds %v", got, tt.want)
+			}
+
+			for i, kind := range tt.want {
+				if got[i].Kind != kind {
+					t.Errorf("anomaly %d is %+v, want kind %d", i, got[i], kind)
+				}
+			}
+		})
+	}
+}
+
+func TestVoteRateMonitorAnomaly(t *testing.T) {
+	var got VoteRateAnomaly
+	m := NewClient(ClientOptions{}).NewVoteRateMonitor(VoteRateMonitorOptions{
+		OnAnomaly:      func(anomaly VoteRateAnomaly) { got = anomaly },
+		BaselineWindow: 2,
+	})
+
+	at := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	for _, votes := range []int{8, 12, 1} {
+		for j := 0; j < votes; j++ {
+			m.RecordVote(VoteCreatePayload{})
+		}
+		m.closeInterval(at)
+	}
+
+	if got.Kind != VoteRateCollapse || got.Votes != 1 || got.Baseline != 10 || !got.At.Equal(at) {
+		t.Fatalf("got %+v, want collapse to 1 vote against baseline of 10", got)
+	}
+}
+
+func TestVoteRateMonitorCallbackPanic(t *testing.T) {
+	m := NewClient(ClientOptions{}).NewVoteRateMonitor(VoteRateMonitorOptions{
+		OnAnomaly:      func(anomaly VoteRateAnomaly) { panic("alerting is down") },
+		BaselineWindow: 1,
+	})
+
+	for _, votes := range []int{10, 0} {
+		for j := 0; j < votes; j++ {
+			m.RecordVote(VoteCreatePayload{})
+		}
+		m.closeInterval(time.Now())
+	}
+}
+
+func TestVoteRateMonitorRun(t *testing.T) {
+	scheduler := &instantScheduler{}
+	m := NewClient(ClientOptions{Scheduler: scheduler}).NewVoteRateMonitor(VoteRateMonitorOptions{Interval: time.Hour})
+	m.RecordVote(VoteCreatePayload{})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_ = m.Run(ctx)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.current != 0 || m.history[0] != 1 {
+		t.Fatalf("interval wasn't closed by scheduled job, %d votes pending", m.current)
+	}
+
+	if len(scheduler.intervals) != 1 || scheduler.intervals[0] != time.Hour {
+		t.Fatalf("scheduled intervals %v, want [1h]", scheduler.intervals)
+	}
+}
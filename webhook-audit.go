@@ -0,0 +1,158 @@
+package topgg
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Record of a single webhook delivery, see WebhookOptions.AuditLog.
+type DeliveryRecord struct {
+	At     time.Time       `json:"at"`
+	IP     string          `json:"ip"`
+	Scope  Scope           `json:"scope,omitempty"`   // Empty when delivery was rejected before being decoded.
+	Reason RejectionReason `json:"reason,omitempty"`  // Empty when delivery was accepted. Duplicates are acknowledged with 200, but still carry RejectDuplicate.
+	UserID Snowflake       `json:"user_id,omitempty"` // Voter's platform ID, set for decoded vote.create deliveries.
+	Status int             `json:"status"`
+}
+
+// Receives record of every webhook delivery, accepted or rejected.
+// Append is called from the handler, so it should be quick.
+type AuditLog interface {
+	Append(ctx context.Context, record DeliveryRecord) error
+}
+
+// Filters audit log records. Zero fields match everything.
+type AuditQuery struct {
+	Since        time.Time
+	Until        time.Time
+	UserID       Snowflake
+	Limit        int  // Returns at most this many latest matching records.
+	RejectedOnly bool // Only records with Reason set, including duplicates.
+}
+
+func (q AuditQuery) match(record DeliveryRecord) bool {
+	return (q.Since.IsZero() || !record.At.Before(q.Since)) &&
+		(q.Until.IsZero() || record.At.Before(q.Until)) &&
+		(q.UserID == 0 || record.UserID == q.UserID) &&
+		(!q.RejectedOnly || record.Reason != "")
+}
+
+// Keeps latest records that match query, oldest first.
+func (q AuditQuery) collect(records []DeliveryRecord, record DeliveryRecord) []DeliveryRecord {
+	if !q.match(record) {
+		return records
+	}
+
+	records = append(records, record)
+	if q.Limit > 0 && len(records) > q.Limit {
+		records = records[1:]
+	}
+
+	return records
+}
+
+// Records kept by MemoryAuditLog created with non-positive size.
+const DefaultAuditLogSize = 1000
+
+// In-memory AuditLog keeping given number of latest records.
+type MemoryAuditLog struct {
+	records []DeliveryRecord
+	next    int
+	mu      sync.Mutex
+	full    bool
+}
+
+// Keeps size latest records, DefaultAuditLogSize when size isn't positive.
+func NewMemoryAuditLog(size int) *MemoryAuditLog {
+	if size <= 0 {
+		size = DefaultAuditLogSize
+	}
+
+	return &MemoryAuditLog{records: make([]DeliveryRecord, size)}
+}
+
+func (l *MemoryAuditLog) Append(ctx context.Context, record DeliveryRecord) error {
+	l.mu.Lock()
+	l.records[l.next] = record
+	l.next++
+	if l.next == len(l.records) {
+		l.next = 0
+		l.full = true
+	}
+	l.mu.Unlock()
+	return nil
+}
+
+// Returns retained records matching q, oldest first.
+func (l *MemoryAuditLog) Query(q AuditQuery) []DeliveryRecord {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	ordered := l.records[:l.next]
+	if l.full {
+		ordered = append(append([]DeliveryRecord(nil), l.records[l.next:]...), ordered...)
+	}
+
+	var records []DeliveryRecord
+	for _, record := range ordered {
+		records = q.collect(records, record)
+	}
+
+	return records
+}
+
+// AuditLog appending records as JSON lines to w, e.g. a file opened with os.O_APPEND. Use ReadAuditLog to query it.
+type JSONAuditLog struct {
+	w  io.Writer
+	mu sync.Mutex
+}
+
+func NewJSONAuditLog(w io.Writer) *JSONAuditLog {
+	return &JSONAuditLog{w: w}
+}
+
+func (l *JSONAuditLog) Append(ctx context.Context, record DeliveryRecord) error {
+	line, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	_, err = l.w.Write(append(line, '\n'))
+	return err
+}
+
+// Reads records written by JSONAuditLog and returns ones matching q, in file order.
+func ReadAuditLog(r io.Reader, q AuditQuery) ([]DeliveryRecord, error) {
+	var records []DeliveryRecord
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+
+		var record DeliveryRecord
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			return records, err
+		}
+
+		records = q.collect(records, record)
+	}
+
+	return records, scanner.Err()
+}
+
+type deliveryRecordKey struct{}
+
+// Returns record of the delivery being handled, nil when audit log isn't configured.
+func deliveryRecordFrom(r *http.Request) *DeliveryRecord {
+	record, _ := r.Context().Value(deliveryRecordKey{}).(*DeliveryRecord)
+	return record
+}
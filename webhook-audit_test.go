@@ -0,0 +1,140 @@
+package topgg
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func auditRecords(base time.Time, users ...Snowflake) []DeliveryRecord {
+	records := make([]DeliveryRecord, len(users))
+	for i, user := range users {
+		records[i] = DeliveryRecord{At: base.Add(time.Duration(i) * time.Minute), UserID: user, Status: http.StatusOK}
+	}
+
+	return records
+}
+
+func userIDs(records []DeliveryRecord) []Snowflake {
+	ids := make([]Snowflake, len(records))
+	for i, record := range records {
+		ids[i] = record.UserID
+	}
+
+	return ids
+}
+
+func equalIDs(a, b []Snowflake) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+
+	return true
+}
+
+func TestMemoryAuditLog(t *testing.T) {
+	base := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	rejected := DeliveryRecord{At: base.Add(time.Hour), UserID: 9, Reason: RejectBadAuth, Status: http.StatusUnauthorized}
+
+	tests := []struct {
+		name    string
+		records []DeliveryRecord
+		want    []Snowflake
+		query   AuditQuery
+		size    int
+	}{
+		{name: "keeps records in order", size: 5, records: auditRecords(base, 1, 2, 3), want: []Snowflake{1, 2, 3}},
+		{name: "wraps around keeping latest", size: 3, records: auditRecords(base, 1, 2, 3, 4, 5), want: []Snowflake{3, 4, 5}},
+		{name: "wraps around exactly once", size: 3, records: auditRecords(base, 1, 2, 3), want: []Snowflake{1, 2, 3}},
+		{name: "non-positive size falls back to default", size: 0, records: auditRecords(base, 1, 2), want: []Snowflake{1, 2}},
+		{name: "limit keeps latest matches", size: 10, records: auditRecords(base, 1, 2, 3, 4), query: AuditQuery{Limit: 2}, want: []Snowflake{3, 4}},
+		{name: "user filter", size: 10, records: auditRecords(base, 1, 2, 1, 3), query: AuditQuery{UserID: 1}, want: []Snowflake{1, 1}},
+		{name: "time range", size: 10, records: auditRecords(base, 1, 2, 3, 4), query: AuditQuery{Since: base.Add(time.Minute), Until: base.Add(3 * time.Minute)}, want: []Snowflake{2, 3}},
+		{name: "rejected only", size: 10, records: append(auditRecords(base, 1, 2), rejected), query: AuditQuery{RejectedOnly: true}, want: []Snowflake{9}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			log := NewMemoryAuditLog(tt.size)
+			for _, record := range tt.records {
+				if err := log.Append(context.Background(), record); err != nil {
+					t.Fatalf("Append: %v", err)
+				}
+			}
+
+			if got := userIDs(log.Query(tt.query)); !equalIDs(got, tt.want) {
+				t.Fatalf("got records of users %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestJSONAuditLogRoundTrip(t *testing.T) {
+	base := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	var buf bytes.Buffer
+	log := NewJSONAuditLog(&buf)
+
+	records := auditRecords(base, 1, 2, 3)
+	records[1].Reason = RejectDuplicate
+	records[1].Scope = ScopeVoteCreate
+	records[1].IP = "203.0.113.7"
+	for _, record := range records {
+		if err := log.Append(context.Background(), record); err != nil {
+			t.Fatalf("Append: %v", err)
+		}
+	}
+
+	all, err := ReadAuditLog(bytes.NewReader(buf.Bytes()), AuditQuery{})
+	if err != nil {
+		t.Fatalf("ReadAuditLog: %v", err)
+	}
+
+	if len(all) != len(records) {
+		t.Fatalf("read %d records, want %d", len(all), len(records))
+	}
+
+	for i := range records {
+		if !all[i].At.Equal(records[i].At) || all[i].UserID != records[i].UserID || all[i].Reason != records[i].Reason ||
+			all[i].Scope != records[i].Scope || all[i].IP != records[i].IP || all[i].Status != records[i].Status {
+			t.Fatalf("record %d read back as %+v, want %+v", i, all[i], records[i])
+		}
+	}
+
+	latest, err := ReadAuditLog(bytes.NewReader(buf.Bytes()), AuditQuery{Limit: 1})
+	if err != nil || len(latest) != 1 || latest[0].UserID != 3 {
+		t.Fatalf("got %v, %v; want latest record only", latest, err)
+	}
+
+	if _, err := ReadAuditLog(bytes.NewReader([]byte("{broken\n")), AuditQuery{}); err == nil {
+		t.Fatal("broken line wasn't reported")
+	}
+}
+
+func TestWebhookAuditLog(t *testing.T) {
+	log := NewMemoryAuditLog(0)
+	w := NewClient(ClientOptions{}).NewWebhookHandler(WebhookOptions{Secret: testSecret, AuditLog: log})
+
+	deliver(w, signedRequest(t, testSecret, voteBody(1), time.Now()))
+	deliver(w, signedRequest(t, "wrong-secret", voteBody(2), time.Now()))
+
+	records := log.Query(AuditQuery{})
+	if len(records) != 2 {
+		t.Fatalf("got %d records, want 2", len(records))
+	}
+
+	if accepted := records[0]; accepted.UserID != 1 || accepted.Scope != ScopeVoteCreate || accepted.Status != http.StatusOK || accepted.Reason != "" {
+		t.Fatalf("accepted delivery recorded as %+v", accepted)
+	}
+
+	if rejected := records[1]; rejected.Status != http.StatusUnauthorized || rejected.Reason == "" {
+		t.Fatalf("rejected delivery recorded as %+v", rejected)
+	}
+}
@@ -0,0 +1,296 @@
+package topgg
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"sync/atomic"
+)
+
+// Decides what happens with a delivery when async dispatch queue of the webhook is full.
+type QueuePolicy uint8
+
+const (
+	QueueBlock      QueuePolicy = iota // Waits for free slot in the queue, until request gets cancelled.
+	QueueDropOldest                    // Drops the oldest queued delivery to make room for new one.
+	QueueReject                        // Responds with 503, so Top.gg retries delivery later.
+)
+
+// Decides when webhook acknowledges delivery to Top.gg.
+type AckMode uint8
+
+const (
+	AckDefault       AckMode = iota // AckAfterProcess when callbacks run synchronously, AckBeforeProcess with workers.
+	AckBeforeProcess                // Responds before callback runs. Lowest latency, but delivery is lost if callback fails (at-most-once).
+	AckAfterProcess                 // Responds after callback returns, with 500 if it panicked so Top.gg retries delivery (at-least-once).
+)
+
+type webhookJob struct {
+	callback func()
+	status   chan int           // Receives response status when delivery is acknowledged after processing, nil otherwise.
+	taken    *atomic.Bool       // Set by whoever takes job first, the worker running it or the handler giving up on it. Nil when status is.
+	vote     *VoteCreatePayload // Saved to DrainStore when Shutdown gives up on acknowledged job, nil for other scopes.
+	scope    Scope
+}
+
+// Counts goroutines Shutdown waits for, like sync.WaitGroup but with waiting that can be abandoned.
+type drainGroup struct {
+	idle   chan struct{} // Closed once count drops to zero, nil while it's zero.
+	mu     sync.Mutex
+	count  int
+	closed bool
+}
+
+// Counts goroutine in, unless group was closed.
+func (g *drainGroup) enter() bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if g.closed {
+		return false
+	}
+
+	g.inc()
+	return true
+}
+
+// Counts goroutine in, even if group was closed.
+func (g *drainGroup) add() {
+	g.mu.Lock()
+	g.inc()
+	g.mu.Unlock()
+}
+
+// Must be called with g.mu held.
+func (g *drainGroup) inc() {
+	if g.count == 0 {
+		g.idle = make(chan struct{})
+	}
+	g.count++
+}
+
+func (g *drainGroup) leave() {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	g.count--
+	if g.count == 0 {
+		close(g.idle)
+	}
+}
+
+// Makes enter fail from now on.
+func (g *drainGroup) close() {
+	g.mu.Lock()
+	g.closed = true
+	g.mu.Unlock()
+}
+
+// Returns channel closed once no goroutine is counted in.
+func (g *drainGroup) wait() <-chan struct{} {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if g.count == 0 {
+		done := make(chan struct{})
+		close(done)
+		return done
+	}
+
+	return g.idle
+}
+
+func (w *Webhook) work() {
+	defer w.pending.leave()
+	for {
+		var job webhookJob
+		select {
+		case <-w.abandoned:
+			return
+		case queued, ok := <-w.queue:
+			if !ok {
+				return
+			}
+			job = queued
+		}
+
+		if w.isAbandoned() {
+			w.shelve(job)
+			continue
+		}
+
+		if job.taken != nil && !job.taken.CompareAndSwap(false, true) {
+			// Already answered with 503, so Top.gg redelivers it.
+			continue
+		}
+
+		ok := w.invoke(job.scope, job.callback)
+		if job.status == nil {
+			continue
+		}
+
+		if ok {
+			job.status <- http.StatusOK
+		} else {
+			job.status <- http.StatusInternalServerError
+		}
+	}
+}
+
+// Runs callback according to configured ack mode and returns status code to respond with.
+// Vote is kept with queued job, so it can be saved when Shutdown gives up on it.
+func (w *Webhook) dispatch(ctx context.Context, scope Scope, callback func(), vote *VoteCreatePayload) int {
+	if w.ackMode == AckBeforeProcess {
+		if w.queue == nil {
+			w.pending.add()
+			go func() {
+				defer w.pending.leave()
+				w.invoke(scope, callback)
+			}()
+			return http.StatusOK
+		}
+
+		if !w.enqueue(ctx, webhookJob{callback: callback, vote: vote, scope: scope}) {
+			return http.StatusServiceUnavailable
+		}
+
+		return http.StatusOK
+	}
+
+	if w.queue == nil {
+		if !w.invoke(scope, callback) {
+			return http.StatusInternalServerError
+		}
+
+		return http.StatusOK
+	}
+
+	job := webhookJob{callback: callback, status: make(chan int, 1), taken: &atomic.Bool{}, scope: scope}
+	if !w.enqueue(ctx, job) {
+		return http.StatusServiceUnavailable
+	}
+
+	select {
+	case status := <-job.status:
+		return status
+	case <-ctx.Done():
+		if job.taken.CompareAndSwap(false, true) {
+			return http.StatusServiceUnavailable
+		}
+
+		// Worker is already running it, answering 503 would get it processed twice.
+		return <-job.status
+	}
+}
+
+// Puts job into async dispatch queue according to configured policy.
+// Returns false when job couldn't be queued.
+func (w *Webhook) enqueue(ctx context.Context, job webhookJob) bool {
+	defer func() { w.sink.Gauge(MetricWebhookQueueDepth, float64(len(w.queue))) }()
+
+	// Held while queueing, so abandon can wait for jobs queued right before it.
+	w.queueMu.RLock()
+	defer w.queueMu.RUnlock()
+
+	if w.isAbandoned() {
+		return false
+	}
+
+	switch w.queuePolicy {
+	case QueueReject:
+		select {
+		case w.queue <- job:
+			return true
+		default:
+			w.tracef("Dispatch queue is full, rejecting delivery")
+			return false
+		}
+	case QueueDropOldest:
+		for {
+			select {
+			case w.queue <- job:
+				return true
+			default:
+			}
+
+			select {
+			case dropped := <-w.queue:
+				if dropped.status != nil {
+					dropped.status <- http.StatusServiceUnavailable
+				}
+
+				w.metrics.dropped.Add(1)
+				w.sink.Count(MetricWebhookDropped, 1)
+				w.tracef("Dispatch queue is full, dropped oldest delivery")
+			default:
+			}
+		}
+	default:
+		select {
+		case w.queue <- job:
+			return true
+		case <-ctx.Done():
+			return false
+		case <-w.abandoned:
+			return false
+		}
+	}
+}
+
+func (w *Webhook) isAbandoned() bool {
+	select {
+	case <-w.abandoned:
+		return true
+	default:
+		return false
+	}
+}
+
+// Gives up on queued jobs once Shutdown deadline passes, settling them with shelve. Workers stop
+// after callbacks they're running return and no job is queued from now on.
+func (w *Webhook) abandon() {
+	w.abandonOnce.Do(func() {
+		close(w.abandoned)
+
+		// Waits for jobs being queued, later ones see abandoned channel and fail.
+		w.queueMu.Lock()
+		w.queueMu.Unlock()
+
+		for {
+			select {
+			case job, ok := <-w.queue:
+				if !ok {
+					return
+				}
+				w.shelve(job)
+			default:
+				return
+			}
+		}
+	})
+}
+
+// Settles job that won't run. Handler waiting for it answers 503, so Top.gg redelivers it.
+// Acknowledged vote is saved to DrainStore, other acknowledged jobs are lost.
+func (w *Webhook) shelve(job webhookJob) {
+	if job.taken != nil {
+		if job.taken.CompareAndSwap(false, true) {
+			job.status <- http.StatusServiceUnavailable
+		}
+		return
+	}
+
+	if job.vote != nil && w.drainStore != nil {
+		err := w.storeVote(context.Background(), w.drainStore, *job.vote)
+		if err == nil {
+			w.tracef("Saved queued vote of user %s on shutdown", job.vote.User.PlatformID)
+			return
+		}
+
+		w.tracef("Failed to save queued vote of user %s on shutdown: %v", job.vote.User.PlatformID, err)
+	}
+
+	w.metrics.dropped.Add(1)
+	w.sink.Count(MetricWebhookDropped, 1)
+	w.tracef("Dropped queued %s delivery on shutdown", job.scope)
+}
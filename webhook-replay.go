@@ -0,0 +1,50 @@
+package topgg
+
+import (
+	"sync"
+	"time"
+)
+
+// Remembers deliveries seen within timestamp window. Older deliveries are rejected
+// by timestamp check anyway, so entries are dropped once they fall out of it.
+type replayCache struct {
+	seen   *expiringMap[string, struct{}] // Delivery keys, expiring when they stop being accepted.
+	window time.Duration
+	mu     sync.Mutex
+}
+
+func newReplayCache(window time.Duration, maxEntries int) *replayCache {
+	return &replayCache{
+		seen:   newExpiringMap[string, struct{}](maxEntries),
+		window: window,
+	}
+}
+
+// Marks delivery as seen, returns false if it already was.
+func (c *replayCache) claim(key string, signedAt time.Time) bool {
+	now := time.Now()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if _, ok := c.seen.get(key, now); ok {
+		return false
+	}
+
+	c.seen.set(key, struct{}{}, signedAt.Add(c.window), now)
+	return true
+}
+
+func (c *replayCache) memoryStats() MemoryStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.seen.memoryStats()
+}
+
+// Forgets delivery, so its redelivery is processed again.
+func (c *replayCache) release(key string) {
+	c.mu.Lock()
+	c.seen.delete(key)
+	c.mu.Unlock()
+}
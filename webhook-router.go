@@ -0,0 +1,157 @@
+package topgg
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// Serves webhooks of several projects from a single endpoint, e.g. when hosting multiple listed bots
+// behind one domain. Each delivery is forwarded to the Webhook registered for project it belongs to,
+// which then verifies it with its own secret and runs its own callbacks.
+type WebhookRouter struct {
+	projects    map[Snowflake]*Webhook
+	ipLimiter   *ipRateLimiter // Most permissive limit of registered webhooks, applied before body is read.
+	webhooks    []*Webhook
+	maxBodySize int64
+	mu          sync.RWMutex
+}
+
+func NewWebhookRouter() *WebhookRouter {
+	return &WebhookRouter{
+		projects:    make(map[Snowflake]*Webhook),
+		ipLimiter:   newIPRateLimiter(0),
+		maxBodySize: defaultMaxBodySize,
+	}
+}
+
+// Registers webhook handling deliveries of given project (Top.gg project ID, not platform ID).
+func (wr *WebhookRouter) Handle(projectID Snowflake, w *Webhook) {
+	wr.mu.Lock()
+	defer wr.mu.Unlock()
+
+	if previous, ok := wr.projects[projectID]; ok {
+		for i, registered := range wr.webhooks {
+			if registered == previous {
+				wr.webhooks = append(wr.webhooks[:i], wr.webhooks[i+1:]...)
+				break
+			}
+		}
+	}
+
+	wr.projects[projectID] = w
+	wr.webhooks = append(wr.webhooks, w)
+
+	// Limits are recomputed, as replaced webhook may have been the one setting them.
+	wr.maxBodySize = 0
+	limit := wr.webhooks[0].ipLimiter.limit
+	for _, registered := range wr.webhooks {
+		if registered.maxBodySize > wr.maxBodySize {
+			wr.maxBodySize = registered.maxBodySize
+		}
+
+		// Webhook without rate limit disables the shared one, so router doesn't limit its deliveries.
+		if registered.ipLimiter.limit <= 0 {
+			limit = 0
+		} else if limit > 0 && registered.ipLimiter.limit > limit {
+			limit = registered.ipLimiter.limit
+		}
+	}
+	wr.ipLimiter.setLimit(limit)
+}
+
+// Routes delivery by its project ID. Events without project (e.g. integration.delete)
+// go to the first webhook whose secret matches the signature.
+func (wr *WebhookRouter) ServeHTTP(rw http.ResponseWriter, r *http.Request) {
+	wr.mu.RLock()
+	maxBodySize := wr.maxBodySize
+	var front *Webhook
+	if len(wr.webhooks) > 0 {
+		front = wr.webhooks[0]
+	}
+	wr.mu.RUnlock()
+
+	if r.Method != http.MethodPost {
+		wr.reject(front, rw, r, RejectMethodNotAllowed, http.StatusMethodNotAllowed)
+		return
+	}
+
+	if front != nil {
+		if ip := front.ClientIP(r); !wr.ipLimiter.allow(ip) {
+			front.tracef("Router rejected request from %s, exceeded inbound rate limit", ip)
+			wr.reject(front, rw, r, RejectRateLimited, http.StatusTooManyRequests)
+			return
+		}
+	}
+
+	if r.ContentLength > maxBodySize {
+		wr.reject(front, rw, r, RejectOversized, http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxBodySize+1))
+	if err != nil {
+		wr.reject(front, rw, r, RejectBadRequest, http.StatusBadRequest)
+		return
+	}
+
+	if int64(len(body)) > maxBodySize {
+		wr.reject(front, rw, r, RejectOversized, http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	w := wr.route(r, body)
+	if w == nil {
+		wr.reject(front, rw, r, RejectUnknownProject, http.StatusNotFound)
+		return
+	}
+
+	r.Body = io.NopCloser(bytes.NewReader(body))
+	w.ServeHTTP(rw, r)
+}
+
+// Rejects delivery that couldn't be routed yet. It's reported through the first registered webhook
+// (OnRejected, audit log, metrics), as it isn't known which project it belongs to.
+func (wr *WebhookRouter) reject(front *Webhook, rw http.ResponseWriter, r *http.Request, reason RejectionReason, status int) {
+	if front == nil {
+		http.Error(rw, strings.ToLower(http.StatusText(status)), status)
+		return
+	}
+
+	front.track(rw, r, func(rw http.ResponseWriter, r *http.Request) {
+		front.reject(rw, r, reason, status)
+	})
+}
+
+func (wr *WebhookRouter) route(r *http.Request, body []byte) *Webhook {
+	var payload struct {
+		Data struct {
+			Project *struct {
+				ID Snowflake `json:"id"`
+			} `json:"project"`
+		} `json:"data"`
+	}
+
+	wr.mu.RLock()
+	defer wr.mu.RUnlock()
+
+	if len(wr.webhooks) == 0 {
+		return nil
+	}
+
+	// Project ID isn't trusted, selected webhook still verifies the signature.
+	// Webhooks are usually created by one client, so codec of any of them will do.
+	if err := wr.webhooks[0].codec.Unmarshal(body, &payload); err == nil && payload.Data.Project != nil {
+		return wr.projects[payload.Data.Project.ID]
+	}
+
+	for _, w := range wr.webhooks {
+		if _, _, err := w.validateV1(w.signature(r), body); err == nil {
+			return w
+		}
+	}
+
+	return nil
+}
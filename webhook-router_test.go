@@ -0,0 +1,138 @@
+package topgg
+
+import (
+	"io"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// Body recording whether anyone read from it.
+type trackedBody struct {
+	io.Reader
+	read atomic.Bool
+}
+
+func (b *trackedBody) Read(p []byte) (int, error) {
+	b.read.Store(true)
+	return b.Reader.Read(p)
+}
+
+func (b *trackedBody) Close() error { return nil }
+
+// Codec counting decoded payloads.
+type countingCodec struct {
+	stdCodec
+	unmarshals atomic.Int64
+}
+
+func (c *countingCodec) Unmarshal(data []byte, v any) error {
+	c.unmarshals.Add(1)
+	return c.stdCodec.Unmarshal(data, v)
+}
+
+func TestWebhookRouter(t *testing.T) {
+	tests := []struct {
+		request      func(t *testing.T) *http.Request
+		name         string
+		wantReasons  []RejectionReason
+		want         []int     // Status of each delivery of the request.
+		project      Snowflake // Project webhook is registered for, deliveries are sent for project 0.
+		noWebhook    bool
+		wantBodyRead bool
+	}{
+		{
+			name: "routes signed delivery",
+			request: func(t *testing.T) *http.Request {
+				return signedRequest(t, testSecret, voteBody(1), time.Now())
+			},
+			want:         []int{http.StatusOK},
+			wantBodyRead: true,
+		},
+		{
+			name: "rejected method is reported",
+			request: func(t *testing.T) *http.Request {
+				req := signedRequest(t, testSecret, voteBody(1), time.Now())
+				req.Method = http.MethodGet
+				return req
+			},
+			want:        []int{http.StatusMethodNotAllowed},
+			wantReasons: []RejectionReason{RejectMethodNotAllowed},
+		},
+		{
+			name: "rate limit applies before body is read",
+			request: func(t *testing.T) *http.Request {
+				return signedRequest(t, testSecret, voteBody(1), time.Now())
+			},
+			want:        []int{http.StatusOK, http.StatusOK, http.StatusTooManyRequests},
+			wantReasons: []RejectionReason{RejectRateLimited},
+		},
+		{
+			name:      "router without webhooks reads default body size",
+			noWebhook: true,
+			request: func(t *testing.T) *http.Request {
+				return signedRequest(t, testSecret, voteBody(1), time.Now())
+			},
+			want:         []int{http.StatusNotFound},
+			wantBodyRead: true,
+		},
+		{
+			name:    "unknown project is reported",
+			project: 7,
+			request: func(t *testing.T) *http.Request {
+				return signedRequest(t, testSecret, voteBody(1), time.Now())
+			},
+			want:         []int{http.StatusNotFound},
+			wantReasons:  []RejectionReason{RejectUnknownProject},
+			wantBodyRead: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			codec := &countingCodec{}
+			var reasons []RejectionReason
+			router := NewWebhookRouter()
+			if !tt.noWebhook {
+				router.Handle(tt.project, NewClient(ClientOptions{Codec: codec}).NewWebhookHandler(WebhookOptions{
+					Secret:           testSecret,
+					MaxRequestsPerIP: 2,
+					OnRejected:       func(reason RejectionReason, r *http.Request) { reasons = append(reasons, reason) },
+				}))
+			}
+
+			var body *trackedBody
+			for i, want := range tt.want {
+				req := tt.request(t)
+				raw, _ := io.ReadAll(req.Body)
+				body = &trackedBody{Reader: strings.NewReader(string(raw))}
+				req.Body = body
+
+				if got := deliver(router, req); got != want {
+					t.Fatalf("delivery %d got %d, want %d", i, got, want)
+				}
+			}
+
+			if read := body.read.Load(); read != tt.wantBodyRead {
+				t.Errorf("body of last delivery read: %v, want %v", read, tt.wantBodyRead)
+			}
+
+			if len(reasons) != len(tt.wantReasons) {
+				t.Fatalf("OnRejected got %v, want %v", reasons, tt.wantReasons)
+			}
+
+			for i, reason := range tt.wantReasons {
+				if reasons[i] != reason {
+					t.Errorf("OnRejected got %v, want %v", reasons, tt.wantReasons)
+				}
+			}
+
+			// Router sniffs the project, then webhook decodes base payload (data isn't decoded without OnVote).
+			if tt.wantBodyRead && !tt.noWebhook && tt.project == 0 && codec.unmarshals.Load() != 2 {
+				t.Errorf("codec decoded %d payloads, want 2", codec.unmarshals.Load())
+			}
+		})
+	}
+}
@@ -0,0 +1,79 @@
+package topgg
+
+import (
+	"crypto/hmac"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Returns x-topgg-signature header value for body signed with secret at given time, the way Top.gg signs
+// deliveries. Useful for relays forwarding deliveries to another Webhook, or for tests.
+func SignWebhook(secret string, body []byte, at time.Time) (string, error) {
+	timestamp := strconv.FormatInt(at.Unix(), 10)
+	digest, err := signV1(secret, timestamp, body)
+	if err != nil {
+		return "", err
+	}
+
+	return "t=" + timestamp + ",v1=" + digest, nil
+}
+
+// Like SignWebhook, but also names the key used, so receiving hop can pick it among several (e.g. during rotation).
+// The result is still accepted by Webhook configured with the same secret, which ignores the kid part.
+func SignRelay(keyID, secret string, body []byte, at time.Time) (string, error) {
+	if keyID == "" || strings.ContainsAny(keyID, ",=") {
+		return "", fmt.Errorf("invalid key ID %q", keyID)
+	}
+
+	timestamp := strconv.FormatInt(at.Unix(), 10)
+	digest, err := signV1(secret, timestamp, body)
+	if err != nil {
+		return "", err
+	}
+
+	return "t=" + timestamp + ",kid=" + keyID + ",v1=" + digest, nil
+}
+
+// Verifies signature produced by SignRelay against secret of its key ID and returns that ID.
+// Signatures older or newer than window are rejected, 0 disables the check.
+func VerifyRelay(signature string, body []byte, keys map[string]string, window time.Duration) (string, error) {
+	parts := make(map[string]string)
+	for _, part := range strings.Split(signature, ",") {
+		if k, v, ok := strings.Cut(part, "="); ok {
+			parts[k] = v
+		}
+	}
+
+	timestamp, keyID, digest := parts["t"], parts["kid"], parts["v1"]
+	if timestamp == "" || keyID == "" || digest == "" {
+		return "", errors.New("invalid signature format")
+	}
+
+	unix, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return "", errors.New("invalid timestamp format")
+	}
+
+	if age := time.Since(time.Unix(unix, 0)); window > 0 && (age > window || age < -window) {
+		return "", errors.New("timestamp outside of accepted time window")
+	}
+
+	secret, ok := keys[keyID]
+	if !ok {
+		return "", fmt.Errorf("unknown key ID %q", keyID)
+	}
+
+	expected, err := signV1(secret, timestamp, body)
+	if err != nil {
+		return "", err
+	}
+
+	if !hmac.Equal([]byte(digest), []byte(expected)) {
+		return "", errors.New("signature mismatch")
+	}
+
+	return keyID, nil
+}
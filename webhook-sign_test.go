@@ -0,0 +1,98 @@
+package topgg
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestVerifyRelay(t *testing.T) {
+	body := voteBody(1)
+	keys := map[string]string{"2026-01": "old-secret", "2026-02": testSecret}
+	now := time.Now()
+
+	sign := func(t *testing.T, keyID, secret string, at time.Time) string {
+		t.Helper()
+		signature, err := SignRelay(keyID, secret, body, at)
+		if err != nil {
+			t.Fatalf("SignRelay: %v", err)
+		}
+
+		return signature
+	}
+
+	tests := []struct {
+		signature func(t *testing.T) string
+		name      string
+		wantKeyID string
+		body      []byte
+		window    time.Duration
+		wantErr   bool
+	}{
+		{name: "round trip", signature: func(t *testing.T) string { return sign(t, "2026-02", testSecret, now) }, body: body, window: time.Minute, wantKeyID: "2026-02"},
+		{name: "rotated key", signature: func(t *testing.T) string { return sign(t, "2026-01", "old-secret", now) }, body: body, window: time.Minute, wantKeyID: "2026-01"},
+		{name: "wrong key", signature: func(t *testing.T) string { return sign(t, "2026-02", "other-secret", now) }, body: body, window: time.Minute, wantErr: true},
+		{name: "unknown kid", signature: func(t *testing.T) string { return sign(t, "2025-12", testSecret, now) }, body: body, window: time.Minute, wantErr: true},
+		{name: "tampered body", signature: func(t *testing.T) string { return sign(t, "2026-02", testSecret, now) }, body: voteBody(2), window: time.Minute, wantErr: true},
+		{name: "too old", signature: func(t *testing.T) string { return sign(t, "2026-02", testSecret, now.Add(-2*time.Minute)) }, body: body, window: time.Minute, wantErr: true},
+		{name: "too new", signature: func(t *testing.T) string { return sign(t, "2026-02", testSecret, now.Add(2*time.Minute)) }, body: body, window: time.Minute, wantErr: true},
+		{name: "window disabled", signature: func(t *testing.T) string { return sign(t, "2026-02", testSecret, now.Add(-time.Hour)) }, body: body, wantKeyID: "2026-02"},
+		{name: "missing kid", signature: func(t *testing.T) string {
+			signature, _ := SignWebhook(testSecret, body, now)
+			return signature
+		}, body: body, window: time.Minute, wantErr: true},
+		{name: "garbage", signature: func(t *testing.T) string { return "t=abc,kid=2026-02,v1=00" }, body: body, window: time.Minute, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			keyID, err := VerifyRelay(tt.signature(t), tt.body, keys, tt.window)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("signature was accepted with key %q", keyID)
+				}
+				return
+			}
+
+			if err != nil || keyID != tt.wantKeyID {
+				t.Fatalf("VerifyRelay = %q, %v; want key %q", keyID, err, tt.wantKeyID)
+			}
+		})
+	}
+}
+
+func TestSignRelayRejectsInvalidKeyID(t *testing.T) {
+	for _, keyID := range []string{"", "a,b", "a=b"} {
+		if _, err := SignRelay(keyID, testSecret, voteBody(1), time.Now()); err == nil {
+			t.Errorf("key ID %q was accepted", keyID)
+		}
+	}
+}
+
+func TestWebhookAcceptsSignedDeliveries(t *testing.T) {
+	body := voteBody(1)
+	relay, err := SignRelay("2026-02", testSecret, body, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	direct, err := SignWebhook(testSecret, body, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for name, signature := range map[string]string{"SignWebhook": direct, "SignRelay": relay} {
+		t.Run(name, func(t *testing.T) {
+			w := NewClient(ClientOptions{}).NewWebhookHandler(WebhookOptions{Secret: testSecret})
+			req := httptest.NewRequest(http.MethodPost, "/webhook", strings.NewReader(string(body)))
+			req.Header.Set("Content-Type", "application/json")
+			req.Header.Set("x-topgg-signature", signature)
+
+			if status := deliver(w, req); status != http.StatusOK {
+				t.Fatalf("got status %d, want 200", status)
+			}
+		})
+	}
+}
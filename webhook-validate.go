@@ -0,0 +1,47 @@
+package topgg
+
+import (
+	_ "embed"
+	"fmt"
+)
+
+// OpenAPI 3 description of webhook deliveries accepted by Webhook, e.g. for validating them in own proxies.
+//
+//go:embed webhook-openapi.yaml
+var WebhookOpenAPISpec string
+
+// Checks that delivery body matches the schema of WebhookOpenAPISpec, without verifying its signature.
+// Unknown or missing fields, wrong types and unknown event types are reported as ErrSchemaMismatch.
+// Webhook runs the same check on every delivery when WebhookOptions.StrictDecoding is set.
+func ValidateWebhookPayload(body []byte) error {
+	var payload WebhookPayload
+	if err := decodeJSON(stdCodec{}, body, &payload, true); err != nil {
+		return err
+	}
+
+	_, err := decodeWebhookData(stdCodec{}, payload, true)
+	return err
+}
+
+// Decodes data of payload into struct of its type, e.g. *VoteCreatePayload. Unknown types are reported as ErrSchemaMismatch.
+func decodeWebhookData(codec Codec, payload WebhookPayload, strict bool) (any, error) {
+	var data any
+	switch payload.Type {
+	case ScopeVoteCreate:
+		data = &VoteCreatePayload{}
+	case ScopeWebhookTest:
+		data = &WebhookTestPayload{}
+	case ScopeIntegrationCreate:
+		data = &IntegrationCreatePayload{}
+	case ScopeIntegrationDelete:
+		data = &IntegrationDeletePayload{}
+	default:
+		return nil, fmt.Errorf("%w: unknown type %q", ErrSchemaMismatch, payload.Type)
+	}
+
+	if err := decodeJSON(codec, payload.Data, data, strict); err != nil {
+		return nil, fmt.Errorf("%s: %w", payload.Type, err)
+	}
+
+	return data, nil
+}
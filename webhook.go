@@ -1,6 +1,8 @@
 package topgg
 
 import (
+	"bytes"
+	"context"
 	"crypto/hmac"
 	"crypto/sha256"
 	"encoding/hex"
@@ -8,7 +10,10 @@ import (
 	"fmt"
 	"io"
 	"log"
+	"mime"
+	"net"
 	"net/http"
+	"net/url"
 	"strconv"
 	"strings"
 	"sync"
@@ -25,6 +30,38 @@ const (
 	ScopeIntegrationDelete Scope = "integration.delete"
 )
 
+// Reports whether scope is one of constants above.
+func (s Scope) Known() bool {
+	switch s {
+	case ScopeVoteCreate, ScopeWebhookTest, ScopeIntegrationCreate, ScopeIntegrationDelete:
+		return true
+	default:
+		return false
+	}
+}
+
+// Reports whether delivery is a test sent from Top.gg dashboard rather than a real event.
+func (s Scope) IsTest() bool {
+	return s == ScopeWebhookTest
+}
+
+// Why webhook delivery was rejected, passed to WebhookOptions.OnRejected.
+type RejectionReason string
+
+const (
+	RejectMethodNotAllowed     RejectionReason = "method_not_allowed"
+	RejectRateLimited          RejectionReason = "rate_limited"           // Exceeded MaxRequestsPerIP.
+	RejectUnsupportedMediaType RejectionReason = "unsupported_media_type" // Content-Type isn't application/json, only with RequireContentType.
+	RejectOversized            RejectionReason = "oversized"              // Body exceeded MaxBodySize.
+	RejectBadRequest           RejectionReason = "bad_request"
+	RejectBadAuth              RejectionReason = "bad_auth"        // Missing, malformed, expired or invalid signature.
+	RejectBadJSON              RejectionReason = "bad_json"        // Signature was valid, but payload couldn't be decoded.
+	RejectUnavailable          RejectionReason = "unavailable"     // Shutting down or dispatch queue is full, Top.gg retries such deliveries.
+	RejectCallbackFailed       RejectionReason = "callback_failed" // Callback panicked.
+	RejectDuplicate            RejectionReason = "duplicate"       // Replay of already processed delivery, acknowledged with 200 without running callbacks.
+	RejectUnknownProject       RejectionReason = "unknown_project" // WebhookRouter has no webhook for project of delivery.
+)
+
 // Represents a minimal project object from a webhook payload
 type PartialProject struct {
 	Type       ProjectType `json:"type"`
@@ -63,6 +100,36 @@ type VoteCreatePayload struct {
 	Weight    int               `json:"weight"`
 }
 
+// Reports whether vote counts more than once (Weight above 1), as votes cast on weekends do.
+func (v VoteCreatePayload) IsWeekend() bool {
+	return v.Weight > 1
+}
+
+// Returns query parameters Top.gg forwarded from vote page URL (e.g. "?ref=discord"),
+// so referral and campaign attribution can use usual url.Values accessors.
+func (v VoteCreatePayload) QueryValues() url.Values {
+	values := make(url.Values, len(v.Query))
+	for key, value := range v.Query {
+		values.Set(key, value)
+	}
+
+	return values
+}
+
+// Set of expected vote query parameters, nil when all are kept.
+func queryParamSet(params []string) map[string]struct{} {
+	if len(params) == 0 {
+		return nil
+	}
+
+	set := make(map[string]struct{}, len(params))
+	for _, param := range params {
+		set[param] = struct{}{}
+	}
+
+	return set
+}
+
 // https://docs.top.gg/webhooks/events#webhook-test
 type WebhookTestPayload struct {
 	Project PartialProject `json:"project"`
@@ -74,13 +141,36 @@ type WebhookPayload struct {
 	Data json.RawMessage `json:"data"`
 }
 
+// Default WebhookOptions.MaxBodySize.
+const defaultMaxBodySize = 2 * 1024 * 1024
+
 type WebhookOptions struct {
 	OnVote              func(vote VoteCreatePayload)
 	OnIntegrationCreate func(integration IntegrationCreatePayload)
 	OnIntegrationDelete func(integration IntegrationDeletePayload)
 	OnTest              func(test WebhookTestPayload)
+	OnRejected          func(reason RejectionReason, r *http.Request) // Called for every rejected delivery, e.g. to tell attacks apart from Top.gg side changes. Request body is already consumed.
+	OnError             func(err error)                               // Called with *PanicError when a callback panics. Delivery is answered with 500, so Top.gg retries it.
+	AuditLog            AuditLog                                      // Receives record of every delivery, e.g. MemoryAuditLog or JSONAuditLog, to investigate votes that didn't count.
+	ReplayStore         VoteStore                                     // Rejects vote.create deliveries whose (user, vote time) pair is already stored, so replays are caught across restarts and replicas. See RejectReplays.
+	DrainStore          VoteStore                                     // Receives acknowledged votes still queued when Shutdown deadline passes, e.g. store of VoteChecker, so they still count after restart.
 	Secret              string
+	SignatureHeader     string   // Header carrying the signature, defaults to "x-topgg-signature". Useful when reverse proxy renames headers.
+	SignatureQueryParam string   // Query parameter used as fallback when signature header is missing, disabled when empty.
+	Secrets             []string // Additional accepted secrets, e.g. to rotate secret without rejecting deliveries in between.
+	QueryParams         []string // Vote page query parameters (e.g. "ref", "utm_campaign") kept in VoteCreatePayload.Query, others are dropped before OnVote. Empty keeps all of them.
+	TrustedProxies      []string // CIDRs (or single IPs) of reverse proxies whose X-Forwarded-For/X-Real-IP headers are honoured when resolving client IP. Invalid entries are skipped with a trace message.
 	TimestampWindow     time.Duration
+	MaxBodySize         int64       // Larger deliveries are rejected with 413 before being read, defaults to 2MB.
+	Workers             int         // Number of goroutines running callbacks from async dispatch queue, 0 runs them within request.
+	QueueSize           int         // Capacity of async dispatch queue, defaults to 100. Ignored when Workers is 0.
+	MaxReplayEntries    int         // Caps deliveries remembered by RejectReplays, evicting oldest ones first. 0 means no limit besides TimestampWindow.
+	MaxRequestsPerIP    int         // Limit of requests per second from a single IP address, 0 disables inbound rate limiting.
+	QueuePolicy         QueuePolicy // What to do with deliveries when async dispatch queue is full.
+	AckMode             AckMode     // When deliveries are acknowledged, relative to running callbacks.
+	StrictDecoding      bool        // Rejects payloads with unknown or missing fields as bad JSON, see WithStrictDecoding.
+	RequireContentType  bool        // Rejects deliveries whose Content-Type isn't application/json with 415. Otherwise they're only traced.
+	RejectReplays       bool        // Remembers processed deliveries within TimestampWindow so repeated ones don't run callbacks again, hardening setups where secret might have leaked. Kept in memory, so use ReplayStore to cover restarts and replicas.
 }
 
 type Webhook struct {
@@ -88,26 +178,267 @@ type Webhook struct {
 	onIntegrationCreate func(integration IntegrationCreatePayload)
 	onIntegrationDelete func(integration IntegrationDeletePayload)
 	onTest              func(test WebhookTestPayload)
+	onRejected          func(reason RejectionReason, r *http.Request)
+	onError             func(err error)
 	traceLogger         *log.Logger
-	secret              string
+	metrics             *webhookMetrics
+	counters            *expvarCounters
+	sink                MetricsSink
+	codec               Codec
+	replays             *replayCache
+	auditLog            AuditLog
+	replayStore         VoteStore
+	drainStore          VoteStore
+	queue               chan webhookJob
+	abandoned           chan struct{} // Closed when Shutdown gives up on queued jobs.
+	ipLimiter           *ipRateLimiter
+	queryParams         map[string]struct{} // Nil keeps all parameters.
+	signatureHeader     string
+	signatureQueryParam string
+	secrets             []string
+	integrationSecret   string // Last secret added by integration.create, replaced by the next one.
+	trustedProxies      []*net.IPNet
+	handlers            drainGroup // Requests being served.
+	pending             drainGroup // Workers and detached callbacks.
 	timestampWindow     time.Duration
+	voteValidity        time.Duration // Fallback expiry of stored votes missing ExpiresAt.
+	maxBodySize         int64
+	closeOnce           sync.Once
+	abandonOnce         sync.Once
 	secretMu            sync.RWMutex
+	queueMu             sync.RWMutex
+	strictDecoding      bool
+	requireContentType  bool
+	queuePolicy         QueuePolicy
+	ackMode             AckMode
 }
 
 func (w *Webhook) tracef(format string, v ...any) {
 	w.traceLogger.Printf("[WEBHOOK] "+format, v...)
 }
 
+// Records status code written by handler.
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
+}
+
+// Returns delivery counters and callback latency of this handler.
+func (w *Webhook) Stats() WebhookStats {
+	return WebhookStats{
+		HandlerLatency: w.metrics.handlerLatency.Snapshot(),
+		Received:       w.metrics.received.Load(),
+		Accepted:       w.metrics.accepted.Load(),
+		Rejected:       w.metrics.rejected.Load(),
+		Dropped:        w.metrics.dropped.Load(),
+		QueueDepth:     len(w.queue),
+	}
+}
+
+// Reports memory used by replay protection, zero when RejectReplays isn't set.
+func (w *Webhook) MemoryStats() MemoryStats {
+	if w.replays == nil {
+		return MemoryStats{}
+	}
+
+	return w.replays.memoryStats()
+}
+
+// Replaces accepted secrets. Deliveries signed with any of them are accepted, which allows for
+// zero-downtime rotation: add new secret, update it on Top.gg dashboard, then remove the old one.
+func (w *Webhook) SetSecrets(secrets ...string) {
+	accepted := make([]string, 0, len(secrets))
+	for _, secret := range secrets {
+		if secret != "" {
+			accepted = append(accepted, secret)
+		}
+	}
+
+	w.secretMu.Lock()
+	w.secrets = accepted
+	w.integrationSecret = ""
+	w.secretMu.Unlock()
+}
+
+// Accepts secret on top of current ones, trying it first. Empty and already accepted secrets are ignored.
+// Secret added by previous call is dropped, so repeated integration.create events can't grow the list.
+func (w *Webhook) addSecret(secret string) {
+	if secret == "" {
+		return
+	}
+
+	w.secretMu.Lock()
+	defer w.secretMu.Unlock()
+
+	// Copied, as validateV1 reads the slice outside of the lock.
+	accepted := make([]string, 1, len(w.secrets)+1)
+	accepted[0] = secret
+	for _, current := range w.secrets {
+		if current == secret {
+			return
+		}
+
+		if current != w.integrationSecret {
+			accepted = append(accepted, current)
+		}
+	}
+
+	w.secrets = accepted
+	w.integrationSecret = secret
+}
+
+// Runs user callback of given scope, measuring time spent in it.
+// Returns false if callback panicked, so a single bad delivery can't crash worker goroutines.
+func (w *Webhook) invoke(scope Scope, callback func()) bool {
+	start := time.Now()
+	err := safeCall("webhook", string(scope), callback)
+	latency := time.Since(start)
+	w.metrics.handlerLatency.Observe(latency)
+	w.sink.Timing(MetricWebhookLatency, latency)
+
+	if err == nil {
+		return true
+	}
+
+	w.panicked(err)
+	return false
+}
+
+// Reports panic recovered from user callback to OnError.
+func (w *Webhook) panicked(err error) {
+	w.tracef("Recovered from panic in webhook callback: %v", err)
+	if w.onError != nil {
+		if err := safeCall("webhook", "OnError", func() { w.onError(err) }); err != nil {
+			w.tracef("%v", err)
+		}
+	}
+}
+
 // Handles modern v1 (x-topgg-signature HMAC) webhooks.
 func (w *Webhook) ServeHTTP(rw http.ResponseWriter, r *http.Request) {
+	w.track(rw, r, func(rw http.ResponseWriter, r *http.Request) {
+		if !w.handlers.enter() {
+			w.reject(rw, r, RejectUnavailable, http.StatusServiceUnavailable)
+			return
+		}
+		defer w.handlers.leave()
+
+		w.serve(rw, r)
+	})
+}
+
+// Counts delivery handled by handle in stats and metrics, and records it in audit log.
+func (w *Webhook) track(rw http.ResponseWriter, r *http.Request, handle func(rw http.ResponseWriter, r *http.Request)) {
+	w.metrics.received.Add(1)
+	recorder := &statusRecorder{ResponseWriter: rw, status: http.StatusOK}
+
+	var record *DeliveryRecord
+	if w.auditLog != nil {
+		record = &DeliveryRecord{At: time.Now(), IP: w.ClientIP(r)}
+		r = r.WithContext(context.WithValue(r.Context(), deliveryRecordKey{}, record))
+	}
+
+	defer func() {
+		w.sink.Count(MetricWebhookRequests, 1, "status:"+strconv.Itoa(recorder.status))
+		if recorder.status < http.StatusMultipleChoices {
+			w.metrics.accepted.Add(1)
+		} else {
+			w.metrics.rejected.Add(1)
+		}
+
+		if record != nil {
+			record.Status = recorder.status
+			if err := w.auditLog.Append(context.Background(), *record); err != nil {
+				w.tracef("Failed to append delivery to audit log: %v", err)
+			}
+		}
+	}()
+
+	handle(recorder, r)
+}
+
+// Stops accepting new deliveries (responding with 503, so Top.gg retries them later) and waits
+// until queued and in-flight callbacks finish. When ctx is done first, deliveries still queued are
+// given up on: those not acknowledged yet are answered with 503, acknowledged votes are saved to
+// DrainStore and other acknowledged deliveries are lost. Callbacks already running aren't interrupted.
+func (w *Webhook) Shutdown(ctx context.Context) error {
+	w.handlers.close()
+
+	select {
+	case <-w.handlers.wait():
+		// No handler is left to put jobs into the queue.
+		w.closeOnce.Do(func() {
+			if w.queue != nil {
+				close(w.queue)
+			}
+		})
+	case <-ctx.Done():
+		w.giveUp()
+		return ctx.Err()
+	}
+
+	select {
+	case <-w.pending.wait():
+		return nil
+	case <-ctx.Done():
+		w.giveUp()
+		return ctx.Err()
+	}
+}
+
+func (w *Webhook) giveUp() {
+	if depth := len(w.queue); depth > 0 {
+		w.tracef("Shutdown deadline exceeded with %d deliveries left in queue", depth)
+	}
+
+	w.abandon()
+}
+
+func (w *Webhook) serve(rw http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
-		http.Error(rw, "method not allowed", http.StatusMethodNotAllowed)
+		w.reject(rw, r, RejectMethodNotAllowed, http.StatusMethodNotAllowed)
 		return
 	}
 
-	body, err := io.ReadAll(io.LimitReader(r.Body, 2*1024*1024)) // 2MB limit
-	if err != nil {
-		http.Error(rw, "bad request", http.StatusBadRequest)
+	if !w.allowIP(r) {
+		w.reject(rw, r, RejectRateLimited, http.StatusTooManyRequests)
+		return
+	}
+
+	if mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type")); err != nil || mediaType != "application/json" {
+		if w.requireContentType {
+			w.reject(rw, r, RejectUnsupportedMediaType, http.StatusUnsupportedMediaType)
+			return
+		}
+
+		// Signature check decides, since proxies in front of the handler may drop or rewrite the header.
+		w.tracef("Accepting delivery with Content-Type %q, expected application/json", r.Header.Get("Content-Type"))
+	}
+
+	if r.ContentLength > w.maxBodySize {
+		w.reject(rw, r, RejectOversized, http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	buf := bodyBufferPool.Get().(*bytes.Buffer)
+	defer releaseBodyBuffer(buf)
+
+	buf.Reset()
+	if _, err := buf.ReadFrom(io.LimitReader(r.Body, w.maxBodySize+1)); err != nil {
+		w.reject(rw, r, RejectBadRequest, http.StatusBadRequest)
+		return
+	}
+
+	// Body must not outlive serve, everything callbacks get is decoded into own memory.
+	body := buf.Bytes()
+
+	if int64(len(body)) > w.maxBodySize {
+		w.reject(rw, r, RejectOversized, http.StatusRequestEntityTooLarge)
 		return
 	}
 
@@ -117,91 +448,369 @@ func (w *Webhook) ServeHTTP(rw http.ResponseWriter, r *http.Request) {
 		}
 	}()
 
-	signatureHeader := r.Header.Get("x-topgg-signature")
+	signatureHeader := w.signature(r)
 	if signatureHeader == "" {
-		http.Error(rw, "unauthorized", http.StatusUnauthorized)
+		w.reject(rw, r, RejectBadAuth, http.StatusUnauthorized)
 		return
 	}
 
-	w.handleV1(rw, body, signatureHeader)
+	w.handleV1(rw, r, body, signatureHeader)
+}
+
+// Reuses delivery read buffers, so busy webhooks don't allocate new body for every vote.
+var bodyBufferPool = sync.Pool{
+	New: func() any { return new(bytes.Buffer) },
+}
+
+func releaseBodyBuffer(buf *bytes.Buffer) {
+	// Buffers grown by unusually big deliveries aren't kept around.
+	if buf.Cap() <= 64*1024 {
+		bodyBufferPool.Put(buf)
+	}
+}
+
+// Returns signature of the delivery from configured header or query parameter.
+func (w *Webhook) signature(r *http.Request) string {
+	signatureHeader := r.Header.Get(w.signatureHeader)
+	if signatureHeader == "" && w.signatureQueryParam != "" {
+		signatureHeader = r.URL.Query().Get(w.signatureQueryParam)
+	}
+
+	return signatureHeader
 }
 
 // Parses modern v1 Webhooks using HMAC verification and routes to callbacks.
-// The integration secret will automatically update in-memory upon integration.create events.
-func (w *Webhook) handleV1(rw http.ResponseWriter, body []byte, signatureHeader string) {
-	if err := w.validateV1(signatureHeader, body); err != nil {
+// Secret of integration.create events is added to accepted ones in-memory once the delivery is acknowledged.
+func (w *Webhook) handleV1(rw http.ResponseWriter, r *http.Request, body []byte, signatureHeader string) {
+	signedAt, deliveryKey, err := w.validateV1(signatureHeader, body)
+	if err != nil {
 		w.tracef("Failed to validate signature: %v", err)
-		http.Error(rw, "unauthorized", http.StatusUnauthorized)
+		w.reject(rw, r, RejectBadAuth, http.StatusUnauthorized)
 		return
 	}
 
+	if w.replays != nil {
+		if !w.replays.claim(deliveryKey, signedAt) {
+			// Acknowledged, so a legitimate redelivery of processed event isn't retried again.
+			w.tracef("Ignored replayed delivery signed at %s", signedAt.Format(time.RFC3339))
+			w.report(r, RejectDuplicate)
+			rw.WriteHeader(http.StatusOK)
+			return
+		}
+
+		recorder := &statusRecorder{ResponseWriter: rw, status: http.StatusOK}
+		rw = recorder
+		defer func() {
+			// Failed deliveries are retried by Top.gg, so they must not be treated as replays.
+			if recorder.status >= http.StatusMultipleChoices {
+				w.replays.release(deliveryKey)
+			}
+		}()
+	}
+
 	var payload WebhookPayload
-	if err := json.Unmarshal(body, &payload); err != nil {
+	if err := decodeJSON(w.codec, body, &payload, w.strictDecoding); err != nil {
 		w.tracef("Failed to unmarshal base payload: %v", err)
-		http.Error(rw, "bad request", http.StatusBadRequest)
+		w.reject(rw, r, RejectBadJSON, http.StatusBadRequest)
 		return
 	}
 
-	switch payload.Type {
-	case ScopeVoteCreate:
-		if w.onVote == nil {
-			break
-		}
+	record := deliveryRecordFrom(r)
+	if record != nil {
+		record.Scope = payload.Type
+	}
 
-		var vote VoteCreatePayload
-		if err := json.Unmarshal(payload.Data, &vote); err != nil {
+	if payload.Type == ScopeVoteCreate {
+		w.counters.add(ExpvarWebhookVotes)
+	}
+
+	// Decoded once, and only when something reads it. Strict mode always does, to check the schema.
+	var data any
+	if w.strictDecoding || w.readsData(payload.Type, record != nil) {
+		decoded, err := decodeWebhookData(w.codec, payload, w.strictDecoding)
+		if err != nil {
 			w.tracef("Failed to unmarshal %s payload: %v", payload.Type, err)
-			http.Error(rw, err.Error(), http.StatusBadRequest)
+			w.rejectWithMessage(rw, r, RejectBadJSON, http.StatusBadRequest, err.Error())
 			return
 		}
 
-		w.onVote(vote)
-	case ScopeIntegrationCreate:
-		var integration IntegrationCreatePayload
-		if err := json.Unmarshal(payload.Data, &integration); err != nil {
-			w.tracef("Failed to unmarshal %s payload: %v", payload.Type, err)
-			http.Error(rw, err.Error(), http.StatusBadRequest)
-			return
+		data = decoded
+	}
+
+	var (
+		callback func()
+		queued   *VoteCreatePayload // Vote of vote.create delivery with callback, see DrainStore.
+		accepted func()             // Runs once delivery is acknowledged with 200.
+	)
+	switch data := data.(type) {
+	case *VoteCreatePayload:
+		vote := *data
+		if record != nil {
+			record.UserID = vote.User.PlatformID
 		}
 
-		w.secretMu.Lock()
-		w.secret = integration.Secret
-		w.secretMu.Unlock()
+		if w.replayStore != nil {
+			seen, err := w.replayStore.GetVote(r.Context(), vote.User.PlatformID)
+			if err != nil {
+				w.tracef("Failed to look up vote of user %s in replay store: %v", vote.User.PlatformID, err)
+				w.reject(rw, r, RejectUnavailable, http.StatusServiceUnavailable)
+				return
+			}
+
+			if seen != nil && seen.VotedAt.Equal(vote.VotedAt) {
+				w.tracef("Ignored replayed vote of user %s cast at %s", vote.User.PlatformID, vote.VotedAt.Format(time.RFC3339))
+				w.report(r, RejectDuplicate)
+				rw.WriteHeader(http.StatusOK)
+				return
+			}
+
+			accepted = func() { w.rememberVote(r.Context(), vote) }
+		}
+
+		if w.queryParams != nil {
+			for key := range vote.Query {
+				if _, ok := w.queryParams[key]; !ok {
+					delete(vote.Query, key)
+				}
+			}
+		}
+
+		if w.onVote != nil {
+			callback = func() { w.onVote(vote) }
+			queued = &vote
+		}
+	case *IntegrationCreatePayload:
+		integration := *data
+		// Applied only once delivery is accepted, so rejected or failed one doesn't lock out current secret.
+		accepted = func() { w.addSecret(integration.Secret) }
 		if w.onIntegrationCreate != nil {
-			w.onIntegrationCreate(integration)
+			callback = func() { w.onIntegrationCreate(integration) }
 		}
-	case ScopeIntegrationDelete:
-		if w.onIntegrationDelete == nil {
-			break
+	case *IntegrationDeletePayload:
+		if w.onIntegrationDelete != nil {
+			integration := *data
+			callback = func() { w.onIntegrationDelete(integration) }
 		}
+	case *WebhookTestPayload:
+		if w.onTest != nil {
+			test := *data
+			callback = func() { w.onTest(test) }
+		}
+	}
 
-		var integration IntegrationDeletePayload
-		if err := json.Unmarshal(payload.Data, &integration); err != nil {
-			w.tracef("Failed to unmarshal %s payload: %v", payload.Type, err)
-			http.Error(rw, err.Error(), http.StatusBadRequest)
+	if callback != nil {
+		switch status := w.dispatch(r.Context(), payload.Type, callback, queued); status {
+		case http.StatusOK:
+		case http.StatusServiceUnavailable:
+			w.reject(rw, r, RejectUnavailable, status)
+			return
+		default:
+			w.reject(rw, r, RejectCallbackFailed, status)
 			return
 		}
+	}
+
+	if accepted != nil {
+		accepted()
+	}
+
+	rw.WriteHeader(http.StatusOK)
+}
 
-		w.onIntegrationDelete(integration)
+// Reports whether handleV1 uses data of delivery with given scope outside of strict mode.
+// Vote is also decoded for audit log, so its records name the voter.
+func (w *Webhook) readsData(scope Scope, audited bool) bool {
+	switch scope {
+	case ScopeVoteCreate:
+		return w.onVote != nil || w.replayStore != nil || audited
+	case ScopeIntegrationCreate:
+		return true
+	case ScopeIntegrationDelete:
+		return w.onIntegrationDelete != nil
 	case ScopeWebhookTest:
-		if w.onTest == nil {
-			break
+		return w.onTest != nil
+	default:
+		return false
+	}
+}
+
+// Stores vote in replay store, so its redeliveries are recognized. Check and store aren't atomic,
+// so the same vote delivered to two replicas at once may still be processed by both.
+func (w *Webhook) rememberVote(ctx context.Context, vote VoteCreatePayload) {
+	if err := w.storeVote(ctx, w.replayStore, vote); err != nil {
+		w.tracef("Failed to store vote of user %s in replay store: %v", vote.User.PlatformID, err)
+	}
+}
+
+func (w *Webhook) storeVote(ctx context.Context, store VoteStore, vote VoteCreatePayload) error {
+	expiresAt := vote.ExpiresAt
+	if expiresAt.IsZero() {
+		expiresAt = vote.VotedAt.Add(w.voteValidity)
+	}
+
+	return store.PutVote(ctx, vote.User.PlatformID, PartialVote{
+		VotedAt:   vote.VotedAt,
+		ExpiresAt: expiresAt,
+		Weight:    vote.Weight,
+	})
+}
+
+// Responds with given status and reports rejection to OnRejected hook and metrics sink.
+func (w *Webhook) reject(rw http.ResponseWriter, r *http.Request, reason RejectionReason, status int) {
+	w.rejectWithMessage(rw, r, reason, status, strings.ToLower(http.StatusText(status)))
+}
+
+func (w *Webhook) rejectWithMessage(rw http.ResponseWriter, r *http.Request, reason RejectionReason, status int, message string) {
+	http.Error(rw, message, status)
+	w.report(r, reason)
+}
+
+func (w *Webhook) report(r *http.Request, reason RejectionReason) {
+	if record := deliveryRecordFrom(r); record != nil {
+		record.Reason = reason
+	}
+
+	w.sink.Count(MetricWebhookRejections, 1, "reason:"+string(reason))
+	if w.onRejected != nil {
+		if err := safeCall("webhook", "OnRejected", func() { w.onRejected(reason, r) }); err != nil {
+			w.panicked(err)
 		}
+	}
+}
 
-		var test WebhookTestPayload
-		if err := json.Unmarshal(payload.Data, &test); err != nil {
-			w.tracef("Failed to unmarshal %s payload: %v", payload.Type, err)
-			http.Error(rw, err.Error(), http.StatusBadRequest)
-			return
+// Returns IP address of the client that sent the request. Forwarding headers are only honoured
+// when request came from one of TrustedProxies, otherwise anyone could spoof them.
+func (w *Webhook) ClientIP(r *http.Request) string {
+	return clientIP(r, w.trustedProxies)
+}
+
+// Resolves client IP of the request, trusting forwarding headers only when set by one of given proxies.
+// Shared with MetricsHandler, so both see the same client behind a reverse proxy.
+func clientIP(r *http.Request, proxies []*net.IPNet) string {
+	ip, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		ip = r.RemoteAddr
+	}
+
+	if !inNetworks(proxies, ip) {
+		return ip
+	}
+
+	// Each proxy appends address it received request from, so the first untrusted one from the right is the client.
+	if forwarded := r.Header.Values("X-Forwarded-For"); len(forwarded) > 0 {
+		hops := strings.Split(strings.Join(forwarded, ","), ",")
+		for i := len(hops) - 1; i >= 0; i-- {
+			hop := strings.TrimSpace(hops[i])
+			if net.ParseIP(hop) == nil {
+				break
+			}
+
+			ip = hop
+			if !inNetworks(proxies, hop) {
+				break
+			}
 		}
 
-		w.onTest(test)
+		return ip
 	}
 
-	rw.WriteHeader(http.StatusOK)
+	if realIP := strings.TrimSpace(r.Header.Get("X-Real-IP")); net.ParseIP(realIP) != nil {
+		return realIP
+	}
+
+	return ip
+}
+
+// Parses CIDR, treating single IP address as network of just that address.
+func parseCIDR(entry string) (*net.IPNet, error) {
+	cidr := entry
+	if !strings.Contains(cidr, "/") {
+		if ip := net.ParseIP(cidr); ip != nil && ip.To4() != nil {
+			cidr += "/32"
+		} else {
+			cidr += "/128"
+		}
+	}
+
+	_, network, err := net.ParseCIDR(cidr)
+	return network, err
+}
+
+// Reports whether ip belongs to one of networks.
+func inNetworks(networks []*net.IPNet, ip string) bool {
+	if len(networks) == 0 {
+		return false
+	}
+
+	parsed := net.ParseIP(ip)
+	if parsed == nil {
+		return false
+	}
+
+	for _, network := range networks {
+		if network.Contains(parsed) {
+			return true
+		}
+	}
+
+	return false
 }
 
-func (w *Webhook) validateV1(signatureHeader string, body []byte) error {
+// Applies inbound rate limit to remote address of the request.
+// Signature check alone still costs CPU, so abusive clients are turned away before it.
+func (w *Webhook) allowIP(r *http.Request) bool {
+	ip := w.ClientIP(r)
+	allowed := w.ipLimiter.allow(ip)
+	if !allowed {
+		w.tracef("Rejected request from %s, exceeded inbound rate limit", ip)
+	}
+
+	return allowed
+}
+
+// Inbound rate limit of limit requests per second from every IP.
+type ipRateLimiter struct {
+	buckets *expiringMap[string, *Bucket] // Buckets of IPs seen within last second.
+	limit   int                           // 0 disables rate limiting.
+	mu      sync.Mutex
+}
+
+func newIPRateLimiter(limit int) *ipRateLimiter {
+	return &ipRateLimiter{buckets: newExpiringMap[string, *Bucket](0), limit: limit}
+}
+
+func (l *ipRateLimiter) setLimit(limit int) {
+	l.mu.Lock()
+	l.limit = limit
+	l.mu.Unlock()
+}
+
+// Takes one request of given IP, returns false when it's over the limit.
+func (l *ipRateLimiter) allow(ip string) bool {
+	now := time.Now()
+	// Bucket window lasts a second, so bucket of IP idle for longer is as good as new one.
+	expiresAt := now.Add(time.Second)
+	l.mu.Lock()
+	if l.limit <= 0 {
+		l.mu.Unlock()
+		return true
+	}
+
+	bucket, ok := l.buckets.get(ip, now)
+	if ok {
+		l.buckets.touch(ip, expiresAt)
+	} else {
+		bucket = &Bucket{Limit: l.limit}
+		l.buckets.set(ip, bucket, expiresAt, now)
+	}
+	l.mu.Unlock()
+
+	allowed, _ := bucket.take(now)
+	return allowed
+}
+
+// Verifies signature of the delivery, returning its timestamp and key identifying it for replay protection.
+func (w *Webhook) validateV1(signatureHeader string, body []byte) (time.Time, string, error) {
 	parts := strings.Split(signatureHeader, ",")
 	parsedSignature := make(map[string]string)
 	for _, part := range parts {
@@ -215,42 +824,52 @@ func (w *Webhook) validateV1(signatureHeader string, body []byte) error {
 	sig, hasSig := parsedSignature["v1"]
 
 	if !hasT || !hasSig {
-		return fmt.Errorf("invalid signature format")
+		return time.Time{}, "", fmt.Errorf("invalid signature format")
 	}
 
 	tInt, err := strconv.ParseInt(tStr, 10, 64)
 	if err != nil {
-		return fmt.Errorf("invalid timestamp format")
+		return time.Time{}, "", fmt.Errorf("invalid timestamp format")
 	}
 
 	// Replay attack prevention
 	tTime := time.Unix(tInt, 0)
 	if w.timestampWindow > 0 {
 		if time.Since(tTime) > w.timestampWindow || time.Since(tTime) < -w.timestampWindow {
-			return fmt.Errorf("timestamp outside of accepted time window")
+			return time.Time{}, "", fmt.Errorf("timestamp outside of accepted time window")
 		}
 	}
 
 	w.secretMu.RLock()
-	secret := w.secret
+	secrets := w.secrets
 	w.secretMu.RUnlock()
 
+	for _, secret := range secrets {
+		digest, err := signV1(secret, tStr, body)
+		if err != nil {
+			return time.Time{}, "", err
+		}
+
+		if hmac.Equal([]byte(sig), []byte(digest)) {
+			return tTime, tStr + ":" + sig, nil
+		}
+	}
+
+	return time.Time{}, "", fmt.Errorf("invalid signature")
+}
+
+// Computes hex encoded HMAC-SHA256 of "<timestamp>.<body>" using given secret.
+func signV1(secret, timestamp string, body []byte) (string, error) {
 	mac := hmac.New(sha256.New, []byte(secret))
-	_, err = fmt.Fprintf(mac, "%s.", tStr)
+	_, err := fmt.Fprintf(mac, "%s.", timestamp)
 	if err != nil {
-		return fmt.Errorf("failed to write timestamp to hmac: %w", err)
+		return "", fmt.Errorf("failed to write timestamp to hmac: %w", err)
 	}
 
 	_, err = mac.Write(body)
 	if err != nil {
-		return fmt.Errorf("failed to write body to hmac: %w", err)
-	}
-
-	digest := hex.EncodeToString(mac.Sum(nil))
-
-	if !hmac.Equal([]byte(sig), []byte(digest)) {
-		return fmt.Errorf("invalid signature")
+		return "", fmt.Errorf("failed to write body to hmac: %w", err)
 	}
 
-	return nil
+	return hex.EncodeToString(mac.Sum(nil)), nil
 }
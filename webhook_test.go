@@ -0,0 +1,723 @@
+package topgg
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+const testSecret = "test-secret"
+
+func voteBody(id int) []byte {
+	return voteBodyAt(id, time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC))
+}
+
+// Vote of user with given ID, cast at votedAt.
+func voteBodyAt(id int, votedAt time.Time) []byte {
+	return []byte(fmt.Sprintf(`{"type":"vote.create","data":{"id":"%d","weight":1,"created_at":%q,"expires_at":%q,"project":{},"user":{"platform_id":"%d"}}}`,
+		id, votedAt.Format(time.RFC3339), votedAt.Add(DefaultVoteValidity).Format(time.RFC3339), id))
+}
+
+// Builds delivery of given body signed with secret at given time.
+func signedRequest(t testing.TB, secret string, body []byte, at time.Time) *http.Request {
+	t.Helper()
+
+	signature, err := SignWebhook(secret, body, at)
+	if err != nil {
+		t.Fatalf("SignWebhook: %v", err)
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/webhook", strings.NewReader(string(body)))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("x-topgg-signature", signature)
+	return req
+}
+
+func deliver(w http.Handler, req *http.Request) int {
+	rec := httptest.NewRecorder()
+	w.ServeHTTP(rec, req)
+	return rec.Code
+}
+
+func TestWebhookShutdownDrains(t *testing.T) {
+	tests := []struct {
+		name    string
+		workers int
+		ackMode AckMode
+	}{
+		{"sync", 0, AckAfterProcess},
+		{"detached", 0, AckBeforeProcess},
+		{"workers ack after", 4, AckAfterProcess},
+		{"workers ack before", 4, AckBeforeProcess},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var processed atomic.Int64
+			w := NewClient(ClientOptions{}).NewWebhookHandler(WebhookOptions{
+				Secret:    testSecret,
+				Workers:   tt.workers,
+				QueueSize: 1000,
+				AckMode:   tt.ackMode,
+				OnVote: func(vote VoteCreatePayload) {
+					time.Sleep(time.Millisecond)
+					processed.Add(1)
+				},
+			})
+
+			// Deliveries and Shutdown are released together, so some of them race with closing the queue.
+			start := make(chan struct{})
+			var accepted atomic.Int64
+			var wg sync.WaitGroup
+			for i := 0; i < 200; i++ {
+				req := signedRequest(t, testSecret, voteBody(i), time.Now())
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					<-start
+					switch status := deliver(w, req); status {
+					case http.StatusOK:
+						accepted.Add(1)
+					case http.StatusServiceUnavailable:
+					default:
+						t.Errorf("unexpected status %d", status)
+					}
+				}()
+			}
+
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				<-start
+				if err := w.Shutdown(context.Background()); err != nil {
+					t.Errorf("Shutdown: %v", err)
+				}
+			}()
+
+			close(start)
+			wg.Wait()
+
+			if err := w.Shutdown(context.Background()); err != nil {
+				t.Fatalf("second Shutdown: %v", err)
+			}
+
+			if got, want := processed.Load(), accepted.Load(); got != want {
+				t.Fatalf("processed %d deliveries, acknowledged %d", got, want)
+			}
+
+			if status := deliver(w, signedRequest(t, testSecret, voteBody(1000), time.Now())); status != http.StatusServiceUnavailable {
+				t.Fatalf("delivery after Shutdown got %d, want 503", status)
+			}
+		})
+	}
+}
+
+func TestWebhookShutdownDeadline(t *testing.T) {
+	tests := []struct {
+		name       string
+		wantStatus int // Status of deliveries still queued at deadline.
+		ackMode    AckMode
+		wantSaved  bool
+	}{
+		{name: "ack before process saves votes", ackMode: AckBeforeProcess, wantStatus: http.StatusOK, wantSaved: true},
+		{name: "ack after process answers 503", ackMode: AckAfterProcess, wantStatus: http.StatusServiceUnavailable},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			release := make(chan struct{})
+			running := make(chan struct{}, 1)
+			var processed atomic.Int64
+			store := NewMemoryVoteStore()
+			w := NewClient(ClientOptions{}).NewWebhookHandler(WebhookOptions{
+				Secret:     testSecret,
+				Workers:    1,
+				AckMode:    tt.ackMode,
+				DrainStore: store,
+				OnVote: func(vote VoteCreatePayload) {
+					processed.Add(1)
+					running <- struct{}{}
+					<-release
+				},
+			})
+
+			// First delivery keeps the only worker busy, the rest stays queued.
+			statuses := make(chan int, 4)
+			go func() { statuses <- deliver(w, signedRequest(t, testSecret, voteBodyAt(1, time.Now()), time.Now())) }()
+			<-running
+
+			for i := 2; i <= 4; i++ {
+				req := signedRequest(t, testSecret, voteBodyAt(i, time.Now()), time.Now())
+				go func() { statuses <- deliver(w, req) }()
+			}
+
+			deadline := time.Now().Add(5 * time.Second)
+			for len(w.queue) < 3 && time.Now().Before(deadline) {
+				time.Sleep(time.Millisecond)
+			}
+
+			ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+			defer cancel()
+
+			if err := w.Shutdown(ctx); err != context.DeadlineExceeded {
+				t.Fatalf("Shutdown returned %v, want deadline exceeded", err)
+			}
+
+			// Queued deliveries are settled by Shutdown, without waiting for the running callback.
+			for i := 0; i < 3 && tt.ackMode == AckAfterProcess; i++ {
+				if status := <-statuses; status != tt.wantStatus {
+					t.Fatalf("queued delivery got %d, want %d", status, tt.wantStatus)
+				}
+			}
+
+			for id := Snowflake(2); id <= 4; id++ {
+				vote, err := store.GetVote(context.Background(), id)
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				if saved := vote != nil; saved != tt.wantSaved {
+					t.Fatalf("vote of user %d saved: %v, want %v", id, saved, tt.wantSaved)
+				}
+			}
+
+			close(release)
+			select {
+			case <-w.pending.wait():
+			case <-time.After(5 * time.Second):
+				t.Fatal("worker kept running after Shutdown gave up")
+			}
+
+			if n := processed.Load(); n != 1 {
+				t.Fatalf("processed %d deliveries, want only the running one", n)
+			}
+
+			if status := deliver(w, signedRequest(t, testSecret, voteBodyAt(5, time.Now()), time.Now())); status != http.StatusServiceUnavailable {
+				t.Fatalf("delivery after Shutdown got %d, want 503", status)
+			}
+		})
+	}
+}
+
+func TestWebhookInboundRateLimit(t *testing.T) {
+	w := NewClient(ClientOptions{}).NewWebhookHandler(WebhookOptions{
+		Secret:           testSecret,
+		MaxRequestsPerIP: 2,
+	})
+
+	tests := []struct {
+		ip   string
+		want int
+	}{
+		{"192.0.2.1", http.StatusOK},
+		{"192.0.2.1", http.StatusOK},
+		{"192.0.2.1", http.StatusTooManyRequests},
+		{"192.0.2.2", http.StatusOK},
+	}
+
+	for i, tt := range tests {
+		req := signedRequest(t, testSecret, voteBody(i), time.Now())
+		req.RemoteAddr = tt.ip + ":1234"
+		if got := deliver(w, req); got != tt.want {
+			t.Errorf("request %d from %s got %d, want %d", i, tt.ip, got, tt.want)
+		}
+	}
+}
+
+func TestWebhookReplays(t *testing.T) {
+	type delivery struct {
+		user      int
+		votedAt   int // Minutes after vote validity window started.
+		signedAgo int // Seconds.
+		want      int
+		restart   bool // Delivered to new handler, as after restart or on another replica.
+	}
+
+	tests := []struct {
+		name          string
+		deliveries    []delivery
+		wantVotes     int
+		rejectReplays bool
+		replayStore   bool
+		failFirst     bool
+	}{
+		{
+			name:       "disabled processes every delivery",
+			deliveries: []delivery{{user: 1, want: 200}, {user: 1, want: 200}},
+			wantVotes:  2,
+		},
+		{
+			name:          "identical delivery is acknowledged once processed",
+			rejectReplays: true,
+			deliveries:    []delivery{{user: 1, want: 200}, {user: 1, want: 200}, {user: 1, signedAgo: 1, want: 200}},
+			wantVotes:     2,
+		},
+		{
+			name:          "memory cache doesn't survive restart",
+			rejectReplays: true,
+			deliveries:    []delivery{{user: 1, want: 200}, {user: 1, want: 200, restart: true}},
+			wantVotes:     2,
+		},
+		{
+			name:        "store rejects re-signed vote",
+			replayStore: true,
+			deliveries:  []delivery{{user: 1, want: 200}, {user: 1, signedAgo: 5, want: 200}},
+			wantVotes:   1,
+		},
+		{
+			name:        "store survives restart",
+			replayStore: true,
+			deliveries:  []delivery{{user: 1, want: 200}, {user: 1, want: 200, restart: true}},
+			wantVotes:   1,
+		},
+		{
+			name:        "store accepts next vote of the same user",
+			replayStore: true,
+			deliveries:  []delivery{{user: 1, want: 200}, {user: 1, votedAt: 720, want: 200}, {user: 2, want: 200}},
+			wantVotes:   3,
+		},
+		{
+			name:          "failed delivery isn't remembered by cache",
+			rejectReplays: true,
+			failFirst:     true,
+			deliveries:    []delivery{{user: 1, want: 500}, {user: 1, want: 200}},
+			wantVotes:     1,
+		},
+		{
+			name:        "failed delivery isn't remembered by store",
+			replayStore: true,
+			failFirst:   true,
+			deliveries:  []delivery{{user: 1, want: 500}, {user: 1, signedAgo: 1, want: 200}},
+			wantVotes:   1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			now := time.Now()
+			base := now.Add(-time.Hour)
+
+			var votes int
+			failed := !tt.failFirst
+			opt := WebhookOptions{
+				Secret:        testSecret,
+				RejectReplays: tt.rejectReplays,
+				OnVote: func(vote VoteCreatePayload) {
+					if !failed {
+						failed = true
+						panic("callback failed")
+					}
+					votes++
+				},
+			}
+			if tt.replayStore {
+				opt.ReplayStore = NewMemoryVoteStore()
+			}
+
+			client := NewClient(ClientOptions{})
+			w := client.NewWebhookHandler(opt)
+			for i, d := range tt.deliveries {
+				if d.restart {
+					w = client.NewWebhookHandler(opt)
+				}
+
+				body := voteBodyAt(d.user, base.Add(time.Duration(d.votedAt)*time.Minute))
+				req := signedRequest(t, testSecret, body, now.Add(-time.Duration(d.signedAgo)*time.Second))
+				if got := deliver(w, req); got != d.want {
+					t.Errorf("delivery %d got %d, want %d", i, got, d.want)
+				}
+			}
+
+			if votes != tt.wantVotes {
+				t.Fatalf("OnVote called %d times, want %d", votes, tt.wantVotes)
+			}
+		})
+	}
+}
+
+func TestWebhookReplayCacheBounded(t *testing.T) {
+	w := NewClient(ClientOptions{}).NewWebhookHandler(WebhookOptions{
+		Secret:           testSecret,
+		RejectReplays:    true,
+		MaxReplayEntries: 2,
+	})
+
+	now := time.Now()
+	for i := 0; i < 3; i++ {
+		deliver(w, signedRequest(t, testSecret, voteBody(i), now))
+	}
+
+	if stats := w.MemoryStats(); stats.Entries != 2 || stats.Evictions != 1 {
+		t.Fatalf("got %+v, want 2 entries after 1 eviction", stats)
+	}
+}
+
+func TestWebhookSignature(t *testing.T) {
+	body := voteBody(1)
+
+	tests := []struct {
+		name   string
+		header func(t *testing.T) string
+		body   []byte // Defaults to signed body.
+		want   int
+	}{
+		{
+			name:   "primary secret",
+			header: signatureOf(testSecret, body, 0),
+			want:   http.StatusOK,
+		},
+		{
+			name:   "additional secret",
+			header: signatureOf("additional-secret", body, 0),
+			want:   http.StatusOK,
+		},
+		{
+			name:   "unknown secret",
+			header: signatureOf("unknown-secret", body, 0),
+			want:   http.StatusUnauthorized,
+		},
+		{
+			name:   "tampered body",
+			header: signatureOf(testSecret, body, 0),
+			body:   voteBody(2),
+			want:   http.StatusUnauthorized,
+		},
+		{
+			name:   "timestamp outside of window",
+			header: signatureOf(testSecret, body, -time.Minute),
+			want:   http.StatusUnauthorized,
+		},
+		{
+			name:   "timestamp in future",
+			header: signatureOf(testSecret, body, time.Minute),
+			want:   http.StatusUnauthorized,
+		},
+		{
+			name:   "missing signature",
+			header: func(t *testing.T) string { return "" },
+			want:   http.StatusUnauthorized,
+		},
+		{
+			name:   "malformed signature",
+			header: func(t *testing.T) string { return "v1=deadbeef" },
+			want:   http.StatusUnauthorized,
+		},
+		{
+			name:   "non-numeric timestamp",
+			header: func(t *testing.T) string { return "t=now,v1=deadbeef" },
+			want:   http.StatusUnauthorized,
+		},
+	}
+
+	w := NewClient(ClientOptions{}).NewWebhookHandler(WebhookOptions{
+		Secret:          testSecret,
+		Secrets:         []string{"additional-secret"},
+		TimestampWindow: 30 * time.Second,
+	})
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			delivered := body
+			if tt.body != nil {
+				delivered = tt.body
+			}
+
+			req := httptest.NewRequest(http.MethodPost, "/webhook", strings.NewReader(string(delivered)))
+			req.Header.Set("Content-Type", "application/json")
+			req.Header.Set("x-topgg-signature", tt.header(t))
+			if got := deliver(w, req); got != tt.want {
+				t.Fatalf("got %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+// Returns signature header of body signed with secret, shifted from now by offset.
+func signatureOf(secret string, body []byte, offset time.Duration) func(t *testing.T) string {
+	return func(t *testing.T) string {
+		return signedRequest(t, secret, body, time.Now().Add(offset)).Header.Get("x-topgg-signature")
+	}
+}
+
+func integrationBody(secret string) []byte {
+	return []byte(fmt.Sprintf(`{"type":"integration.create","data":{"connection_id":"1","webhook_secret":%q,"project":{},"user":{}}}`, secret))
+}
+
+func TestWebhookSecretRotation(t *testing.T) {
+	tests := []struct {
+		name       string
+		newSecret  string
+		accepted   []string // Secrets whose deliveries are accepted afterwards.
+		rejected   []string
+		failCreate bool // OnIntegrationCreate panics, so delivery is answered with 500.
+	}{
+		{
+			name:      "new secret is accepted next to current one",
+			newSecret: "new-secret",
+			accepted:  []string{testSecret, "new-secret"},
+		},
+		{
+			name:     "empty secret keeps current one",
+			accepted: []string{testSecret},
+			rejected: []string{""},
+		},
+		{
+			name:      "repeated secret is accepted once",
+			newSecret: testSecret,
+			accepted:  []string{testSecret},
+		},
+		{
+			name:       "failed delivery doesn't change secrets",
+			newSecret:  "new-secret",
+			failCreate: true,
+			accepted:   []string{testSecret},
+			rejected:   []string{"new-secret"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := NewClient(ClientOptions{}).NewWebhookHandler(WebhookOptions{
+				Secret: testSecret,
+				OnIntegrationCreate: func(integration IntegrationCreatePayload) {
+					if tt.failCreate {
+						panic("callback failed")
+					}
+				},
+			})
+
+			want := http.StatusOK
+			if tt.failCreate {
+				want = http.StatusInternalServerError
+			}
+
+			if got := deliver(w, signedRequest(t, testSecret, integrationBody(tt.newSecret), time.Now())); got != want {
+				t.Fatalf("integration.create got %d, want %d", got, want)
+			}
+
+			for _, secret := range tt.accepted {
+				if got := deliver(w, signedRequest(t, secret, voteBody(1), time.Now())); got != http.StatusOK {
+					t.Errorf("delivery signed with %q got %d, want 200", secret, got)
+				}
+			}
+
+			for _, secret := range tt.rejected {
+				if got := deliver(w, signedRequest(t, secret, voteBody(1), time.Now())); got != http.StatusUnauthorized {
+					t.Errorf("delivery signed with %q got %d, want 401", secret, got)
+				}
+			}
+
+			w.secretMu.RLock()
+			defer w.secretMu.RUnlock()
+			if len(w.secrets) != len(tt.accepted) {
+				t.Errorf("handler accepts %d secrets, want %d", len(w.secrets), len(tt.accepted))
+			}
+		})
+	}
+}
+
+func TestWebhookIntegrationSecretReplaced(t *testing.T) {
+	w := NewClient(ClientOptions{}).NewWebhookHandler(WebhookOptions{Secret: testSecret})
+	for i := 0; i < 5; i++ {
+		secret := fmt.Sprintf("integration-%d", i)
+		if got := deliver(w, signedRequest(t, testSecret, integrationBody(secret), time.Now())); got != http.StatusOK {
+			t.Fatalf("integration.create of %q got %d, want 200", secret, got)
+		}
+	}
+
+	tests := []struct {
+		secret string
+		want   int
+	}{
+		{testSecret, http.StatusOK},
+		{"integration-4", http.StatusOK},
+		{"integration-3", http.StatusUnauthorized},
+		{"integration-0", http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		if got := deliver(w, signedRequest(t, tt.secret, voteBody(1), time.Now())); got != tt.want {
+			t.Errorf("delivery signed with %q got %d, want %d", tt.secret, got, tt.want)
+		}
+	}
+
+	w.secretMu.RLock()
+	defer w.secretMu.RUnlock()
+	if len(w.secrets) != 2 {
+		t.Errorf("handler accepts %d secrets after 5 integrations, want 2", len(w.secrets))
+	}
+}
+
+func TestWebhookSetSecrets(t *testing.T) {
+	w := NewClient(ClientOptions{}).NewWebhookHandler(WebhookOptions{Secret: testSecret})
+	w.SetSecrets("next-secret", "")
+
+	tests := []struct {
+		secret string
+		want   int
+	}{
+		{"next-secret", http.StatusOK},
+		{testSecret, http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		if got := deliver(w, signedRequest(t, tt.secret, voteBody(1), time.Now())); got != tt.want {
+			t.Errorf("delivery signed with %q got %d, want %d", tt.secret, got, tt.want)
+		}
+	}
+}
+
+func TestWebhookAbandonedDelivery(t *testing.T) {
+	tests := []struct {
+		name          string
+		workerBusy    bool // Worker is stuck on another delivery until request gives up.
+		want          int
+		wantProcessed int64
+	}{
+		{name: "queued delivery isn't processed after 503", workerBusy: true, want: http.StatusServiceUnavailable, wantProcessed: 0},
+		{name: "running delivery is answered once processed", want: http.StatusOK, wantProcessed: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			release := make(chan struct{})
+			started := make(chan struct{})
+			var processed atomic.Int64
+			w := NewClient(ClientOptions{}).NewWebhookHandler(WebhookOptions{
+				Secret:    testSecret,
+				Workers:   1,
+				QueueSize: 10,
+				AckMode:   AckAfterProcess,
+				OnVote: func(vote VoteCreatePayload) {
+					if vote.ID == 1 {
+						close(started)
+						<-release
+						return
+					}
+
+					time.Sleep(50 * time.Millisecond)
+					processed.Add(1)
+				},
+			})
+
+			var busy sync.WaitGroup
+			if tt.workerBusy {
+				busy.Add(1)
+				go func() {
+					defer busy.Done()
+					deliver(w, signedRequest(t, testSecret, voteBody(1), time.Now()))
+				}()
+				<-started
+			}
+
+			ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+			defer cancel()
+
+			req := signedRequest(t, testSecret, voteBody(2), time.Now()).WithContext(ctx)
+			if got := deliver(w, req); got != tt.want {
+				t.Errorf("got %d, want %d", got, tt.want)
+			}
+
+			close(release)
+			busy.Wait()
+			if err := w.Shutdown(context.Background()); err != nil {
+				t.Fatalf("Shutdown: %v", err)
+			}
+
+			if got := processed.Load(); got != tt.wantProcessed {
+				t.Fatalf("processed %d times, want %d", got, tt.wantProcessed)
+			}
+		})
+	}
+}
+
+func TestWebhookContentType(t *testing.T) {
+	tests := []struct {
+		contentType string
+		want        int
+		require     bool
+	}{
+		{contentType: "application/json", want: http.StatusOK},
+		{contentType: "application/json; charset=utf-8", want: http.StatusOK},
+		{contentType: "", want: http.StatusOK},
+		{contentType: "text/plain", want: http.StatusOK},
+		{contentType: "application/json", require: true, want: http.StatusOK},
+		{contentType: "", require: true, want: http.StatusUnsupportedMediaType},
+		{contentType: "text/plain", require: true, want: http.StatusUnsupportedMediaType},
+	}
+
+	for _, tt := range tests {
+		w := NewClient(ClientOptions{}).NewWebhookHandler(WebhookOptions{
+			Secret:             testSecret,
+			RequireContentType: tt.require,
+		})
+
+		req := signedRequest(t, testSecret, voteBody(1), time.Now())
+		req.Header.Set("Content-Type", tt.contentType)
+		if got := deliver(w, req); got != tt.want {
+			t.Errorf("Content-Type %q (required: %v) got %d, want %d", tt.contentType, tt.require, got, tt.want)
+		}
+	}
+}
+
+// ResponseWriter dropping everything, so benchmarks measure the handler only.
+type discardResponseWriter struct {
+	header http.Header
+}
+
+func (w *discardResponseWriter) Header() http.Header         { return w.header }
+func (w *discardResponseWriter) Write(b []byte) (int, error) { return len(b), nil }
+func (w *discardResponseWriter) WriteHeader(status int)      {}
+
+func BenchmarkWebhookServeHTTP(b *testing.B) {
+	w := NewClient(ClientOptions{}).NewWebhookHandler(WebhookOptions{
+		Secret: testSecret,
+		OnVote: func(vote VoteCreatePayload) {},
+	})
+
+	body := voteBody(1)
+	req := signedRequest(b, testSecret, body, time.Now())
+	reader := bytes.NewReader(body)
+	rw := &discardResponseWriter{header: make(http.Header)}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		reader.Reset(body)
+		req.Body = io.NopCloser(reader)
+		w.ServeHTTP(rw, req)
+	}
+}
+
+// Compares reading delivery body into pooled buffer, as serve does, with a fresh buffer per delivery.
+func BenchmarkWebhookBodyRead(b *testing.B) {
+	body := voteBody(1)
+	reader := bytes.NewReader(body)
+
+	b.Run("pooled", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			reader.Reset(body)
+			buf := bodyBufferPool.Get().(*bytes.Buffer)
+			buf.Reset()
+			_, _ = buf.ReadFrom(reader)
+			releaseBodyBuffer(buf)
+		}
+	})
+
+	b.Run("unpooled", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			reader.Reset(body)
+			_, _ = io.ReadAll(reader)
+		}
+	})
+}